@@ -40,6 +40,7 @@ func (s *MysqlStore) CreateTableUser() error {
 			user_name varchar(100) not null,
 			email varchar(50) not null unique,
 			photo_profile varchar(200),
+			default_audience varchar(20) not null default 'friends',
 			primary key(user_id)
 		);
 	`
@@ -56,6 +57,7 @@ func (s *MysqlStore) CreateTableUser_Friend() error {
 			user_friend_id varchar(100),
 			user_id varchar(100) references user(user_id),
 			friend_id varchar(100) references user(user_id),
+			created_at timestamp not null default current_timestamp,
 			primary key(user_friend_id)
 		);
 	`
@@ -75,6 +77,7 @@ func (s *MysqlStore) CreatePostTable() error {
 			type varchar(10),
 			created_at timestamp,
 			updated_at timestamp,
+			audience varchar(20) not null default 'friends',
 			primary key(post_id)
 		);
 	`
@@ -112,6 +115,7 @@ func (s *MysqlStore) CreateTableComment() error {
 			comment_post_id varchar(100) references post(post_id),
 			created_at timestamp,
 			updated_at timestamp,
+			root_post_id varchar(100) references post(post_id),
 			primary key(comment_id)
 		);
 	`
@@ -220,6 +224,796 @@ func (s *MysqlStore) CreateTableLog() error {
 	return err
 }
 
+// create table user_block
+func (s *MysqlStore) CreateTableUser_Block() error {
+	createTable := `
+		create table if not exists user_block (
+			user_block_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			blocked_id varchar(100) references user(user_id),
+			created_at timestamp,
+			primary key(user_block_id),
+			unique(user_id, blocked_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table suggestion_dismiss
+func (s *MysqlStore) CreateTableSuggestion_Dismiss() error {
+	createTable := `
+		create table if not exists suggestion_dismiss (
+			suggestion_dismiss_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			dismissed_id varchar(100) references user(user_id),
+			created_at timestamp,
+			primary key(suggestion_dismiss_id),
+			unique(user_id, dismissed_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table user_follow
+func (s *MysqlStore) CreateTableUser_Follow() error {
+	createTable := `
+		create table if not exists user_follow (
+			user_follow_id varchar(100),
+			follower_id varchar(100) references user(user_id),
+			following_id varchar(100) references user(user_id),
+			created_at timestamp,
+			primary key(user_follow_id),
+			unique(follower_id, following_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table timeline
+func (s *MysqlStore) CreateTableTimeline() error {
+	createTable := `
+		create table if not exists timeline (
+			user_id varchar(100) references user(user_id),
+			post_id varchar(100) references post(post_id) on delete cascade,
+			author_id varchar(100) references user(user_id),
+			created_at timestamp,
+			primary key(user_id, post_id),
+			index(user_id, created_at),
+			index(user_id, author_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table high_fanout_user
+func (s *MysqlStore) CreateTableHigh_Fanout_User() error {
+	createTable := `
+		create table if not exists high_fanout_user (
+			user_id varchar(100) references user(user_id),
+			created_at timestamp,
+			primary key(user_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// fill timeline from existing post when the table is still empty
+func (s *MysqlStore) BackfillTimeline() error {
+	var number int
+
+	if err := s.db.QueryRow("select count(*) as `number` from timeline;").Scan(&number); err != nil {
+		return err
+	}
+
+	if number > 0 {
+		return nil
+	}
+
+	backfill := []string{
+		`insert ignore into timeline(user_id, post_id, author_id, created_at) select user_id, post_id, user_id, created_at from post where type = 'main';`,
+		`insert ignore into timeline(user_id, post_id, author_id, created_at) select user_friend.user_id, post.post_id, post.user_id, post.created_at from user_friend inner join post on user_friend.friend_id = post.user_id where post.type = 'main';`,
+		`insert ignore into timeline(user_id, post_id, author_id, created_at) select user_follow.follower_id, post.post_id, post.user_id, post.created_at from user_follow inner join post on user_follow.following_id = post.user_id where post.type = 'main' and post.audience = 'public';`,
+	}
+
+	for _, query := range backfill {
+		if _, err := s.db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// create table post_history, prior revision of edited post and comment
+func (s *MysqlStore) CreateTablePost_History() error {
+	createTable := `
+		create table if not exists post_history (
+			post_history_id varchar(100),
+			post_id varchar(100) references post(post_id) on delete cascade,
+			content varchar(500),
+			images text,
+			created_at timestamp,
+			primary key(post_history_id),
+			index(post_id, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table reaction, one reaction per user on post or comment
+func (s *MysqlStore) CreateTableReaction() error {
+	createTable := `
+		create table if not exists reaction (
+			reaction_id varchar(100),
+			post_id varchar(100) references post(post_id) on delete cascade,
+			user_id varchar(100) references user(user_id),
+			type varchar(20) not null,
+			created_at timestamp,
+			primary key(reaction_id),
+			unique(post_id, user_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table post_audience, user that can see post with custom audience
+func (s *MysqlStore) CreateTablePost_Audience() error {
+	createTable := `
+		create table if not exists post_audience (
+			post_id varchar(100) references post(post_id) on delete cascade,
+			user_id varchar(100) references user(user_id),
+			primary key(post_id, user_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table post_hashtag
+func (s *MysqlStore) CreateTablePost_Hashtag() error {
+	createTable := `
+		create table if not exists post_hashtag (
+			post_id varchar(100) references post(post_id) on delete cascade,
+			tag varchar(100) not null,
+			start_index int not null,
+			end_index int not null,
+			created_at timestamp,
+			primary key(post_id, start_index),
+			index(tag, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table post_mention
+func (s *MysqlStore) CreateTablePost_Mention() error {
+	createTable := `
+		create table if not exists post_mention (
+			post_id varchar(100) references post(post_id) on delete cascade,
+			user_id varchar(100) references user(user_id),
+			start_index int not null,
+			end_index int not null,
+			created_at timestamp,
+			primary key(post_id, start_index),
+			index(user_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create media table, file uploaded by user that post and profile reference by id
+func (s *MysqlStore) CreateTableMedia() error {
+	createTable := `
+		create table if not exists media (
+			media_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			storage_key varchar(300) not null,
+			content_type varchar(50) not null,
+			size bigint not null,
+			width int not null,
+			height int not null,
+			blurhash varchar(100) not null default '',
+			created_at timestamp,
+			primary key(media_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create media variant table, resized copy of uploaded image
+func (s *MysqlStore) CreateTableMedia_Variant() error {
+	createTable := `
+		create table if not exists media_variant (
+			media_id varchar(100) references media(media_id),
+			name varchar(20) not null,
+			storage_key varchar(300) not null,
+			content_type varchar(50) not null,
+			width int not null,
+			height int not null,
+			size bigint not null,
+			primary key(media_id, name)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table collection, named private group of saved post
+func (s *MysqlStore) CreateTableCollection() error {
+	createTable := `
+		create table if not exists collection (
+			collection_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			name varchar(100) not null,
+			created_at timestamp,
+			primary key(collection_id),
+			unique(user_id, name)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table bookmark, empty collection_id mean saved without collection
+func (s *MysqlStore) CreateTableBookmark() error {
+	createTable := `
+		create table if not exists bookmark (
+			bookmark_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			post_id varchar(100) references post(post_id),
+			collection_id varchar(100) not null default '',
+			created_at timestamp,
+			primary key(bookmark_id),
+			unique(user_id, post_id),
+			index(user_id, collection_id, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table draft, post that is not published yet.
+// status: draft, scheduled, publishing, published, failed
+func (s *MysqlStore) CreateTableDraft() error {
+	createTable := `
+		create table if not exists draft (
+			draft_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			content text,
+			images text,
+			audience varchar(20) not null default '',
+			audience_list text,
+			status varchar(20) not null,
+			publish_at timestamp null,
+			post_id varchar(100) not null,
+			claimed_by varchar(100) not null default '',
+			claimed_at timestamp null,
+			error varchar(300) not null default '',
+			poll text,
+			created_at timestamp,
+			updated_at timestamp,
+			primary key(draft_id),
+			index(status, publish_at),
+			index(user_id, status, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table poll, attached to main post
+func (s *MysqlStore) CreateTablePoll() error {
+	createTable := `
+		create table if not exists poll (
+			post_id varchar(100) references post(post_id),
+			multiple boolean not null default false,
+			closes_at timestamp null,
+			closed_notified boolean not null default false,
+			created_at timestamp,
+			primary key(post_id),
+			index(closed_notified, closes_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table poll_option
+func (s *MysqlStore) CreateTablePoll_Option() error {
+	createTable := `
+		create table if not exists poll_option (
+			option_id varchar(100),
+			post_id varchar(100) references poll(post_id),
+			text varchar(200) not null,
+			position int not null,
+			primary key(option_id),
+			index(post_id, position)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table poll_vote. slot is empty for single choice poll and option_id for multi choice,
+// so the unique key allow one vote per poll or one vote per option
+func (s *MysqlStore) CreateTablePoll_Vote() error {
+	createTable := `
+		create table if not exists poll_vote (
+			post_id varchar(100) references poll(post_id),
+			option_id varchar(100) references poll_option(option_id),
+			user_id varchar(100) references user(user_id),
+			slot varchar(100) not null,
+			created_at timestamp,
+			primary key(post_id, user_id, slot),
+			index(option_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table poll_ballot, one row per voter so the database allow only one vote
+// even when multi choice vote come in at the same time
+func (s *MysqlStore) CreateTablePoll_Ballot() error {
+	createTable := `
+		create table if not exists poll_ballot (
+			post_id varchar(100) references poll(post_id),
+			user_id varchar(100) references user(user_id),
+			created_at timestamp,
+			primary key(post_id, user_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// ballot of vote made before poll_ballot exist
+func (s *MysqlStore) BackfillPoll_Ballot() error {
+	var number int
+
+	if err := s.db.QueryRow("select count(*) as `number` from poll_ballot;").Scan(&number); err != nil {
+		return err
+	}
+
+	if number > 0 {
+		return nil
+	}
+
+	_, err := s.db.Exec(`insert ignore into poll_ballot(post_id, user_id, created_at) select post_id, user_id, min(created_at) from poll_vote group by post_id, user_id;`)
+
+	return err
+}
+
+// create table report, one report per reporter and target
+func (s *MysqlStore) CreateTableReport() error {
+	createTable := `
+		create table if not exists report (
+			report_id varchar(100),
+			reporter_id varchar(100) references user(user_id),
+			target_type varchar(20) not null,
+			target_id varchar(100) not null,
+			target_user_id varchar(100) references user(user_id),
+			reason varchar(30) not null,
+			detail varchar(500) not null default '',
+			status varchar(20) not null default 'open',
+			resolved_by varchar(100) not null default '',
+			resolved_at timestamp null,
+			created_at timestamp,
+			primary key(report_id),
+			unique(reporter_id, target_type, target_id),
+			index(status, created_at),
+			index(target_type, target_id, status)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table moderation_action, audit record of every moderator action
+func (s *MysqlStore) CreateTableModeration_Action() error {
+	createTable := `
+		create table if not exists moderation_action (
+			action_id varchar(100),
+			moderator_id varchar(100) references user(user_id),
+			action varchar(20) not null,
+			target_type varchar(20) not null,
+			target_id varchar(100) not null,
+			target_user_id varchar(100) references user(user_id),
+			report_id varchar(100) not null default '',
+			note varchar(500) not null default '',
+			suspended_until timestamp null,
+			created_at timestamp,
+			primary key(action_id),
+			index(created_at),
+			index(target_user_id, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table trending, result of the last trending refresh per period
+func (s *MysqlStore) CreateTableTrending() error {
+	createTable := `
+		create table if not exists trending (
+			period varchar(10) not null,
+			kind varchar(10) not null,
+			item_id varchar(100) not null,
+			score double not null,
+			computed_at timestamp,
+			primary key(period, kind, item_id),
+			index(period, kind, score)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table story, removed by reaper after expires_at
+func (s *MysqlStore) CreateTableStory() error {
+	createTable := `
+		create table if not exists story (
+			story_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			type varchar(10) not null,
+			content varchar(500) not null default '',
+			media_id varchar(100) not null default '',
+			created_at timestamp,
+			expires_at timestamp,
+			primary key(story_id),
+			index(user_id, expires_at),
+			index(expires_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table story_view, viewer that already seen the story
+func (s *MysqlStore) CreateTableStory_View() error {
+	createTable := `
+		create table if not exists story_view (
+			story_id varchar(100) references story(story_id) on delete cascade,
+			viewer_id varchar(100) references user(user_id),
+			viewed_at timestamp,
+			primary key(story_id, viewer_id),
+			index(story_id, viewed_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table album, auto_key is profile or post for album made by the system and null for album made by user
+func (s *MysqlStore) CreateTableAlbum() error {
+	createTable := `
+		create table if not exists album (
+			album_id varchar(100),
+			user_id varchar(100) references user(user_id),
+			name varchar(100) not null,
+			auto_key varchar(20),
+			audience varchar(20) not null default 'public',
+			cover_image varchar(300) not null default '',
+			created_at timestamp,
+			updated_at timestamp,
+			primary key(album_id),
+			unique(user_id, auto_key),
+			index(user_id, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table album_photo, every image of user is in exactly one album
+func (s *MysqlStore) CreateTableAlbum_Photo() error {
+	createTable := `
+		create table if not exists album_photo (
+			user_id varchar(100) references user(user_id),
+			image varchar(300),
+			album_id varchar(100) references album(album_id),
+			media_id varchar(100) not null default '',
+			source varchar(10) not null,
+			created_at timestamp,
+			primary key(user_id, image),
+			index(album_id, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// put existing post image and profile photo to the auto album when the table is still empty
+func (s *MysqlStore) BackfillAlbum() error {
+	var number int
+
+	if err := s.db.QueryRow("select count(*) as `number` from album_photo;").Scan(&number); err != nil {
+		return err
+	}
+
+	if number > 0 {
+		return nil
+	}
+
+	backfill := []string{
+		`insert ignore into album(album_id, user_id, name, auto_key, audience, created_at, updated_at) select uuid(), user_id, 'Post photos', 'post', 'public', min(created_at), min(created_at) from image_post group by user_id;`,
+		`insert ignore into album(album_id, user_id, name, auto_key, audience, created_at, updated_at) select uuid(), user_id, 'Profile photos', 'profile', 'public', current_timestamp, current_timestamp from user where photo_profile <> '';`,
+		`insert ignore into album_photo(user_id, image, album_id, media_id, source, created_at) select image_post.user_id, image_post.image, album.album_id, coalesce(min(image_post.media_id), ''), 'post', min(image_post.created_at) from image_post inner join album on album.user_id = image_post.user_id and album.auto_key = 'post' group by image_post.user_id, image_post.image, album.album_id;`,
+		`insert ignore into album_photo(user_id, image, album_id, media_id, source, created_at) select user.user_id, user.photo_profile, album.album_id, coalesce((select media_id from media where media.user_id = user.user_id and user.photo_profile like concat('%/media/', media.media_id) limit 1), ''), 'profile', current_timestamp from user inner join album on album.user_id = user.user_id and album.auto_key = 'profile' where user.photo_profile <> '';`,
+	}
+
+	for _, query := range backfill {
+		if _, err := s.db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// create table notification_preference, type that is not here use in_app
+func (s *MysqlStore) CreateTableNotification_Preference() error {
+	createTable := `
+		create table if not exists notification_preference (
+			user_id varchar(100) references user(user_id),
+			type varchar(20) not null,
+			channel varchar(10) not null,
+			primary key(user_id, type)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table notification_quiet_hour, minute of the day in the user own utc offset
+func (s *MysqlStore) CreateTableNotification_Quiet_Hour() error {
+	createTable := `
+		create table if not exists notification_quiet_hour (
+			user_id varchar(100) references user(user_id),
+			enabled boolean not null default false,
+			start_minute int not null default 0,
+			end_minute int not null default 0,
+			utc_offset int not null default 0,
+			primary key(user_id)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table notification_mute, target is post, room or user
+func (s *MysqlStore) CreateTableNotification_Mute() error {
+	createTable := `
+		create table if not exists notification_mute (
+			user_id varchar(100) references user(user_id),
+			target_type varchar(10) not null,
+			target_id varchar(100) not null,
+			created_at timestamp,
+			primary key(user_id, target_type, target_id),
+			index(user_id, created_at)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// create table notification_queue, notification held back by quiet hour
+func (s *MysqlStore) CreateTableNotification_Queue() error {
+	createTable := `
+		create table if not exists notification_queue (
+			notification_id varchar(100) references notification(notification_id),
+			user_id varchar(100) references user(user_id),
+			channel varchar(10) not null,
+			send_after timestamp not null,
+			primary key(notification_id),
+			index(send_after)
+		);
+	`
+
+	_, err := s.db.Exec(createTable)
+
+	return err
+}
+
+// add column to table that already exist, skip it when the column is there.
+// table that is not created yet is skipped too, it is created with the column.
+func (s *MysqlStore) AddColumn(table, column, definition string) error {
+	var number int
+
+	query := "select count(*) as `number` from information_schema.tables where table_schema = database() and table_name = ?;"
+	if err := s.db.QueryRow(query, table).Scan(&number); err != nil {
+		return err
+	}
+
+	if number == 0 {
+		return nil
+	}
+
+	query = "select count(*) as `number` from information_schema.columns where table_schema = database() and table_name = ? and column_name = ?;"
+	if err := s.db.QueryRow(query, table, column).Scan(&number); err != nil {
+		return err
+	}
+
+	if number > 0 {
+		return nil
+	}
+
+	_, err := s.db.Exec("alter table " + table + " add column " + column + " " + definition + ";")
+
+	return err
+}
+
+// migrate column that added after the table was created
+func (s *MysqlStore) MigrateColumn() error {
+	if err := s.AddColumn("post", "audience", "varchar(20) not null default 'friends'"); err != nil {
+		return err
+	}
+
+	if err := s.AddColumn("user_friend", "created_at", "timestamp not null default current_timestamp"); err != nil {
+		return err
+	}
+
+	if err := s.AddColumn("user", "default_audience", "varchar(20) not null default 'friends'"); err != nil {
+		return err
+	}
+
+	if err := s.AddColumn("comment", "root_post_id", "varchar(100) references post(post_id)"); err != nil {
+		return err
+	}
+
+	if err := s.AddColumn("post", "original_post_id", "varchar(100) references post(post_id)"); err != nil {
+		return err
+	}
+
+	if err := s.AddColumn("image_post", "media_id", "varchar(100) references media(media_id)"); err != nil {
+		return err
+	}
+
+	// chat room of room_invite notification
+	if err := s.AddColumn("notification", "room_id", "varchar(100) not null default ''"); err != nil {
+		return err
+	}
+
+	// role is user or moderator, moderator is set directly in database
+	if err := s.AddColumn("user", "role", "varchar(20) not null default 'user'"); err != nil {
+		return err
+	}
+
+	if err := s.AddColumn("user", "suspended_until", "timestamp null"); err != nil {
+		return err
+	}
+
+	// content hidden by moderator
+	if err := s.AddColumn("post", "hidden", "boolean not null default false"); err != nil {
+		return err
+	}
+
+	if err := s.AddColumn("message", "hidden", "boolean not null default false"); err != nil {
+		return err
+	}
+
+	// poll of draft saved before poll support
+	if err := s.AddColumn("draft", "poll", "text"); err != nil {
+		return err
+	}
+
+	// placeholder of media uploaded before variant support
+	if err := s.AddColumn("media", "blurhash", "varchar(100) not null default ''"); err != nil {
+		return err
+	}
+
+	// comment before thread support are all direct comment of main post
+	if _, err := s.db.Exec(`update comment set root_post_id = post_id where root_post_id is null;`); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// add FULLTEXT index when it is not there yet
+func (s *MysqlStore) AddFulltext(table, index, column string) error {
+	var number int
+
+	query := "select count(*) as `number` from information_schema.statistics where table_schema = database() and table_name = ? and index_name = ?;"
+	if err := s.db.QueryRow(query, table, index).Scan(&number); err != nil {
+		return err
+	}
+
+	if number > 0 {
+		return nil
+	}
+
+	_, err := s.db.Exec("alter table " + table + " add fulltext index " + index + " (" + column + ");")
+
+	return err
+}
+
+// InitFulltext create index used by search, error mean the database can't do
+// FULLTEXT search and the caller should fall back to the in memory index
+func (s *MysqlStore) InitFulltext() error {
+	if err := s.AddFulltext("user", "ft_user_name", "user_name"); err != nil {
+		return err
+	}
+
+	if err := s.AddFulltext("post", "ft_post_content", "content"); err != nil {
+		return err
+	}
+
+	if err := s.AddFulltext("message", "ft_message_content", "content"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *MysqlStore) InitDB() error {
 	if err := s.CreateTableUser(); err != nil {
 		return err
@@ -261,6 +1055,142 @@ func (s *MysqlStore) InitDB() error {
 		return err
 	}
 
+	if err := s.CreateTableUser_Block(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableSuggestion_Dismiss(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableUser_Follow(); err != nil {
+		return err
+	}
+
+	if err := s.MigrateColumn(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableTimeline(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableHigh_Fanout_User(); err != nil {
+		return err
+	}
+
+	if err := s.BackfillTimeline(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePost_History(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableReaction(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePost_Audience(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePost_Hashtag(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePost_Mention(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableMedia(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableMedia_Variant(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableCollection(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableBookmark(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableDraft(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePoll(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePoll_Option(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePoll_Vote(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTablePoll_Ballot(); err != nil {
+		return err
+	}
+
+	if err := s.BackfillPoll_Ballot(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableReport(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableModeration_Action(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableTrending(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableStory(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableStory_View(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableAlbum(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableAlbum_Photo(); err != nil {
+		return err
+	}
+
+	if err := s.BackfillAlbum(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableNotification_Preference(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableNotification_Quiet_Hour(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableNotification_Mute(); err != nil {
+		return err
+	}
+
+	if err := s.CreateTableNotification_Queue(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,278 @@
+package filter
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// content that about to be written, filter can change Text (masking) or reject it
+type ContentType struct {
+	User_ID string
+	Kind    string // post, comment or message
+	Text    string
+	Edit    bool // edit of existing content, spam check that count new content skip it
+}
+
+// RejectionType tell the caller which filter rejected the content and why
+type RejectionType struct {
+	Filter string `json:"filter"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+func (r *RejectionType) Error() string {
+	return r.Reason
+}
+
+// response body when REST caller is rejected
+type RejectedResType struct {
+	Error     string         `json:"error"`
+	Rejection *RejectionType `json:"rejection"`
+}
+
+// AsRejection return rejection inside err, nil when err is not from filter
+func AsRejection(err error) *RejectionType {
+	var rej *RejectionType
+	if errors.As(err, &rej) {
+		return rej
+	}
+
+	return nil
+}
+
+// Filter check one content, return *RejectionType to reject or other error when it can't check
+type Filter interface {
+	Apply(c *ContentType) error
+}
+
+// Pipeline run every filter in order and stop at the first rejection
+type Pipeline struct {
+	filters []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// Apply run the pipeline, nil pipeline accept everything.
+// Content without text (e.g. image only post) still go through so burst limit count it,
+// filter that look at the text let it pass.
+func (p *Pipeline) Apply(c *ContentType) error {
+	if p == nil {
+		return nil
+	}
+
+	for _, f := range p.filters {
+		if err := f.Apply(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// build pipeline from env so every deployment can tune it
+//
+//	FILTER_BLOCK_WORDS    comma separated word that reject content
+//	FILTER_MASK_WORDS     comma separated word that replaced with *
+//	FILTER_MAX_LINKS      maximum link in one content, default 5
+//	FILTER_REPEAT_WINDOW  same text from same user inside window is rejected, default 10m, 0 to disable
+//	FILTER_REPEAT_KINDS   kind checked for repeat, default post,comment
+//	FILTER_BURST_WINDOW   window of burst limit, default 1m
+//	FILTER_BURST_POST     maximum post inside burst window, default 10, 0 to disable
+//	FILTER_BURST_COMMENT  maximum comment inside burst window, default 20, 0 to disable
+//	FILTER_BURST_MESSAGE  maximum message inside burst window, default 30, 0 to disable
+func NewPipelineFromEnv(history History) (*Pipeline, error) {
+	filters := []Filter{}
+
+	block := splitEnv("FILTER_BLOCK_WORDS", "")
+	mask := splitEnv("FILTER_MASK_WORDS", "")
+	if len(block) > 0 || len(mask) > 0 {
+		filters = append(filters, NewWordFilter(block, mask))
+	}
+
+	maxLinks, err := intEnv("FILTER_MAX_LINKS", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	filters = append(filters, &LinkFilter{Max: maxLinks})
+
+	repeatWindow, err := durationEnv("FILTER_REPEAT_WINDOW", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	if repeatWindow > 0 {
+		kinds := map[string]bool{}
+		for _, k := range splitEnv("FILTER_REPEAT_KINDS", "post,comment") {
+			kinds[k] = true
+		}
+
+		filters = append(filters, &RepeatFilter{History: history, Window: repeatWindow, Kinds: kinds})
+	}
+
+	burstWindow, err := durationEnv("FILTER_BURST_WINDOW", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := map[string]int{}
+	for kind, def := range map[string]int{"post": 10, "comment": 20, "message": 30} {
+		limit, err := intEnv("FILTER_BURST_"+strings.ToUpper(kind), def)
+		if err != nil {
+			return nil, err
+		}
+
+		if limit > 0 {
+			limits[kind] = limit
+		}
+	}
+
+	if burstWindow > 0 && len(limits) > 0 {
+		filters = append(filters, &BurstFilter{History: history, Window: burstWindow, Limits: limits})
+	}
+
+	return NewPipeline(filters...), nil
+}
+
+func splitEnv(key, def string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = def
+	}
+
+	res := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+func intEnv(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New(key + " must be a number")
+	}
+
+	return n, nil
+}
+
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	if value == "0" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New(key + " must be a duration like 10m")
+	}
+
+	return d, nil
+}
+
+// WordFilter reject content that has blocked word and mask the masked word, match whole word and ignore case
+type WordFilter struct {
+	Block map[string]bool
+	Mask  map[string]bool
+}
+
+func NewWordFilter(block, mask []string) *WordFilter {
+	f := &WordFilter{Block: map[string]bool{}, Mask: map[string]bool{}}
+	for _, w := range block {
+		f.Block[strings.ToLower(w)] = true
+	}
+
+	for _, w := range mask {
+		f.Mask[strings.ToLower(w)] = true
+	}
+
+	return f
+}
+
+func (f *WordFilter) Apply(c *ContentType) error {
+	if isBlank(c.Text) {
+		return nil
+	}
+
+	text := []rune(c.Text)
+	masked := false
+
+	for i := 0; i < len(text); {
+		if !isWordRune(text[i]) {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(text) && isWordRune(text[j]) {
+			j++
+		}
+
+		word := strings.ToLower(string(text[i:j]))
+		if f.Block[word] {
+			return &RejectionType{Filter: "word", Code: "blocked_word", Reason: "content has word that is not allowed"}
+		}
+
+		if f.Mask[word] {
+			for k := i; k < j; k++ {
+				text[k] = '*'
+			}
+
+			masked = true
+		}
+
+		i = j
+	}
+
+	if masked {
+		c.Text = string(text)
+	}
+
+	return nil
+}
+
+func isBlank(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var linkRegex = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+`)
+
+// LinkFilter limit number of link in one content
+type LinkFilter struct {
+	Max int
+}
+
+func (f *LinkFilter) Apply(c *ContentType) error {
+	if isBlank(c.Text) {
+		return nil
+	}
+
+	if n := len(linkRegex.FindAllStringIndex(c.Text, -1)); n > f.Max {
+		return &RejectionType{Filter: "link", Code: "too_many_links", Reason: "content has more than " + strconv.Itoa(f.Max) + " links"}
+	}
+
+	return nil
+}
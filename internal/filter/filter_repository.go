@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Repository is History backed by post and message table
+type Repository struct {
+	db DBTX
+}
+
+func NewFilterRepository(db DBTX) *Repository {
+	return &Repository{db: db}
+}
+
+// table and extra condition of each kind
+func kindSource(kind string) (string, error) {
+	switch kind {
+	case "post":
+		return "post where type in ('main', 'quote') and", nil
+	case "comment":
+		return "post where type = 'child' and", nil
+	case "message":
+		return "message where", nil
+	}
+
+	return "", fmt.Errorf("unknown content kind %s", kind)
+}
+
+// count content of kind written by user since the time
+func (r *Repository) CountRecent(user_id, kind string, since time.Time) (int, error) {
+	source, err := kindSource(kind)
+	if err != nil {
+		return 0, err
+	}
+
+	var number int
+
+	query := "select count(*) as `number` from " + source + " user_id = ? and created_at >= ?;"
+	err = r.db.QueryRow(query, user_id, since).Scan(&number)
+	if err != nil {
+		log.Println("1. CountRecent", err)
+		return 0, err
+	}
+
+	return number, nil
+}
+
+// count content of kind with the same text written by user since the time
+func (r *Repository) CountSame(user_id, kind, text string, since time.Time) (int, error) {
+	source, err := kindSource(kind)
+	if err != nil {
+		return 0, err
+	}
+
+	var number int
+
+	query := "select count(*) as `number` from " + source + " user_id = ? and created_at >= ? and content = ?;"
+	err = r.db.QueryRow(query, user_id, since, text).Scan(&number)
+	if err != nil {
+		log.Println("1. CountSame", err)
+		return 0, err
+	}
+
+	return number, nil
+}
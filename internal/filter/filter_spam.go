@@ -0,0 +1,59 @@
+package filter
+
+import "time"
+
+// History count content that user wrote before, kept in database so every instance see the same
+type History interface {
+	CountRecent(user_id, kind string, since time.Time) (int, error)
+	CountSame(user_id, kind, text string, since time.Time) (int, error)
+}
+
+// RepeatFilter reject the same text posted again by the same user inside the window
+type RepeatFilter struct {
+	History History
+	Window  time.Duration
+	Kinds   map[string]bool
+}
+
+func (f *RepeatFilter) Apply(c *ContentType) error {
+	// image only content is not the same content just because both has no text
+	if c.Edit || !f.Kinds[c.Kind] || isBlank(c.Text) {
+		return nil
+	}
+
+	n, err := f.History.CountSame(c.User_ID, c.Kind, c.Text, time.Now().UTC().Add(-f.Window))
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return &RejectionType{Filter: "repeat", Code: "repeated_content", Reason: "you already posted the same " + c.Kind + " recently"}
+	}
+
+	return nil
+}
+
+// BurstFilter limit how many content of each kind user can write inside the window
+type BurstFilter struct {
+	History History
+	Window  time.Duration
+	Limits  map[string]int
+}
+
+func (f *BurstFilter) Apply(c *ContentType) error {
+	limit, ok := f.Limits[c.Kind]
+	if c.Edit || !ok {
+		return nil
+	}
+
+	n, err := f.History.CountRecent(c.User_ID, c.Kind, time.Now().UTC().Add(-f.Window))
+	if err != nil {
+		return err
+	}
+
+	if n >= limit {
+		return &RejectionType{Filter: "burst", Code: "rate_limited", Reason: "too many " + c.Kind + " in a short time, try again later"}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWordFilter(t *testing.T) {
+	f := NewWordFilter([]string{"Spam", "scam"}, []string{"darn", "héck"})
+
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		reject bool
+	}{
+		{"clean", "hello world", "hello world", false},
+		{"empty", "", "", false},
+		{"blocked", "buy spam now", "", true},
+		{"blocked ignore case", "SCAM alert", "", true},
+		{"blocked next to punctuation", "this is (spam)!", "", true},
+		{"blocked inside word is fine", "spammer and scampi", "spammer and scampi", false},
+		{"masked", "darn it", "**** it", false},
+		{"masked ignore case keep length", "DARN, Darn", "****, ****", false},
+		{"masked inside word is fine", "darnation", "darnation", false},
+		{"masked unicode", "what the héck.", "what the ****.", false},
+		{"masked then blocked", "darn spam", "", true},
+		{"digit is part of word", "spam2 darn1", "spam2 darn1", false},
+	}
+
+	for _, tt := range tests {
+		c := &ContentType{Kind: "post", Text: tt.text}
+		err := f.Apply(c)
+
+		if tt.reject {
+			if rej := AsRejection(err); rej == nil || rej.Code != "blocked_word" {
+				t.Errorf("%s: Apply() err %v, want blocked_word", tt.name, err)
+			}
+
+			continue
+		}
+
+		if err != nil || c.Text != tt.want {
+			t.Errorf("%s: Apply() = %q, %v, want %q", tt.name, c.Text, err, tt.want)
+		}
+	}
+}
+
+// history where every user already posted n times, the same text included
+type fakeHistory struct {
+	n int
+}
+
+func (h fakeHistory) CountRecent(user_id, kind string, since time.Time) (int, error) {
+	return h.n, nil
+}
+
+func (h fakeHistory) CountSame(user_id, kind, text string, since time.Time) (int, error) {
+	return h.n, nil
+}
+
+func TestPipelineEmptyText(t *testing.T) {
+	pipeline := func(n int) *Pipeline {
+		h := fakeHistory{n: n}
+		return NewPipeline(
+			NewWordFilter([]string{"spam"}, nil),
+			&LinkFilter{Max: 0},
+			&RepeatFilter{History: h, Window: time.Minute, Kinds: map[string]bool{"post": true}},
+			&BurstFilter{History: h, Window: time.Minute, Limits: map[string]int{"post": 3}},
+		)
+	}
+
+	tests := []struct {
+		name    string
+		history int
+		text    string
+		code    string // empty when accepted
+	}{
+		{"image only post under burst limit", 2, "", ""},
+		{"blank text under burst limit", 2, "  \n\t", ""},
+		{"image only post over burst limit", 3, "", "rate_limited"},
+		{"text is still repeat checked", 1, "hello", "repeated_content"},
+		{"nil pipeline", -1, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p *Pipeline
+			if tt.history >= 0 {
+				p = pipeline(tt.history)
+			}
+
+			err := p.Apply(&ContentType{User_ID: "u1", Kind: "post", Text: tt.text})
+
+			code := ""
+			if rej := AsRejection(err); rej != nil {
+				code = rej.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if code != tt.code {
+				t.Errorf("Apply() rejection %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
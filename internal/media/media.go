@@ -0,0 +1,163 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"time"
+)
+
+// maximum size of uploaded file
+const MaxUploadSize = 10 << 20
+
+// maximum pixel of uploaded image, small file can still decode to huge image
+const MaxPixels = 25_000_000
+
+type MediaType struct {
+	Media_ID     string         `json:"media_id"`
+	User_ID      string         `json:"user_id"`
+	Storage_Key  string         `json:"-"`
+	Content_Type string         `json:"content_type"`
+	Size         int64          `json:"size"`
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	Blurhash     string         `json:"blurhash"`
+	URL          string         `json:"url"`
+	Variants     []*VariantType `json:"variants"`
+	Created_At   time.Time      `json:"created_at"`
+}
+
+// media record that does not exist (anymore)
+var ErrNotFound = fmt.Errorf("media not found")
+
+// content type that accepted and the extension used in storage
+var AllowedTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Sniff detect content type from the file itself, client supplied type is not trusted
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := AllowedTypes[contentType]; !ok {
+		return "", fmt.Errorf("file type %s not allowed", contentType)
+	}
+
+	return contentType, nil
+}
+
+// decode only the header to get dimension and make sure the file is real image,
+// it must be checked before the whole image is decoded
+func Dimension(data []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid image")
+	}
+
+	if cfg.Width < 1 || cfg.Height < 1 {
+		return 0, 0, fmt.Errorf("invalid image")
+	}
+
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return 0, 0, fmt.Errorf("image larger than %d megapixel", MaxPixels/1_000_000)
+	}
+
+	return cfg.Width, cfg.Height, nil
+}
+
+// StripMetadata remove EXIF and other metadata that can leak location or device
+func StripMetadata(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	}
+
+	return data, nil
+}
+
+// drop APP1 (exif, xmp) and APP13 (iptc) segment, everything from SOS is copied as is
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("invalid jpeg")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil, fmt.Errorf("invalid jpeg")
+		}
+
+		marker := data[i+1]
+
+		// start of scan, the rest is image data
+		if marker == 0xDA {
+			out.Write(data[i:])
+			return out.Bytes(), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, fmt.Errorf("invalid jpeg")
+		}
+
+		if marker != 0xE1 && marker != 0xED {
+			out.Write(data[i:end])
+		}
+
+		i = end
+	}
+
+	return nil, fmt.Errorf("invalid jpeg")
+}
+
+// chunk that only hold metadata
+var pngMetaChunk = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("invalid png")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:8])
+
+	i := 8
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunk := string(data[i+4 : i+8])
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, fmt.Errorf("invalid png")
+		}
+
+		if !pngMetaChunk[chunk] {
+			out.Write(data[i:end])
+		}
+
+		i = end
+
+		if chunk == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid png")
+}
@@ -0,0 +1,281 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/erlnerlngga/backend-socius/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	Repository *Repository
+	Store      BlobStore
+}
+
+func NewMediaHandler(r *Repository, s BlobStore) *Handler {
+	return &Handler{
+		Repository: r,
+		Store:      s,
+	}
+}
+
+// upload one image as multipart form field "file", return media that can be referenced by id
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) error {
+	// small extra room for the other multipart part
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize+1<<20)
+
+	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
+		log.Println("1. Upload", err)
+		return fmt.Errorf("file too large or invalid form")
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Println("2. Upload", err)
+		return err
+	}
+
+	defer file.Close()
+
+	if header.Size > MaxUploadSize {
+		return fmt.Errorf("file larger than %d MB", MaxUploadSize>>20)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(file, MaxUploadSize+1)); err != nil {
+		log.Println("3. Upload", err)
+		return err
+	}
+
+	if buf.Len() > MaxUploadSize {
+		return fmt.Errorf("file larger than %d MB", MaxUploadSize>>20)
+	}
+
+	contentType, err := Sniff(buf.Bytes())
+	if err != nil {
+		log.Println("4. Upload", err)
+		return err
+	}
+
+	width, height, err := Dimension(buf.Bytes())
+	if err != nil {
+		log.Println("5. Upload", err)
+		return err
+	}
+
+	orientation := Orientation(buf.Bytes(), contentType)
+
+	data, err := StripMetadata(buf.Bytes(), contentType)
+	if err != nil {
+		log.Println("6. Upload", err)
+		return err
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Println("7. Upload", err)
+		return fmt.Errorf("invalid image")
+	}
+
+	img := ToRGBA(decoded)
+
+	// orientation tag is gone with the metadata, so the pixel of the original are turned instead
+	if orientation != 1 {
+		img = Orient(img, orientation)
+		width, height = img.Bounds().Dx(), img.Bounds().Dy()
+
+		var out bytes.Buffer
+		if contentType == "image/jpeg" {
+			err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+		} else {
+			err = png.Encode(&out, img)
+		}
+
+		if err != nil {
+			log.Println("8. Upload", err)
+			return err
+		}
+
+		data = out.Bytes()
+	}
+
+	variants, err := generateVariant(img, contentType)
+	if err != nil {
+		log.Println("9. Upload", err)
+		return err
+	}
+
+	mediaID, key := NewKey(contentType)
+
+	// keys written so far, removed again when something fail
+	written := []string{}
+	cleanup := func() {
+		for _, k := range written {
+			h.Store.Delete(k)
+		}
+	}
+
+	if err := h.Store.Put(key, data, contentType); err != nil {
+		log.Println("10. Upload", err)
+		return err
+	}
+
+	written = append(written, key)
+
+	for _, v := range variants {
+		v.Variant.Media_ID = mediaID
+		v.Variant.Storage_Key = VariantKey(mediaID, v.Variant.Name, v.Variant.Content_Type)
+
+		if err := h.Store.Put(v.Variant.Storage_Key, v.Data, v.Variant.Content_Type); err != nil {
+			log.Println("11. Upload", err)
+			cleanup()
+			return err
+		}
+
+		written = append(written, v.Variant.Storage_Key)
+	}
+
+	m := &MediaType{
+		Media_ID:     mediaID,
+		User_ID:      util.GetUserID(r),
+		Storage_Key:  key,
+		Content_Type: contentType,
+		Size:         int64(len(data)),
+		Width:        width,
+		Height:       height,
+		Blurhash:     Blurhash(img),
+		Variants:     []*VariantType{},
+	}
+
+	// media and its variants are saved together, so media id never exist with half the variant
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		if err := tx.CreateMedia(m); err != nil {
+			log.Println("12. Upload", err)
+			return err
+		}
+
+		for _, v := range variants {
+			if err := tx.CreateVariant(v.Variant); err != nil {
+				log.Println("13. Upload", err)
+				return err
+			}
+
+			m.Variants = append(m.Variants, v.Variant)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		cleanup()
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, m)
+}
+
+// serve the stored file, media id is random so it is public like the old cdn url
+func (h *Handler) GetMedia(w http.ResponseWriter, r *http.Request) error {
+	mediaID := chi.URLParam(r, "mediaID")
+
+	m, err := h.Repository.GetMedia(mediaID)
+	if err != nil {
+		log.Println("1. GetMedia", err)
+		return util.WriteJSON(w, http.StatusNotFound, util.ApiError{Error: err.Error()})
+	}
+
+	file, err := h.Store.Get(m.Storage_Key)
+	if err != nil {
+		log.Println("2. GetMedia", err)
+		return err
+	}
+
+	defer file.Close()
+
+	w.Header().Set("Content-Type", m.Content_Type)
+	w.Header().Set("Content-Length", fmt.Sprint(m.Size))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, file); err != nil {
+		log.Println("3. GetMedia", err)
+	}
+
+	return nil
+}
+
+// serve generated variant of media, e.g. /media/{mediaID}/thumb
+func (h *Handler) GetVariant(w http.ResponseWriter, r *http.Request) error {
+	mediaID := chi.URLParam(r, "mediaID")
+	name := chi.URLParam(r, "variant")
+
+	v, err := h.Repository.GetVariant(mediaID, name)
+	if err != nil {
+		log.Println("1. GetVariant", err)
+		return util.WriteJSON(w, http.StatusNotFound, util.ApiError{Error: err.Error()})
+	}
+
+	file, err := h.Store.Get(v.Storage_Key)
+	if err != nil {
+		log.Println("2. GetVariant", err)
+		return err
+	}
+
+	defer file.Close()
+
+	w.Header().Set("Content-Type", v.Content_Type)
+	w.Header().Set("Content-Length", fmt.Sprint(v.Size))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, file); err != nil {
+		log.Println("3. GetVariant", err)
+	}
+
+	return nil
+}
+
+// DeleteMedia remove media record and every stored file of it, used when the owner of media is gone.
+// Media that is already gone is not an error so caller can retry.
+func (h *Handler) DeleteMedia(media_id string) error {
+	m, err := h.Repository.GetMedia(media_id)
+	if err == ErrNotFound {
+		// already gone, e.g. caller retry after failing halfway
+		return nil
+	}
+
+	if err != nil {
+		log.Println("1. DeleteMedia", err)
+		return err
+	}
+
+	if err := h.Repository.DeleteMedia(media_id); err != nil {
+		log.Println("2. DeleteMedia", err)
+		return err
+	}
+
+	// record is gone first so file that fail to delete is only orphan and never served half
+	keys := []string{m.Storage_Key}
+	for _, v := range m.Variants {
+		keys = append(keys, v.Storage_Key)
+	}
+
+	for _, key := range keys {
+		if err := h.Store.Delete(key); err != nil {
+			log.Println("3. DeleteMedia", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,291 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// size of generated variant, thumb is square crop and the other keep aspect ratio.
+// every size is also generated as webp with "_webp" after the name.
+type variantSpec struct {
+	Name   string
+	Width  int
+	Square bool
+}
+
+var variantSpecs = []variantSpec{
+	{Name: "thumb", Width: 200, Square: true},
+	{Name: "w320", Width: 320},
+	{Name: "w640", Width: 640},
+	{Name: "w1280", Width: 1280},
+}
+
+// extension of stored variant by content type
+var variantExt = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type VariantType struct {
+	Media_ID     string `json:"-"`
+	Name         string `json:"name"`
+	Storage_Key  string `json:"-"`
+	Content_Type string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Size         int64  `json:"size"`
+	URL          string `json:"url"`
+}
+
+// encoded variant ready to store
+type variantFile struct {
+	Variant *VariantType
+	Data    []byte
+}
+
+// ToRGBA convert decoded image once, every variant and the blurhash read from it
+func ToRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	return rgba
+}
+
+// make every variant smaller than the original, png stay png so transparency is kept
+func generateVariant(src *image.RGBA, contentType string) ([]*variantFile, error) {
+	outType := "image/jpeg"
+	if contentType != "image/jpeg" {
+		outType = "image/png"
+	}
+
+	b := src.Bounds()
+	files := []*variantFile{}
+
+	for _, spec := range variantSpecs {
+		var img *image.RGBA
+
+		if spec.Square {
+			side := b.Dx()
+			if b.Dy() < side {
+				side = b.Dy()
+			}
+
+			x := b.Min.X + (b.Dx()-side)/2
+			y := b.Min.Y + (b.Dy()-side)/2
+			size := spec.Width
+			if side < size {
+				size = side
+			}
+
+			img = resize(src, image.Rect(x, y, x+side, y+side), size, size)
+		} else {
+			if spec.Width >= b.Dx() {
+				continue
+			}
+
+			height := int(math.Round(float64(b.Dy()) * float64(spec.Width) / float64(b.Dx())))
+			if height < 1 {
+				height = 1
+			}
+
+			img = resize(src, b, spec.Width, height)
+		}
+
+		var buf bytes.Buffer
+		var err error
+		if outType == "image/jpeg" {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 82})
+		} else {
+			err = png.Encode(&buf, img)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		webp, err := EncodeWebP(img)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, newVariantFile(spec.Name, outType, img, buf.Bytes()), newVariantFile(spec.Name+"_webp", "image/webp", img, webp))
+	}
+
+	return files, nil
+}
+
+func newVariantFile(name, contentType string, img *image.RGBA, data []byte) *variantFile {
+	return &variantFile{
+		Variant: &VariantType{
+			Name:         name,
+			Content_Type: contentType,
+			Width:        img.Bounds().Dx(),
+			Height:       img.Bounds().Dy(),
+			Size:         int64(len(data)),
+		},
+		Data: data,
+	}
+}
+
+// resize area r of src to w x h by averaging every source pixel that fall in the target pixel,
+// src is read in place so no full size copy is made
+func resize(src *image.RGBA, r image.Rectangle, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := r.Dx(), r.Dy()
+
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := (y + 1) * sh / h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := (x + 1) * sw / w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var sr, sg, sb, sa, n int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(r.Min.X+x0, r.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					sr += int(src.Pix[i])
+					sg += int(src.Pix[i+1])
+					sb += int(src.Pix[i+2])
+					sa += int(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(sr / n)
+			dst.Pix[j+1] = uint8(sg / n)
+			dst.Pix[j+2] = uint8(sb / n)
+			dst.Pix[j+3] = uint8(sa / n)
+		}
+	}
+
+	return dst
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash encode small placeholder of the image, see https://blurha.sh
+func Blurhash(src *image.RGBA) string {
+	const compX, compY = 4, 3
+
+	// hash only need very small image
+	b := src.Bounds()
+	w, h := 32, int(math.Round(32*float64(b.Dy())/float64(b.Dx())))
+	if h < 1 {
+		h = 1
+	}
+
+	img := resize(src, b, w, h)
+
+	factors := make([][3]float64, 0, compX*compY)
+	for j := 0; j < compY; j++ {
+		for i := 0; i < compX; i++ {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1.0
+			}
+
+			var r, g, bl float64
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) * math.Cos(math.Pi*float64(j)*float64(y)/float64(h))
+					k := img.PixOffset(x, y)
+					r += basis * srgbToLinear(img.Pix[k])
+					g += basis * srgbToLinear(img.Pix[k+1])
+					bl += basis * srgbToLinear(img.Pix[k+2])
+				}
+			}
+
+			scale := norm / float64(w*h)
+			factors = append(factors, [3]float64{r * scale, g * scale, bl * scale})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(encode83((compX-1)+(compY-1)*9, 1))
+
+	maxValue := 1.0
+	if len(factors) > 1 {
+		actualMax := 0.0
+		for _, f := range factors[1:] {
+			for _, v := range f {
+				actualMax = math.Max(actualMax, math.Abs(v))
+			}
+		}
+
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantised+1) / 166
+		sb.WriteString(encode83(quantised, 1))
+	} else {
+		sb.WriteString(encode83(0, 1))
+	}
+
+	dc := factors[0]
+	sb.WriteString(encode83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+
+	for _, f := range factors[1:] {
+		q := func(v float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+
+		sb.WriteString(encode83(q(f[0])*19*19+q(f[1])*19+q(f[2]), 2))
+	}
+
+	return sb.String()
+}
+
+func encode83(value, length int) string {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = base83Chars[value%83]
+		value /= 83
+	}
+
+	return string(out)
+}
+
+func srgbToLinear(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(math.Round(v * 12.92 * 255))
+	}
+
+	return int(math.Round((1.055*math.Pow(v, 1/2.4) - 0.055) * 255))
+}
+
+func signPow(v, exp float64) float64 {
+	if v < 0 {
+		return -math.Pow(-v, exp)
+	}
+
+	return math.Pow(v, exp)
+}
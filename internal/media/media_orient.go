@@ -0,0 +1,154 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// Orientation read EXIF orientation 1 - 8 from jpeg APP1 or png eXIf, 1 when there is none.
+// It must be read before StripMetadata because the tag is stripped with the rest of EXIF.
+func Orientation(data []byte, contentType string) int {
+	switch contentType {
+	case "image/jpeg":
+		return jpegOrientation(data)
+	case "image/png":
+		return pngOrientation(data)
+	}
+
+	return 1
+}
+
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		marker := data[i+1]
+		if data[i] != 0xFF || marker == 0xDA {
+			return 1
+		}
+
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) {
+			return 1
+		}
+
+		if seg := data[i+4 : end]; marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return exifOrientation(seg[6:])
+		}
+
+		i = end
+	}
+
+	return 1
+}
+
+func pngOrientation(data []byte) int {
+	i := 8
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunk := string(data[i+4 : i+8])
+		if length < 0 || i+12+length > len(data) || chunk == "IDAT" {
+			return 1
+		}
+
+		if chunk == "eXIf" {
+			return exifOrientation(data[i+8 : i+8+length])
+		}
+
+		i += 12 + length
+	}
+
+	return 1
+}
+
+// look for orientation tag (0x0112) in the first IFD of tiff data
+func exifOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	if order.Uint16(t[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int(order.Uint32(t[4:]))
+	if ifd < 8 || ifd+2 > len(t) {
+		return 1
+	}
+
+	n := int(order.Uint16(t[ifd:]))
+	for k := 0; k < n; k++ {
+		e := ifd + 2 + k*12
+		if e+12 > len(t) {
+			return 1
+		}
+
+		// type 3 is SHORT, value is in the first two byte of the value field
+		if order.Uint16(t[e:]) == 0x0112 && order.Uint16(t[e+2:]) == 3 {
+			if v := int(order.Uint16(t[e+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+
+			return 1
+		}
+	}
+
+	return 1
+}
+
+// Orient turn img so it is shown upright for EXIF orientation o, 5 - 8 swap width and height
+func Orient(img *image.RGBA, o int) *image.RGBA {
+	if o < 2 || o > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if o >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // mirror
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 counter clockwise
+				dx, dy = y, w-1-x
+			}
+
+			s := img.PixOffset(b.Min.X+x, b.Min.Y+y)
+			d := dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], img.Pix[s:s+4])
+		}
+	}
+
+	return dst
+}
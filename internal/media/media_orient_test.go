@@ -0,0 +1,110 @@
+package media
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// tiff with one IFD entry
+func exifTIFF(order binary.ByteOrder, tag, typ, value uint16) []byte {
+	t := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(t, "II")
+	} else {
+		copy(t, "MM")
+	}
+
+	order.PutUint16(t[2:], 42)
+	order.PutUint32(t[4:], 8)
+	order.PutUint16(t[8:], 1)
+	order.PutUint16(t[10:], tag)
+	order.PutUint16(t[12:], typ)
+	order.PutUint32(t[14:], 1)
+	order.PutUint16(t[18:], value)
+
+	return t
+}
+
+func jpegWithExif(t *testing.T, tiff []byte) []byte {
+	plain := testJPEG(t)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(seg)+2))
+
+	out := append([]byte{}, plain[:2]...)
+	out = append(out, app1...)
+	out = append(out, seg...)
+	return append(out, plain[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	plainPNG := testPNG(t)
+	exifPNG := append(append(append([]byte{}, plainPNG[:33]...), pngChunk("eXIf", exifTIFF(binary.BigEndian, 0x0112, 3, 8))...), plainPNG[33:]...)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        int
+	}{
+		{"jpeg little endian", jpegWithExif(t, exifTIFF(binary.LittleEndian, 0x0112, 3, 6)), "image/jpeg", 6},
+		{"jpeg big endian", jpegWithExif(t, exifTIFF(binary.BigEndian, 0x0112, 3, 3)), "image/jpeg", 3},
+		{"jpeg without exif", testJPEG(t), "image/jpeg", 1},
+		{"jpeg other tag", jpegWithExif(t, exifTIFF(binary.LittleEndian, 0x010f, 3, 6)), "image/jpeg", 1},
+		{"jpeg wrong type", jpegWithExif(t, exifTIFF(binary.LittleEndian, 0x0112, 4, 6)), "image/jpeg", 1},
+		{"jpeg out of range", jpegWithExif(t, exifTIFF(binary.LittleEndian, 0x0112, 3, 9)), "image/jpeg", 1},
+		{"jpeg truncated ifd", jpegWithExif(t, exifTIFF(binary.LittleEndian, 0x0112, 3, 6)[:16]), "image/jpeg", 1},
+		{"jpeg bad byte order", jpegWithExif(t, append([]byte("XX"), exifTIFF(binary.LittleEndian, 0x0112, 3, 6)[2:]...)), "image/jpeg", 1},
+		{"jpeg garbage", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}, "image/jpeg", 1},
+		{"png exif", exifPNG, "image/png", 8},
+		{"png without exif", plainPNG, "image/png", 1},
+		{"gif", []byte("GIF89a"), "image/gif", 1},
+	}
+
+	for _, tt := range tests {
+		if got := Orientation(tt.data, tt.contentType); got != tt.want {
+			t.Errorf("%s: Orientation() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 3 x 2, every pixel has its own red value
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			src.Set(x, y, color.RGBA{uint8(10*y + x), 0, 0, 255})
+		}
+	}
+
+	// red value of the upright image row by row
+	want := map[int][][]uint8{
+		1: {{0, 1, 2}, {10, 11, 12}},
+		2: {{2, 1, 0}, {12, 11, 10}},
+		3: {{12, 11, 10}, {2, 1, 0}},
+		4: {{10, 11, 12}, {0, 1, 2}},
+		5: {{0, 10}, {1, 11}, {2, 12}},
+		6: {{10, 0}, {11, 1}, {12, 2}},
+		7: {{12, 2}, {11, 1}, {10, 0}},
+		8: {{2, 12}, {1, 11}, {0, 10}},
+	}
+
+	for o, rows := range want {
+		got := Orient(src, o)
+		if got.Bounds().Dx() != len(rows[0]) || got.Bounds().Dy() != len(rows) {
+			t.Errorf("orientation %d: size %v", o, got.Bounds())
+			continue
+		}
+
+		for y, row := range rows {
+			for x, r := range row {
+				if c := got.RGBAAt(x, y); c.R != r {
+					t.Errorf("orientation %d: pixel %d,%d = %d, want %d", o, x, y, c.R, r)
+				}
+			}
+		}
+	}
+}
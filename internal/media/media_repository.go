@@ -0,0 +1,224 @@
+package media
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type Repository struct {
+	db DBTX
+}
+
+func NewMediaRepository(db DBTX) *Repository {
+	return &Repository{db: db}
+}
+
+// run fn inside one transaction, rollback when fn return error
+func (r *Repository) WithTx(fn func(tx *Repository) error) error {
+	db, ok := r.db.(*sql.DB)
+	if !ok {
+		return fn(r)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("1. WithTx", err)
+		return err
+	}
+
+	if err := fn(&Repository{db: tx}); err != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Println("2. WithTx", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("3. WithTx", err)
+		return err
+	}
+
+	return nil
+}
+
+// public url of media, served by GET /media/{mediaID}
+func URL(media_id string) string {
+	return strings.TrimSuffix(os.Getenv("MEDIA_BASE_URL"), "/") + "/media/" + media_id
+}
+
+// public url of media variant, served by GET /media/{mediaID}/{variant}
+func VariantURL(media_id, name string) string {
+	return URL(media_id) + "/" + name
+}
+
+// create media record after the file is stored
+func (r *Repository) CreateMedia(m *MediaType) error {
+	m.Created_At = time.Now().UTC()
+
+	query := `insert into media(media_id, user_id, storage_key, content_type, size, width, height, blurhash, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, m.Media_ID, m.User_ID, m.Storage_Key, m.Content_Type, m.Size, m.Width, m.Height, m.Blurhash, m.Created_At)
+	if err != nil {
+		log.Println("1. CreateMedia", err)
+		return err
+	}
+
+	m.URL = URL(m.Media_ID)
+
+	return nil
+}
+
+// new media id and storage key for content type
+func NewKey(contentType string) (string, string) {
+	id := uuid.New().String()
+	return id, "media/" + id + AllowedTypes[contentType]
+}
+
+// get media by id
+func (r *Repository) GetMedia(media_id string) (*MediaType, error) {
+	m := new(MediaType)
+
+	query := `select media_id, user_id, storage_key, content_type, size, width, height, blurhash, created_at from media where media_id = ?;`
+	err := r.db.QueryRow(query, media_id).Scan(&m.Media_ID, &m.User_ID, &m.Storage_Key, &m.Content_Type, &m.Size, &m.Width, &m.Height, &m.Blurhash, &m.Created_At)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		log.Println("1. GetMedia", err)
+		return nil, err
+	}
+
+	m.URL = URL(m.Media_ID)
+
+	m.Variants, err = r.GetAllVariant(m.Media_ID)
+	if err != nil {
+		log.Println("2. GetMedia", err)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// GetOwnMedia get media by id in the same order, every media must be uploaded by user_id
+func (r *Repository) GetOwnMedia(ids []string, user_id string) ([]*MediaType, error) {
+	result := []*MediaType{}
+
+	for _, id := range ids {
+		m, err := r.GetMedia(id)
+		if err != nil {
+			log.Println("1. GetOwnMedia", err)
+			return nil, err
+		}
+
+		if m.User_ID != user_id {
+			return nil, fmt.Errorf("media %s not found", id)
+		}
+
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+// storage key of variant, kept next to the original
+func VariantKey(media_id, name, contentType string) string {
+	return "media/" + media_id + "/" + name + variantExt[contentType]
+}
+
+// create variant of media
+func (r *Repository) CreateVariant(v *VariantType) error {
+	query := `insert into media_variant(media_id, name, storage_key, content_type, width, height, size) values (?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, v.Media_ID, v.Name, v.Storage_Key, v.Content_Type, v.Width, v.Height, v.Size)
+	if err != nil {
+		log.Println("1. CreateVariant", err)
+		return err
+	}
+
+	v.URL = VariantURL(v.Media_ID, v.Name)
+
+	return nil
+}
+
+// get single variant by name
+func (r *Repository) GetVariant(media_id, name string) (*VariantType, error) {
+	v := new(VariantType)
+
+	query := `select media_id, name, storage_key, content_type, width, height, size from media_variant where media_id = ? and name = ?;`
+	err := r.db.QueryRow(query, media_id, name).Scan(&v.Media_ID, &v.Name, &v.Storage_Key, &v.Content_Type, &v.Width, &v.Height, &v.Size)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("variant not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetVariant", err)
+		return nil, err
+	}
+
+	v.URL = VariantURL(v.Media_ID, v.Name)
+
+	return v, nil
+}
+
+// get all variant of media, smallest first
+func (r *Repository) GetAllVariant(media_id string) ([]*VariantType, error) {
+	query := `select media_id, name, storage_key, content_type, width, height, size from media_variant where media_id = ? order by width asc, name asc;`
+
+	rows, err := r.db.Query(query, media_id)
+	if err != nil {
+		log.Println("1. GetAllVariant", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	variants := []*VariantType{}
+	for rows.Next() {
+		v := new(VariantType)
+
+		if err := rows.Scan(&v.Media_ID, &v.Name, &v.Storage_Key, &v.Content_Type, &v.Width, &v.Height, &v.Size); err != nil {
+			log.Println("2. GetAllVariant", err)
+			return nil, err
+		}
+
+		v.URL = VariantURL(v.Media_ID, v.Name)
+		variants = append(variants, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllVariant", err)
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// delete media and its variant record, the stored file is removed by caller
+func (r *Repository) DeleteMedia(media_id string) error {
+	cascade := []string{
+		`delete from media_variant where media_id = ?;`,
+		`delete from media where media_id = ?;`,
+	}
+
+	for _, query := range cascade {
+		if _, err := r.db.Exec(query, media_id); err != nil {
+			log.Println("1. DeleteMedia", err)
+			return err
+		}
+	}
+
+	return nil
+}
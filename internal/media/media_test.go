@@ -0,0 +1,168 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), 128, 255})
+		}
+	}
+
+	return img
+}
+
+func testPNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(8, 8)); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func testJPEG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(8, 8), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func pngChunk(name string, data []byte) []byte {
+	out := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(out, uint32(len(data)))
+	copy(out[4:], name)
+	out = append(out, data...)
+
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[4:]))
+}
+
+// png with only the header, enough for DecodeConfig
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // rgba
+
+	out := []byte("\x89PNG\r\n\x1a\n")
+	return append(out, pngChunk("IHDR", ihdr)...)
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  bool
+	}{
+		{"png", testPNG(t), "image/png", false},
+		{"jpeg", testJPEG(t), "image/jpeg", false},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif", false},
+		{"html", []byte("<html><script>alert(1)</script>"), "", true},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", true},
+		{"empty", []byte{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff(tt.data)
+			if (err != nil) != tt.err || got != tt.want {
+				t.Errorf("Sniff() = %q, %v, want %q, err %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestDimension(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		width  int
+		height int
+		err    bool
+	}{
+		{"png", testPNG(t), 8, 8, false},
+		{"jpeg", testJPEG(t), 8, 8, false},
+		{"at pixel limit", pngHeader(5000, 5000), 5000, 5000, false},
+		{"over pixel limit", pngHeader(5000, 5001), 0, 0, true},
+		{"huge width", pngHeader(1<<30, 1), 0, 0, true},
+		{"zero size", pngHeader(0, 10), 0, 0, true},
+		{"garbage", []byte("not an image"), 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, err := Dimension(tt.data)
+			if (err != nil) != tt.err || w != tt.width || h != tt.height {
+				t.Errorf("Dimension() = %d, %d, %v, want %d, %d, err %v", w, h, err, tt.width, tt.height, tt.err)
+			}
+		})
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	plainPNG := testPNG(t)
+	// metadata chunk right after IHDR (8 signature + 25 IHDR)
+	exifPNG := append(append(append([]byte{}, plainPNG[:33]...), pngChunk("tEXt", []byte("GPS\x0052.1,4.3"))...), plainPNG[33:]...)
+
+	plainJPEG := testJPEG(t)
+	app1 := append([]byte{0xFF, 0xE1, 0x00, 0x10}, []byte("Exif\x00\x00GPSDATA!")...)
+	exifJPEG := append(append(append([]byte{}, plainJPEG[:2]...), app1...), plainJPEG[2:]...)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        []byte
+		err         bool
+	}{
+		{"png without metadata", plainPNG, "image/png", plainPNG, false},
+		{"png with text chunk", exifPNG, "image/png", plainPNG, false},
+		{"png without iend", plainPNG[:len(plainPNG)-12], "image/png", nil, true},
+		{"png chunk past end", exifPNG[:40], "image/png", nil, true},
+		{"png too short", []byte{0x89, 'P'}, "image/png", nil, true},
+		{"jpeg without metadata", plainJPEG, "image/jpeg", plainJPEG, false},
+		{"jpeg with exif", exifJPEG, "image/jpeg", plainJPEG, false},
+		{"jpeg bad start", []byte{0x00, 0xD8, 0xFF, 0xDA}, "image/jpeg", nil, true},
+		{"jpeg segment past end", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0xFF, 0xFF, 0x00}, "image/jpeg", nil, true},
+		{"jpeg segment length too small", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x01, 0xFF, 0xDA}, "image/jpeg", nil, true},
+		{"jpeg missing marker", []byte{0xFF, 0xD8, 0x00, 0xE0, 0x00, 0x02}, "image/jpeg", nil, true},
+		{"jpeg without scan", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x02}, "image/jpeg", nil, true},
+		{"gif as is", []byte("GIF89a"), "image/gif", []byte("GIF89a"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripMetadata(tt.data, tt.contentType)
+			if (err != nil) != tt.err || !bytes.Equal(got, tt.want) {
+				t.Errorf("StripMetadata() = %d byte, %v, want %d byte, err %v", len(got), err, len(tt.want), tt.err)
+			}
+		})
+	}
+
+	// stripped file must still decode
+	for _, data := range [][]byte{exifPNG, exifJPEG} {
+		contentType, _ := Sniff(data)
+		out, err := StripMetadata(data, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := image.Decode(bytes.NewReader(out)); err != nil {
+			t.Errorf("%s: stripped image does not decode: %v", contentType, err)
+		}
+	}
+}
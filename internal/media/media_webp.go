@@ -0,0 +1,410 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+// EncodeWebP write img as lossless webp (VP8L), see
+// https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+// It use subtract green and one average predictor for the whole image with plain prefix code,
+// no backward reference, so it is simple but still smaller than the raw pixel.
+func EncodeWebP(img *image.RGBA) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 1 || h < 1 || w > 1<<14 || h > 1<<14 {
+		return nil, fmt.Errorf("webp size %dx%d not supported", w, h)
+	}
+
+	argb := make([]uint32, w*h)
+	opaque := true
+	for y := 0; y < h; y++ {
+		i := img.PixOffset(b.Min.X, b.Min.Y+y)
+		for x := 0; x < w; x++ {
+			p := img.Pix[i : i+4]
+			r, g, bl, a := uint32(p[0]), uint32(p[1]), uint32(p[2]), uint32(p[3])
+
+			// RGBA is alpha premultiplied, webp is not
+			if a < 255 {
+				c := color.NRGBAModel.Convert(color.RGBA{p[0], p[1], p[2], p[3]}).(color.NRGBA)
+				r, g, bl = uint32(c.R), uint32(c.G), uint32(c.B)
+			}
+
+			if a != 255 {
+				opaque = false
+			}
+
+			argb[y*w+x] = a<<24 | r<<16 | g<<8 | bl
+			i += 4
+		}
+	}
+
+	bw := new(bitWriter)
+	bw.write(0x2f, 8)
+	bw.write(uint32(w-1), 14)
+	bw.write(uint32(h-1), 14)
+	if opaque {
+		bw.write(0, 1)
+	} else {
+		bw.write(1, 1)
+	}
+	bw.write(0, 3)
+
+	// subtract green transform
+	bw.write(1, 1)
+	bw.write(2, 2)
+	for i, p := range argb {
+		g := (p >> 8) & 0xff
+		r := ((p >> 16) - g) & 0xff
+		bl := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | bl
+	}
+
+	// predictor transform, biggest block so the mode image is tiny
+	const sizeBits = 9
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(sizeBits-2, 3)
+
+	mw, mh := (w+1<<sizeBits-1)>>sizeBits, (h+1<<sizeBits-1)>>sizeBits
+	modes := make([]uint32, mw*mh)
+	for i := range modes {
+		modes[i] = 0xff000000 | predictAverage<<8
+	}
+
+	writeImage(bw, modes)
+
+	residual := make([]uint32, len(argb))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var pred uint32
+			switch {
+			case x == 0 && y == 0:
+				pred = 0xff000000
+			case y == 0:
+				pred = argb[x-1]
+			case x == 0:
+				pred = argb[(y-1)*w]
+			default:
+				pred = average2(argb[y*w+x-1], argb[(y-1)*w+x])
+			}
+
+			residual[y*w+x] = subPixel(argb[y*w+x], pred)
+		}
+	}
+
+	// no more transform
+	bw.write(0, 1)
+
+	// no color cache and one prefix code group for the whole image
+	bw.write(0, 1)
+	bw.write(0, 1)
+	writeCodes(bw, residual)
+
+	data := bw.bytes()
+
+	var out bytes.Buffer
+	size := 4 + 8 + len(data) + len(data)%2
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(size))
+	out.WriteString("WEBPVP8L")
+	binary.Write(&out, binary.LittleEndian, uint32(len(data)))
+	out.Write(data)
+	if len(data)%2 == 1 {
+		out.WriteByte(0)
+	}
+
+	return out.Bytes(), nil
+}
+
+// predictor mode 7, average of left and top pixel
+const predictAverage = 7
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+// subtract per channel, wrapping at 256
+func subPixel(a, b uint32) uint32 {
+	ag := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	rb := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return (ag & 0xff00ff00) | (rb & 0x00ff00ff)
+}
+
+// sub image without color cache, used for the predictor mode
+func writeImage(bw *bitWriter, pixels []uint32) {
+	bw.write(0, 1)
+	writeCodes(bw, pixels)
+}
+
+// write the five prefix code (green, red, blue, alpha, distance) then every pixel as literal
+func writeCodes(bw *bitWriter, pixels []uint32) {
+	// green alphabet has 24 length prefix after the 256 literal
+	sizes := [4]int{256 + 24, 256, 256, 256}
+	shifts := [4]uint{8, 16, 0, 24}
+
+	codes := [4]*prefixCode{}
+	for c := 0; c < 4; c++ {
+		counts := make([]int, sizes[c])
+		for _, p := range pixels {
+			counts[(p>>shifts[c])&0xff]++
+		}
+
+		codes[c] = newPrefixCode(counts, 15)
+		codes[c].writeTo(bw)
+	}
+
+	// distance is never used
+	writeSimpleCode(bw, []int{0})
+
+	for _, p := range pixels {
+		for c := 0; c < 4; c++ {
+			codes[c].writeSymbol(bw, int((p>>shifts[c])&0xff))
+		}
+	}
+}
+
+type prefixCode struct {
+	lengths []int
+	codes   []uint32 // already bit reversed
+	used    []int
+}
+
+// build length limited canonical prefix code from symbol count
+func newPrefixCode(counts []int, maxLength int) *prefixCode {
+	pc := &prefixCode{lengths: make([]int, len(counts)), codes: make([]uint32, len(counts))}
+
+	for s, n := range counts {
+		if n > 0 {
+			pc.used = append(pc.used, s)
+		}
+	}
+
+	switch len(pc.used) {
+	case 0:
+	case 1:
+		// single symbol take no bit
+	case 2:
+		pc.lengths[pc.used[0]] = 1
+		pc.lengths[pc.used[1]] = 1
+	default:
+		pc.lengths = huffmanLength(counts, maxLength)
+	}
+
+	pc.assign()
+
+	return pc
+}
+
+// huffman code length, count is halved until the longest code fit
+func huffmanLength(counts []int, maxLength int) []int {
+	weights := make([]int, len(counts))
+	copy(weights, counts)
+
+	for {
+		lengths := huffmanTree(weights)
+
+		longest := 0
+		for _, l := range lengths {
+			if l > longest {
+				longest = l
+			}
+		}
+
+		if longest <= maxLength {
+			return lengths
+		}
+
+		for i, n := range weights {
+			if n > 0 {
+				weights[i] = n>>1 | 1
+			}
+		}
+	}
+}
+
+func huffmanTree(weights []int) []int {
+	type node struct {
+		weight      int
+		symbol      int
+		left, right *node
+	}
+
+	nodes := []*node{}
+	for s, n := range weights {
+		if n > 0 {
+			nodes = append(nodes, &node{weight: n, symbol: s})
+		}
+	}
+
+	for len(nodes) > 1 {
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].weight < nodes[j].weight })
+		parent := &node{weight: nodes[0].weight + nodes[1].weight, symbol: -1, left: nodes[0], right: nodes[1]}
+		nodes = append([]*node{parent}, nodes[2:]...)
+	}
+
+	lengths := make([]int, len(weights))
+
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if n.symbol >= 0 {
+			lengths[n.symbol] = depth
+			return
+		}
+
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+
+	walk(nodes[0], 0)
+
+	return lengths
+}
+
+// canonical code like deflate, lower symbol get lower code of the same length
+func (pc *prefixCode) assign() {
+	count := make([]uint32, 16)
+	for _, l := range pc.lengths {
+		if l > 0 {
+			count[l]++
+		}
+	}
+
+	next := make([]uint32, 16)
+	code := uint32(0)
+	for l := 1; l < 16; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+
+	for s, l := range pc.lengths {
+		if l == 0 {
+			continue
+		}
+
+		pc.codes[s] = reverseBits(next[l], l)
+		next[l]++
+	}
+}
+
+func reverseBits(v uint32, n int) uint32 {
+	var r uint32
+	for i := 0; i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+
+	return r
+}
+
+func (pc *prefixCode) writeSymbol(bw *bitWriter, s int) {
+	if l := pc.lengths[s]; l > 0 {
+		bw.write(pc.codes[s], l)
+	}
+}
+
+// order the code length code length is written in
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+func (pc *prefixCode) writeTo(bw *bitWriter) {
+	if len(pc.used) <= 2 {
+		writeSimpleCode(bw, pc.used)
+		return
+	}
+
+	// normal code, every length is written as literal 0 - 15 of the code length code
+	counts := make([]int, 19)
+	for _, l := range pc.lengths {
+		counts[l]++
+	}
+
+	// code length code need two symbol to be read back as a real code
+	used := 0
+	for _, n := range counts {
+		if n > 0 {
+			used++
+		}
+	}
+
+	if used == 1 {
+		if counts[0] == 0 {
+			counts[0] = 1
+		} else {
+			counts[1] = 1
+		}
+	}
+
+	lengthCode := newPrefixCode(counts, 7)
+
+	last := 4
+	for i, s := range codeLengthOrder {
+		if lengthCode.lengths[s] > 0 && i+1 > last {
+			last = i + 1
+		}
+	}
+
+	bw.write(0, 1)
+	bw.write(uint32(last-4), 4)
+	for _, s := range codeLengthOrder[:last] {
+		bw.write(uint32(lengthCode.lengths[s]), 3)
+	}
+
+	// max_symbol is the whole alphabet
+	bw.write(0, 1)
+	for _, l := range pc.lengths {
+		lengthCode.writeSymbol(bw, l)
+	}
+}
+
+// simple code of one or two symbol below 256
+func writeSimpleCode(bw *bitWriter, symbols []int) {
+	if len(symbols) == 0 {
+		symbols = []int{0}
+	}
+
+	bw.write(1, 1)
+	bw.write(uint32(len(symbols)-1), 1)
+
+	if symbols[0] < 2 {
+		bw.write(0, 1)
+		bw.write(uint32(symbols[0]), 1)
+	} else {
+		bw.write(1, 1)
+		bw.write(uint32(symbols[0]), 8)
+	}
+
+	if len(symbols) == 2 {
+		bw.write(uint32(symbols[1]), 8)
+	}
+}
+
+// bit writer, least significant bit first like the webp bitstream
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *bitWriter) write(v uint32, n int) {
+	bw.acc |= uint64(v) << bw.nbits
+	bw.nbits += uint(n)
+
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+
+	return bw.buf
+}
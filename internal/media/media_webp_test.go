@@ -0,0 +1,158 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	noise := image.NewRGBA(image.Rect(0, 0, 37, 23))
+	rng.Read(noise.Pix)
+	for i := 3; i < len(noise.Pix); i += 4 {
+		noise.Pix[i] = 255
+	}
+
+	// premultiplied, color can not be above alpha
+	translucent := image.NewRGBA(image.Rect(0, 0, 19, 11))
+	for i := 0; i < len(translucent.Pix); i += 4 {
+		a := uint8(rng.Intn(256))
+		translucent.Pix[i] = uint8(rng.Intn(int(a) + 1))
+		translucent.Pix[i+1] = uint8(rng.Intn(int(a) + 1))
+		translucent.Pix[i+2] = uint8(rng.Intn(int(a) + 1))
+		translucent.Pix[i+3] = a
+	}
+
+	solid := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	for i := range solid.Pix {
+		solid.Pix[i] = 200
+	}
+
+	twoColor := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if (x+y)%2 == 0 {
+				twoColor.Set(x, y, color.RGBA{255, 255, 255, 255})
+			} else {
+				twoColor.Set(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+
+	// sub image that does not start at 0,0 and cross the 512 predictor block
+	wide := testImage(600, 40).SubImage(image.Rect(30, 5, 590, 40)).(*image.RGBA)
+
+	images := map[string]*image.RGBA{
+		"single pixel": testImage(1, 1),
+		"gradient":     testImage(16, 9),
+		"noise":        noise,
+		"translucent":  translucent,
+		"solid":        solid,
+		"two color":    twoColor,
+		"wide":         wide,
+	}
+
+	for name, img := range images {
+		t.Run(name, func(t *testing.T) {
+			data, err := EncodeWebP(img)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if size := binary.LittleEndian.Uint32(data[4:]); int(size) != len(data)-8 {
+				t.Errorf("riff size %d, file %d", size, len(data))
+			}
+
+			got, err := webp.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			b := img.Bounds()
+			if got.Bounds().Dx() != b.Dx() || got.Bounds().Dy() != b.Dy() {
+				t.Fatalf("size %v, want %v", got.Bounds(), b)
+			}
+
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					want := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y))
+					have := color.NRGBAModel.Convert(got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y))
+					if have != want {
+						t.Fatalf("pixel %d,%d = %v, want %v", x, y, have, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPSize(t *testing.T) {
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 0, 0),
+		image.Rect(0, 0, 10, 0),
+		image.Rect(0, 0, 1<<14+1, 1),
+		image.Rect(0, 0, 1, 1<<14+1),
+	} {
+		if _, err := EncodeWebP(image.NewRGBA(r)); err == nil {
+			t.Errorf("EncodeWebP(%v) want error", r)
+		}
+	}
+}
+
+func TestBitWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes [][2]uint32 // value, bit count
+		want   []byte
+	}{
+		{"nothing", nil, nil},
+		{"one bit", [][2]uint32{{1, 1}}, []byte{0x01}},
+		{"least significant first", [][2]uint32{{1, 1}, {0, 1}, {3, 2}}, []byte{0x0d}},
+		{"cross byte", [][2]uint32{{0x2f, 8}, {0x3fff, 14}}, []byte{0x2f, 0xff, 0x3f}},
+		{"high bit kept", [][2]uint32{{0, 4}, {0xf, 4}, {0x1, 1}}, []byte{0xf0, 0x01}},
+	}
+
+	for _, tt := range tests {
+		bw := new(bitWriter)
+		for _, wr := range tt.writes {
+			bw.write(wr[0], int(wr[1]))
+		}
+
+		if got := bw.bytes(); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: bytes() = % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixCodeLengthLimit(t *testing.T) {
+	// fibonacci count give the deepest huffman tree
+	counts := []int{1, 1}
+	for len(counts) < 30 {
+		counts = append(counts, counts[len(counts)-1]+counts[len(counts)-2])
+	}
+
+	for _, max := range []int{15, 7} {
+		pc := newPrefixCode(counts, max)
+
+		// complete code, kraft sum is exactly one
+		kraft := 0.0
+		for s, l := range pc.lengths {
+			if l < 1 || l > max {
+				t.Fatalf("max %d: symbol %d has length %d", max, s, l)
+			}
+
+			kraft += 1 / float64(uint(1)<<l)
+		}
+
+		if kraft != 1 {
+			t.Errorf("max %d: kraft sum %v", max, kraft)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package moderation
+
+import "time"
+
+// kind of content that can be reported
+var TargetTypes = map[string]bool{
+	"post":    true,
+	"comment": true,
+	"user":    true,
+	"message": true,
+}
+
+// reason that can be chosen when reporting
+var ReasonTypes = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"nudity":         true,
+	"self_harm":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+// action of moderator, dismiss close report without doing anything
+var ActionTypes = map[string]bool{
+	"dismiss": true,
+	"hide":    true,
+	"suspend": true,
+}
+
+// default and maximum day of suspension
+const DefaultSuspendDays = 7
+const MaxSuspendDays = 365
+
+type ReportReqType struct {
+	Target_Type string `json:"target_type"`
+	Target_ID   string `json:"target_id"`
+	Reason      string `json:"reason"`
+	Detail      string `json:"detail"`
+}
+
+type ReportType struct {
+	Report_ID        string     `json:"report_id"`
+	Reporter_ID      string     `json:"reporter_id"`
+	Target_Type      string     `json:"target_type"`
+	Target_ID        string     `json:"target_id"`
+	Target_User_ID   string     `json:"target_user_id"`
+	Target_Content   string     `json:"target_content"`
+	Reason           string     `json:"reason"`
+	Detail           string     `json:"detail"`
+	Status           string     `json:"status"`
+	Number_Of_Report int        `json:"number_of_report"` // open report of the same target
+	Resolved_By      string     `json:"resolved_by"`
+	Resolved_At      *time.Time `json:"resolved_at"`
+	Created_At       time.Time  `json:"created_at"`
+}
+
+type ActionReqType struct {
+	Report_ID    string `json:"report_id"`
+	Action       string `json:"action"`
+	Note         string `json:"note"`
+	Suspend_Days int    `json:"suspend_days"`
+}
+
+// audit record, one for every action taken by moderator
+type ActionType struct {
+	Action_ID       string     `json:"action_id"`
+	Moderator_ID    string     `json:"moderator_id"`
+	Action          string     `json:"action"`
+	Target_Type     string     `json:"target_type"`
+	Target_ID       string     `json:"target_id"`
+	Target_User_ID  string     `json:"target_user_id"`
+	Report_ID       string     `json:"report_id"`
+	Note            string     `json:"note"`
+	Suspended_Until *time.Time `json:"suspended_until"`
+	Created_At      time.Time  `json:"created_at"`
+}
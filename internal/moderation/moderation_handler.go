@@ -0,0 +1,305 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/erlnerlngga/backend-socius/internal/user"
+	"github.com/erlnerlngga/backend-socius/util"
+)
+
+type Handler struct {
+	Repository *Repository
+	User       *user.Repository
+}
+
+func NewModerationHandler(r *Repository, u *user.Repository) *Handler {
+	return &Handler{
+		Repository: r,
+		User:       u,
+	}
+}
+
+// report post, comment, user or chat message
+func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) error {
+	req := new(ReportReqType)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Println("1. CreateReport", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	if !TargetTypes[req.Target_Type] {
+		return fmt.Errorf("invalid target type")
+	}
+
+	if !ReasonTypes[req.Reason] {
+		return fmt.Errorf("invalid reason")
+	}
+
+	req.Detail = strings.TrimSpace(req.Detail)
+	if utf8.RuneCountInString(req.Detail) > 500 {
+		return fmt.Errorf("detail is too long")
+	}
+
+	userID := util.GetUserID(r)
+
+	// reporter can only report what they can see
+	var targetUserID string
+	switch req.Target_Type {
+	case "post", "comment":
+		authorID, postType, err := h.Repository.GetPostTarget(req.Target_ID)
+		if err != nil {
+			log.Println("2. CreateReport", err)
+			return err
+		}
+
+		if (postType == "child") != (req.Target_Type == "comment") {
+			return fmt.Errorf("target is not a %s", req.Target_Type)
+		}
+
+		visible, err := h.User.CanViewPost(req.Target_ID, userID)
+		if err != nil {
+			log.Println("3. CreateReport", err)
+			return err
+		}
+
+		if !visible {
+			return fmt.Errorf("post not found")
+		}
+
+		targetUserID = authorID
+
+	case "message":
+		authorID, roomID, err := h.Repository.GetMessageTarget(req.Target_ID)
+		if err != nil {
+			log.Println("4. CreateReport", err)
+			return err
+		}
+
+		member, err := h.Repository.IsRoomMember(roomID, userID)
+		if err != nil {
+			log.Println("5. CreateReport", err)
+			return err
+		}
+
+		if !member {
+			return fmt.Errorf("message not found")
+		}
+
+		targetUserID = authorID
+
+	case "user":
+		exists, err := h.Repository.UserExists(req.Target_ID)
+		if err != nil {
+			log.Println("6. CreateReport", err)
+			return err
+		}
+
+		if !exists {
+			return fmt.Errorf("user not found")
+		}
+
+		targetUserID = req.Target_ID
+	}
+
+	if targetUserID == userID {
+		return fmt.Errorf("can't report yourself")
+	}
+
+	rep := &ReportType{
+		Reporter_ID:    userID,
+		Target_Type:    req.Target_Type,
+		Target_ID:      req.Target_ID,
+		Target_User_ID: targetUserID,
+		Reason:         req.Reason,
+		Detail:         req.Detail,
+	}
+
+	if err := h.Repository.CreateReport(rep); err != nil {
+		log.Println("7. CreateReport", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// moderation queue, oldest report first
+// query: status=open|dismissed|actioned, target_type
+func (h *Handler) GetQueue(w http.ResponseWriter, r *http.Request) error {
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetQueue", err)
+		return err
+	}
+
+	page.Asc = true
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+
+	if status != "open" && status != "dismissed" && status != "actioned" {
+		return fmt.Errorf("invalid status")
+	}
+
+	targetType := r.URL.Query().Get("target_type")
+	if targetType != "" && !TargetTypes[targetType] {
+		return fmt.Errorf("invalid target type")
+	}
+
+	reports, err := h.Repository.GetAllReport(status, targetType, page)
+	if err != nil {
+		log.Println("2. GetQueue", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(reports, page, reportKey))
+}
+
+// report that is closed by another moderator first
+var errResolved = fmt.Errorf("report already resolved")
+
+// act on report, every open report of the same target is resolved together
+func (h *Handler) TakeAction(w http.ResponseWriter, r *http.Request) error {
+	req := new(ActionReqType)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Println("1. TakeAction", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	if !ActionTypes[req.Action] {
+		return fmt.Errorf("invalid action")
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(req.Note) > 500 {
+		return fmt.Errorf("note is too long")
+	}
+
+	rep, err := h.Repository.GetReport(req.Report_ID)
+	if err != nil {
+		log.Println("2. TakeAction", err)
+		return err
+	}
+
+	if rep.Status != "open" {
+		return util.WriteJSON(w, http.StatusConflict, util.ApiError{Error: errResolved.Error()})
+	}
+
+	moderatorID := util.GetUserID(r)
+	action := &ActionType{
+		Moderator_ID:   moderatorID,
+		Action:         req.Action,
+		Target_Type:    rep.Target_Type,
+		Target_ID:      rep.Target_ID,
+		Target_User_ID: rep.Target_User_ID,
+		Report_ID:      rep.Report_ID,
+		Note:           req.Note,
+	}
+
+	if req.Action == "hide" && rep.Target_Type == "user" {
+		return fmt.Errorf("user can't be hidden, suspend instead")
+	}
+
+	var until time.Time
+	if req.Action == "suspend" {
+		days := req.Suspend_Days
+		if days == 0 {
+			days = DefaultSuspendDays
+		}
+
+		if days < 0 || days > MaxSuspendDays {
+			return fmt.Errorf("suspend days must be between 1 and %d", MaxSuspendDays)
+		}
+
+		until = time.Now().UTC().AddDate(0, 0, days)
+		action.Suspended_Until = &until
+	}
+
+	status := "actioned"
+	if req.Action == "dismiss" {
+		status = "dismissed"
+	}
+
+	// action, closing the report and the audit record go together,
+	// moderator that lose the race to another one change nothing
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		switch req.Action {
+		case "hide":
+			if err := tx.HideContent(rep.Target_Type, rep.Target_ID); err != nil {
+				log.Println("3. TakeAction", err)
+				return err
+			}
+
+		case "suspend":
+			if err := tx.SuspendUser(rep.Target_User_ID, until); err != nil {
+				log.Println("4. TakeAction", err)
+				return err
+			}
+		}
+
+		n, err := tx.ResolveReport(rep.Target_Type, rep.Target_ID, status, moderatorID)
+		if err != nil {
+			log.Println("5. TakeAction", err)
+			return err
+		}
+
+		if n == 0 {
+			return errResolved
+		}
+
+		if err := tx.CreateAction(action); err != nil {
+			log.Println("6. TakeAction", err)
+			return err
+		}
+
+		return nil
+	})
+
+	if err == errResolved {
+		return util.WriteJSON(w, http.StatusConflict, util.ApiError{Error: err.Error()})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, action)
+}
+
+// audit record of moderator action, query: user_id to only get action against that user
+func (h *Handler) GetAllAction(w http.ResponseWriter, r *http.Request) error {
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllAction", err)
+		return err
+	}
+
+	actions, err := h.Repository.GetAllAction(r.URL.Query().Get("user_id"), page)
+	if err != nil {
+		log.Println("2. GetAllAction", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(actions, page, actionKey))
+}
+
+// cursor key for report list
+func reportKey(rep *ReportType) (time.Time, string) {
+	return rep.Created_At, rep.Report_ID
+}
+
+// cursor key for action list
+func actionKey(a *ActionType) (time.Time, string) {
+	return a.Created_At, a.Action_ID
+}
@@ -0,0 +1,392 @@
+package moderation
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/util"
+	"github.com/google/uuid"
+)
+
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type Repository struct {
+	db DBTX
+}
+
+func NewModerationRepository(db DBTX) *Repository {
+	return &Repository{db: db}
+}
+
+// run fn in one transaction, it is rolled back when fn return error
+func (r *Repository) WithTx(fn func(tx *Repository) error) error {
+	db, ok := r.db.(*sql.DB)
+	if !ok {
+		return fn(r)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("1. WithTx", err)
+		return err
+	}
+
+	if err := fn(&Repository{db: tx}); err != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Println("2. WithTx", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("3. WithTx", err)
+		return err
+	}
+
+	return nil
+}
+
+// check user has moderator role
+func (r *Repository) IsModerator(user_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from user where user_id = ? and role = 'moderator';"
+	err := r.db.QueryRow(query, user_id).Scan(&number)
+	if err != nil {
+		log.Println("1. IsModerator", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// check user is suspended right now
+func (r *Repository) IsSuspended(user_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from user where user_id = ? and suspended_until > ?;"
+	err := r.db.QueryRow(query, user_id, time.Now().UTC()).Scan(&number)
+	if err != nil {
+		log.Println("1. IsSuspended", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// get author and type of post or comment
+func (r *Repository) GetPostTarget(post_id string) (string, string, error) {
+	var user_id, post_type string
+
+	query := `select user_id, type from post where post_id = ?;`
+	err := r.db.QueryRow(query, post_id).Scan(&user_id, &post_type)
+	if err == sql.ErrNoRows {
+		return "", "", fmt.Errorf("post not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetPostTarget", err)
+		return "", "", err
+	}
+
+	return user_id, post_type, nil
+}
+
+// get author and room of message
+func (r *Repository) GetMessageTarget(message_id string) (string, string, error) {
+	var user_id, room_id string
+
+	query := `select user_id, room_id from message where message_id = ? and hidden = false;`
+	err := r.db.QueryRow(query, message_id).Scan(&user_id, &room_id)
+	if err == sql.ErrNoRows {
+		return "", "", fmt.Errorf("message not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetMessageTarget", err)
+		return "", "", err
+	}
+
+	return user_id, room_id, nil
+}
+
+// check user is inside room
+func (r *Repository) IsRoomMember(room_id, user_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from client where room_id = ? and user_id = ?;"
+	err := r.db.QueryRow(query, room_id, user_id).Scan(&number)
+	if err != nil {
+		log.Println("1. IsRoomMember", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// check user exist
+func (r *Repository) UserExists(user_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from user where user_id = ?;"
+	err := r.db.QueryRow(query, user_id).Scan(&number)
+	if err != nil {
+		log.Println("1. UserExists", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// create report, reporter can only report the same target once
+func (r *Repository) CreateReport(rep *ReportType) error {
+	rep.Report_ID = uuid.New().String()
+	rep.Status = "open"
+	rep.Created_At = time.Now().UTC()
+
+	query := `insert ignore into report(report_id, reporter_id, target_type, target_id, target_user_id, reason, detail, status, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	res, err := r.db.Exec(query, rep.Report_ID, rep.Reporter_ID, rep.Target_Type, rep.Target_ID, rep.Target_User_ID, rep.Reason, rep.Detail, rep.Status, rep.Created_At)
+	if err != nil {
+		log.Println("1. CreateReport", err)
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Println("2. CreateReport", err)
+		return err
+	}
+
+	if n == 0 {
+		return fmt.Errorf("you already reported this")
+	}
+
+	return nil
+}
+
+const reportColumn = "report.report_id, report.reporter_id, report.target_type, report.target_id, report.target_user_id, report.reason, report.detail, report.status, report.resolved_by, report.resolved_at, report.created_at, " +
+	"(select count(*) from report r2 where r2.target_type = report.target_type and r2.target_id = report.target_id and r2.status = 'open')"
+
+func scanReport(row interface{ Scan(...any) error }) (*ReportType, error) {
+	rep := new(ReportType)
+	var resolvedAt sql.NullTime
+
+	err := row.Scan(&rep.Report_ID, &rep.Reporter_ID, &rep.Target_Type, &rep.Target_ID, &rep.Target_User_ID, &rep.Reason, &rep.Detail, &rep.Status, &rep.Resolved_By, &resolvedAt, &rep.Created_At, &rep.Number_Of_Report)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolvedAt.Valid {
+		rep.Resolved_At = &resolvedAt.Time
+	}
+
+	return rep, nil
+}
+
+// get single report
+func (r *Repository) GetReport(report_id string) (*ReportType, error) {
+	query := "select " + reportColumn + " from report where report_id = ?;"
+
+	rep, err := scanReport(r.db.QueryRow(query, report_id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("report not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetReport", err)
+		return nil, err
+	}
+
+	return rep, nil
+}
+
+// get report by status for moderation queue, empty target_type mean every type
+func (r *Repository) GetAllReport(status, target_type string, page *util.PageReqType) ([]*ReportType, error) {
+	cond, args := page.Query("report.created_at", "report.report_id")
+
+	filter := ""
+	queryArgs := []any{status}
+	if target_type != "" {
+		filter = " and report.target_type = ?"
+		queryArgs = append(queryArgs, target_type)
+	}
+
+	query := "select " + reportColumn + " from report where report.status = ?" + filter + cond + ";"
+
+	rows, err := r.db.Query(query, append(queryArgs, args...)...)
+	if err != nil {
+		log.Println("1. GetAllReport", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reports := []*ReportType{}
+	for rows.Next() {
+		rep, err := scanReport(rows)
+		if err != nil {
+			log.Println("2. GetAllReport", err)
+			return nil, err
+		}
+
+		reports = append(reports, rep)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllReport", err)
+		return nil, err
+	}
+
+	for _, rep := range reports {
+		rep.Target_Content, err = r.GetTargetContent(rep.Target_Type, rep.Target_ID)
+		if err != nil {
+			log.Println("4. GetAllReport", err)
+			return nil, err
+		}
+	}
+
+	return reports, nil
+}
+
+// text of reported target so moderator can judge it, empty when target is deleted
+func (r *Repository) GetTargetContent(target_type, target_id string) (string, error) {
+	var query string
+	switch target_type {
+	case "post", "comment":
+		query = `select content from post where post_id = ?;`
+	case "message":
+		query = `select content from message where message_id = ?;`
+	case "user":
+		query = `select user_name from user where user_id = ?;`
+	default:
+		return "", nil
+	}
+
+	var content string
+	err := r.db.QueryRow(query, target_id).Scan(&content)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		log.Println("1. GetTargetContent", err)
+		return "", err
+	}
+
+	return content, nil
+}
+
+// close every open report of the target, return number of report closed
+func (r *Repository) ResolveReport(target_type, target_id, status, moderator_id string) (int64, error) {
+	query := `update report set status = ?, resolved_by = ?, resolved_at = ? where target_type = ? and target_id = ? and status = 'open';`
+	res, err := r.db.Exec(query, status, moderator_id, time.Now().UTC(), target_type, target_id)
+	if err != nil {
+		log.Println("1. ResolveReport", err)
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Println("2. ResolveReport", err)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// hide post, comment or message from every read
+func (r *Repository) HideContent(target_type, target_id string) error {
+	query := `update post set hidden = true where post_id = ?;`
+	if target_type == "message" {
+		query = `update message set hidden = true where message_id = ?;`
+	}
+
+	_, err := r.db.Exec(query, target_id)
+	if err != nil {
+		log.Println("1. HideContent", err)
+		return err
+	}
+
+	return nil
+}
+
+// suspend user until the time, longer suspension that already there is kept
+func (r *Repository) SuspendUser(user_id string, until time.Time) error {
+	query := `update user set suspended_until = ? where user_id = ? and (suspended_until is null or suspended_until < ?);`
+	_, err := r.db.Exec(query, until, user_id, until)
+	if err != nil {
+		log.Println("1. SuspendUser", err)
+		return err
+	}
+
+	return nil
+}
+
+// save audit record of moderator action
+func (r *Repository) CreateAction(a *ActionType) error {
+	a.Action_ID = uuid.New().String()
+	a.Created_At = time.Now().UTC()
+
+	query := `insert into moderation_action(action_id, moderator_id, action, target_type, target_id, target_user_id, report_id, note, suspended_until, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, a.Action_ID, a.Moderator_ID, a.Action, a.Target_Type, a.Target_ID, a.Target_User_ID, a.Report_ID, a.Note, a.Suspended_Until, a.Created_At)
+	if err != nil {
+		log.Println("1. CreateAction", err)
+		return err
+	}
+
+	return nil
+}
+
+// get audit record, empty target_user_id mean every user
+func (r *Repository) GetAllAction(target_user_id string, page *util.PageReqType) ([]*ActionType, error) {
+	cond, args := page.Query("created_at", "action_id")
+
+	filter := " where true"
+	queryArgs := []any{}
+	if target_user_id != "" {
+		filter = " where target_user_id = ?"
+		queryArgs = append(queryArgs, target_user_id)
+	}
+
+	query := "select action_id, moderator_id, action, target_type, target_id, target_user_id, report_id, note, suspended_until, created_at from moderation_action" + filter + cond + ";"
+
+	rows, err := r.db.Query(query, append(queryArgs, args...)...)
+	if err != nil {
+		log.Println("1. GetAllAction", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	actions := []*ActionType{}
+	for rows.Next() {
+		a := new(ActionType)
+		var until sql.NullTime
+
+		if err := rows.Scan(&a.Action_ID, &a.Moderator_ID, &a.Action, &a.Target_Type, &a.Target_ID, &a.Target_User_ID, &a.Report_ID, &a.Note, &until, &a.Created_At); err != nil {
+			log.Println("2. GetAllAction", err)
+			return nil, err
+		}
+
+		if until.Valid {
+			a.Suspended_Until = &until.Time
+		}
+
+		actions = append(actions, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllAction", err)
+		return nil, err
+	}
+
+	return actions, nil
+}
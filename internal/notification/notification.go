@@ -0,0 +1,145 @@
+package notification
+
+import "time"
+
+// event that the other package emit after the change is saved
+type EventType struct {
+	Type     string
+	Actor_ID string // user that cause the event
+	User_ID  string // user that get notified
+	Post_ID  string
+	Room_ID  string
+}
+
+// every event that turn into notification
+var EventTypes = map[string]bool{
+	"comment":     true,
+	"reply":       true,
+	"mention":     true,
+	"reaction":    true,
+	"repost":      true,
+	"quote":       true,
+	"add_friend":  true,
+	"room_invite": true,
+	"poll_closed": true,
+}
+
+// event that is about own content, so the actor is notified too
+var selfEvent = map[string]bool{
+	"poll_closed": true,
+}
+
+// name and photo come from user table when read, so it never go stale
+type NotificationType struct {
+	Notification_ID string    `json:"notification_id"`
+	Issuer          string    `json:"issuer"`
+	Issuer_Name     string    `json:"issuer_name"`
+	Issuer_Photo    string    `json:"issuer_photo"`
+	Notifier        string    `json:"notifier"`
+	Notifier_Name   string    `json:"notifier_name"`
+	Status          string    `json:"status"`
+	Accept          string    `json:"accept"`
+	Post_ID         string    `json:"post_id"`
+	Room_ID         string    `json:"room_id"`
+	Type            string    `json:"type"`
+	Created_At      time.Time `json:"created_at"`
+	Updated_At      time.Time `json:"updated_at"`
+}
+
+type UpdateNotifType struct {
+	Notification_ID string    `json:"notification_id"`
+	Accept          string    `json:"accept"`
+	Updated_At      time.Time `json:"updated_at"`
+}
+
+// where notification of a type is delivered, type without preference use DefaultChannel
+const DefaultChannel = "in_app"
+
+// in_app only save it, email save it and send email too, none drop it
+var Channels = map[string]bool{
+	"in_app": true,
+	"email":  true,
+	"none":   true,
+}
+
+// subject of the email, read as "<issuer name> <subject>"
+var eventSubject = map[string]string{
+	"comment":     "commented on your post",
+	"reply":       "replied to your comment",
+	"mention":     "mentioned you",
+	"reaction":    "reacted to your post",
+	"repost":      "reposted your post",
+	"quote":       "quoted your post",
+	"add_friend":  "sent you a friend request",
+	"room_invite": "added you to a room",
+	"poll_closed": "poll is closed",
+}
+
+type PreferenceType struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+}
+
+// quiet hour is minute of the day in the user own utc offset, end before start cross midnight
+type QuietHourType struct {
+	Enabled      bool `json:"enabled"`
+	Start_Minute int  `json:"start_minute"`
+	End_Minute   int  `json:"end_minute"`
+	Utc_Offset   int  `json:"utc_offset"` // minute, e.g. 420 for utc+7
+}
+
+// check the time is inside quiet hour
+func (q *QuietHourType) Contains(t time.Time) bool {
+	if q == nil || !q.Enabled || q.Start_Minute == q.End_Minute {
+		return false
+	}
+
+	local := t.UTC().Add(time.Duration(q.Utc_Offset) * time.Minute)
+	minute := local.Hour()*60 + local.Minute()
+
+	if q.Start_Minute < q.End_Minute {
+		return minute >= q.Start_Minute && minute < q.End_Minute
+	}
+
+	return minute >= q.Start_Minute || minute < q.End_Minute
+}
+
+// first time after t that the quiet hour end
+func (q *QuietHourType) End(t time.Time) time.Time {
+	offset := time.Duration(q.Utc_Offset) * time.Minute
+	local := t.UTC().Add(offset)
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), 0, q.End_Minute, 0, 0, time.UTC)
+	if !end.After(local) {
+		end = end.Add(24 * time.Hour)
+	}
+
+	return end.Add(-offset)
+}
+
+// notification held back by quiet hour
+type QueuedType struct {
+	Notification_ID string
+	User_ID         string
+	Channel         string
+	Send_After      time.Time
+}
+
+type SettingType struct {
+	Preferences []*PreferenceType `json:"preferences"`
+	Quiet_Hour  *QuietHourType    `json:"quiet_hour"`
+}
+
+// thing that can be muted, event about it is dropped
+var MuteTargets = map[string]bool{
+	"post": true,
+	"room": true,
+	"user": true,
+}
+
+type MuteType struct {
+	User_ID     string    `json:"user_id"`
+	Target_Type string    `json:"target_type"`
+	Target_ID   string    `json:"target_id"`
+	Created_At  time.Time `json:"created_at"`
+}
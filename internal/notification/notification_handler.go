@@ -0,0 +1,224 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	Service *Service
+}
+
+func NewNotificationHandler(s *Service) *Handler {
+	return &Handler{Service: s}
+}
+
+func (h *Handler) UpdateNotificationRead(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	if userID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the notification"})
+	}
+
+	err := h.Service.Repository.UpdatedNotifRead(userID)
+	if err != nil {
+		log.Println("1. UpdateNotificationRead", err)
+		return err
+	}
+
+	h.Service.Changed(userID)
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) GetCountNotification(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	if userID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the notification"})
+	}
+
+	num, err := h.Service.Repository.GetCountNotif(userID)
+	if err != nil {
+		log.Println("1. GetCountNotification", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]int{"number": num})
+}
+
+func (h *Handler) GetAllNotification(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	if userID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the notification"})
+	}
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllNotification", err)
+		return err
+	}
+
+	notif, err := h.Service.Repository.GetAllNotif(userID, page)
+	if err != nil {
+		log.Println("2. GetAllNotification", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(notif, page, notifKey))
+}
+
+// cursor key for notification list
+func notifKey(n *NotificationType) (time.Time, string) {
+	return n.Created_At, n.Notification_ID
+}
+
+// get channel of every type and quiet hour of the caller
+func (h *Handler) GetNotificationSetting(w http.ResponseWriter, r *http.Request) error {
+	userID := util.GetUserID(r)
+
+	prefs, err := h.Service.Repository.GetAllPreference(userID)
+	if err != nil {
+		log.Println("1. GetNotificationSetting", err)
+		return err
+	}
+
+	quiet, err := h.Service.Repository.GetQuietHour(userID)
+	if err != nil {
+		log.Println("2. GetNotificationSetting", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, &SettingType{Preferences: prefs, Quiet_Hour: quiet})
+}
+
+// update channel of some type, type that is not sent keep its channel
+func (h *Handler) UpdateNotificationPreference(w http.ResponseWriter, r *http.Request) error {
+	prefs := []*PreferenceType{}
+
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		log.Println("1. UpdateNotificationPreference", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	for _, p := range prefs {
+		if !EventTypes[p.Type] {
+			return fmt.Errorf("unknown notification type %s", p.Type)
+		}
+
+		if !Channels[p.Channel] {
+			return fmt.Errorf("channel must be in_app, email or none")
+		}
+	}
+
+	if err := h.Service.Repository.UpdatePreference(util.GetUserID(r), prefs); err != nil {
+		log.Println("2. UpdateNotificationPreference", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) UpdateQuietHour(w http.ResponseWriter, r *http.Request) error {
+	quiet := new(QuietHourType)
+
+	if err := json.NewDecoder(r.Body).Decode(quiet); err != nil {
+		log.Println("1. UpdateQuietHour", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	if quiet.Start_Minute < 0 || quiet.Start_Minute >= 24*60 || quiet.End_Minute < 0 || quiet.End_Minute >= 24*60 {
+		return fmt.Errorf("start and end minute must be between 0 and 1439")
+	}
+
+	if quiet.Utc_Offset < -12*60 || quiet.Utc_Offset > 14*60 {
+		return fmt.Errorf("utc offset must be between -720 and 840")
+	}
+
+	if err := h.Service.Repository.UpdateQuietHour(util.GetUserID(r), quiet); err != nil {
+		log.Println("2. UpdateQuietHour", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// mute post, room or user for the caller
+func (h *Handler) MuteNotification(w http.ResponseWriter, r *http.Request) error {
+	mute := new(MuteType)
+
+	if err := json.NewDecoder(r.Body).Decode(mute); err != nil {
+		log.Println("1. MuteNotification", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	if !MuteTargets[mute.Target_Type] {
+		return fmt.Errorf("target type must be post, room or user")
+	}
+
+	if mute.Target_ID == "" {
+		return fmt.Errorf("target id is required")
+	}
+
+	mute.User_ID = util.GetUserID(r)
+	if mute.Target_Type == "user" && mute.Target_ID == mute.User_ID {
+		return fmt.Errorf("can't mute yourself")
+	}
+
+	if err := h.Service.Repository.CreateMute(mute); err != nil {
+		log.Println("2. MuteNotification", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) UnmuteNotification(w http.ResponseWriter, r *http.Request) error {
+	targetType := chi.URLParam(r, "targetType")
+	targetID := chi.URLParam(r, "targetID")
+
+	if !MuteTargets[targetType] {
+		return fmt.Errorf("target type must be post, room or user")
+	}
+
+	if err := h.Service.Repository.DeleteMute(util.GetUserID(r), targetType, targetID); err != nil {
+		log.Println("1. UnmuteNotification", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) GetAllMute(w http.ResponseWriter, r *http.Request) error {
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllMute", err)
+		return err
+	}
+
+	mutes, err := h.Service.Repository.GetAllMute(util.GetUserID(r), page)
+	if err != nil {
+		log.Println("2. GetAllMute", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(mutes, page, muteKey))
+}
+
+// cursor key for mute list, same as the id column of GetAllMute
+func muteKey(m *MuteType) (time.Time, string) {
+	return m.Created_At, m.Target_Type + ":" + m.Target_ID
+}
@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// maximum queued notification delivered per tick
+const queueBatch = 100
+
+// Outbox deliver notification that was held back by quiet hour once the quiet hour end
+type Outbox struct {
+	Service  *Service
+	interval time.Duration
+}
+
+func NewOutbox(s *Service) *Outbox {
+	return &Outbox{
+		Service:  s,
+		interval: time.Duration(1) * time.Minute,
+	}
+}
+
+// flush due notification every interval until context is done
+func (o *Outbox) Run(c context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if err := o.Service.FlushQueue(time.Now().UTC()); err != nil {
+				log.Println("1. Run", err)
+			}
+		}
+	}
+}
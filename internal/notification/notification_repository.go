@@ -0,0 +1,405 @@
+package notification
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/util"
+	"github.com/google/uuid"
+)
+
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type Repository struct {
+	db DBTX
+}
+
+func NewNotificationRepository(db DBTX) *Repository {
+	return &Repository{db: db}
+}
+
+// column of notification with alias n, name of old notification fall back to the stored one
+const notifColumn = "n.notification_id, n.issuer, coalesce(iu.user_name, n.issuer_name, ''), coalesce(iu.photo_profile, ''), n.notifier, coalesce(nu.user_name, n.notifier_name, ''), " +
+	"n.status, coalesce(n.accept, ''), coalesce(n.post_id, ''), n.room_id, n.type, n.created_at, n.updated_at"
+
+const notifJoin = "left join user iu on iu.user_id = n.issuer left join user nu on nu.user_id = n.notifier"
+
+// create notification, name is left empty and joined from user when read
+func (r *Repository) CreateNotification(notif *NotificationType) error {
+	notif.Notification_ID = uuid.New().String()
+	notif.Created_At = time.Now().UTC()
+	notif.Updated_At = time.Now().UTC()
+
+	query := `insert into notification(notification_id, issuer, issuer_name, notifier, notifier_name, status, accept, post_id, room_id, type, created_at, updated_at) values(?, ?, '', ?, '', ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, notif.Notification_ID, notif.Issuer, notif.Notifier, notif.Status, notif.Accept, notif.Post_ID, notif.Room_ID, notif.Type, notif.Created_At, notif.Updated_At)
+	if err != nil {
+		log.Println("1. CreateNotification", err)
+		return err
+	}
+
+	return nil
+}
+
+// get notification by id
+func (r *Repository) GetNotification(notification_id string) (*NotificationType, error) {
+	n := new(NotificationType)
+
+	query := "select " + notifColumn + " from notification n " + notifJoin + " where n.notification_id = ?;"
+	err := r.db.QueryRow(query, notification_id).Scan(&n.Notification_ID, &n.Issuer, &n.Issuer_Name, &n.Issuer_Photo, &n.Notifier, &n.Notifier_Name, &n.Status, &n.Accept, &n.Post_ID, &n.Room_ID, &n.Type, &n.Created_At, &n.Updated_At)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetNotification", err)
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// check there is friend request between both user that is not answered yet, old request has no accept
+func (r *Repository) HasPendingRequest(user_id, other_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from notification where type = 'add_friend' and coalesce(accept, '') in ('pending', '') and ((issuer = ? and notifier = ?) or (issuer = ? and notifier = ?));"
+	err := r.db.QueryRow(query, user_id, other_id, other_id, user_id).Scan(&number)
+	if err != nil {
+		log.Println("1. HasPendingRequest", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// update friend notif
+func (r *Repository) UpdateNotif(notif *UpdateNotifType) error {
+	notif.Updated_At = time.Now().UTC()
+
+	query := `update notification set accept = ?, updated_at = ? where notification_id = ?;`
+	_, err := r.db.Exec(query, notif.Accept, notif.Updated_At, notif.Notification_ID)
+	if err != nil {
+		log.Println("1. UpdateNotif", err)
+		return err
+	}
+
+	return nil
+}
+
+// updated become read
+func (r *Repository) UpdatedNotifRead(user_id string) error {
+
+	query := `update notification set status = "read", updated_at = ? where notifier = ? and status = "not_read";`
+	_, err := r.db.Exec(query, time.Now().UTC(), user_id)
+	if err != nil {
+		log.Println("1. UpdatedNotifRead", err)
+		return err
+	}
+
+	return nil
+}
+
+// get count Notif
+func (r *Repository) GetCountNotif(user_id string) (int, error) {
+	var number int
+	query := "select count(*) as `number` from notification where notifier = ? and status = 'not_read';"
+
+	err := r.db.QueryRow(query, user_id).Scan(&number)
+
+	if err != nil {
+		log.Println("1. GetCountNotif", err)
+		return -1, err
+	}
+
+	return number, nil
+}
+
+// get All notif
+func (r *Repository) GetAllNotif(user_id string, page *util.PageReqType) ([]*NotificationType, error) {
+	cond, args := page.Query("n.created_at", "n.notification_id")
+	query := "select " + notifColumn + " from notification n " + notifJoin + " where n.notifier = ?" + cond + ";"
+
+	rows, err := r.db.Query(query, append([]any{user_id}, args...)...)
+
+	if err != nil {
+		log.Println("1. GetAllNotif", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	notifs := []*NotificationType{}
+	for rows.Next() {
+		newNotif := new(NotificationType)
+
+		if err := rows.Scan(&newNotif.Notification_ID, &newNotif.Issuer, &newNotif.Issuer_Name, &newNotif.Issuer_Photo, &newNotif.Notifier, &newNotif.Notifier_Name, &newNotif.Status, &newNotif.Accept, &newNotif.Post_ID, &newNotif.Room_ID, &newNotif.Type, &newNotif.Created_At, &newNotif.Updated_At); err != nil {
+			log.Println("2. GetAllNotif", err)
+			return nil, err
+		}
+
+		notifs = append(notifs, newNotif)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllNotif", err)
+		return nil, err
+	}
+
+	return notifs, nil
+}
+
+// get channel of user for the type, DefaultChannel when not set
+func (r *Repository) GetChannel(user_id, notif_type string) (string, error) {
+	var channel string
+
+	query := `select channel from notification_preference where user_id = ? and type = ?;`
+	err := r.db.QueryRow(query, user_id, notif_type).Scan(&channel)
+	if err == sql.ErrNoRows {
+		return DefaultChannel, nil
+	}
+
+	if err != nil {
+		log.Println("1. GetChannel", err)
+		return "", err
+	}
+
+	return channel, nil
+}
+
+// get preference of every event type, the one not set use DefaultChannel
+func (r *Repository) GetAllPreference(user_id string) ([]*PreferenceType, error) {
+	query := `select type, channel from notification_preference where user_id = ?;`
+	rows, err := r.db.Query(query, user_id)
+	if err != nil {
+		log.Println("1. GetAllPreference", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	channels := map[string]string{}
+	for rows.Next() {
+		var notif_type, channel string
+		if err := rows.Scan(&notif_type, &channel); err != nil {
+			log.Println("2. GetAllPreference", err)
+			return nil, err
+		}
+
+		channels[notif_type] = channel
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllPreference", err)
+		return nil, err
+	}
+
+	prefs := []*PreferenceType{}
+	for notif_type := range EventTypes {
+		channel, ok := channels[notif_type]
+		if !ok {
+			channel = DefaultChannel
+		}
+
+		prefs = append(prefs, &PreferenceType{Type: notif_type, Channel: channel})
+	}
+
+	sort.Slice(prefs, func(i, j int) bool { return prefs[i].Type < prefs[j].Type })
+
+	return prefs, nil
+}
+
+// save preference, type and channel is checked by the handler
+func (r *Repository) UpdatePreference(user_id string, prefs []*PreferenceType) error {
+	query := `insert into notification_preference(user_id, type, channel) values(?, ?, ?) on duplicate key update channel = values(channel);`
+
+	for _, p := range prefs {
+		if _, err := r.db.Exec(query, user_id, p.Type, p.Channel); err != nil {
+			log.Println("1. UpdatePreference", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get quiet hour of user, disabled when not set
+func (r *Repository) GetQuietHour(user_id string) (*QuietHourType, error) {
+	q := new(QuietHourType)
+
+	query := `select enabled, start_minute, end_minute, utc_offset from notification_quiet_hour where user_id = ?;`
+	err := r.db.QueryRow(query, user_id).Scan(&q.Enabled, &q.Start_Minute, &q.End_Minute, &q.Utc_Offset)
+	if err == sql.ErrNoRows {
+		return q, nil
+	}
+
+	if err != nil {
+		log.Println("1. GetQuietHour", err)
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (r *Repository) UpdateQuietHour(user_id string, q *QuietHourType) error {
+	query := `insert into notification_quiet_hour(user_id, enabled, start_minute, end_minute, utc_offset) values(?, ?, ?, ?, ?)
+		on duplicate key update enabled = values(enabled), start_minute = values(start_minute), end_minute = values(end_minute), utc_offset = values(utc_offset);`
+	_, err := r.db.Exec(query, user_id, q.Enabled, q.Start_Minute, q.End_Minute, q.Utc_Offset)
+	if err != nil {
+		log.Println("1. UpdateQuietHour", err)
+		return err
+	}
+
+	return nil
+}
+
+// check user muted the post, room or actor of the event
+func (r *Repository) IsMuted(user_id string, e *EventType) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from notification_mute where user_id = ? and ((target_type = 'post' and target_id = ?) or (target_type = 'room' and target_id = ?) or (target_type = 'user' and target_id = ?));"
+	err := r.db.QueryRow(query, user_id, e.Post_ID, e.Room_ID, e.Actor_ID).Scan(&number)
+	if err != nil {
+		log.Println("1. IsMuted", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// mute again is ignored
+func (r *Repository) CreateMute(m *MuteType) error {
+	m.Created_At = time.Now().UTC()
+
+	query := `insert ignore into notification_mute(user_id, target_type, target_id, created_at) values(?, ?, ?, ?);`
+	_, err := r.db.Exec(query, m.User_ID, m.Target_Type, m.Target_ID, m.Created_At)
+	if err != nil {
+		log.Println("1. CreateMute", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteMute(user_id, target_type, target_id string) error {
+	query := `delete from notification_mute where user_id = ? and target_type = ? and target_id = ?;`
+	_, err := r.db.Exec(query, user_id, target_type, target_id)
+	if err != nil {
+		log.Println("1. DeleteMute", err)
+		return err
+	}
+
+	return nil
+}
+
+// get all mute of user, newest first
+func (r *Repository) GetAllMute(user_id string, page *util.PageReqType) ([]*MuteType, error) {
+	cond, args := page.Query("created_at", "concat(target_type, ':', target_id)")
+	query := "select user_id, target_type, target_id, created_at from notification_mute where user_id = ?" + cond + ";"
+
+	rows, err := r.db.Query(query, append([]any{user_id}, args...)...)
+	if err != nil {
+		log.Println("1. GetAllMute", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	mutes := []*MuteType{}
+	for rows.Next() {
+		m := new(MuteType)
+		if err := rows.Scan(&m.User_ID, &m.Target_Type, &m.Target_ID, &m.Created_At); err != nil {
+			log.Println("2. GetAllMute", err)
+			return nil, err
+		}
+
+		mutes = append(mutes, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllMute", err)
+		return nil, err
+	}
+
+	return mutes, nil
+}
+
+// get email and name of user to send email notification
+func (r *Repository) GetRecipient(user_id string) (string, string, error) {
+	var email, user_name string
+
+	query := `select email, user_name from user where user_id = ?;`
+	err := r.db.QueryRow(query, user_id).Scan(&email, &user_name)
+	if err != nil {
+		log.Println("1. GetRecipient", err)
+		return "", "", err
+	}
+
+	return email, user_name, nil
+}
+
+// hold notification back until send_after
+func (r *Repository) QueueNotif(notification_id, user_id, channel string, send_after time.Time) error {
+	query := `insert into notification_queue(notification_id, user_id, channel, send_after) values(?, ?, ?, ?);`
+	_, err := r.db.Exec(query, notification_id, user_id, channel, send_after)
+	if err != nil {
+		log.Println("1. QueueNotif", err)
+		return err
+	}
+
+	return nil
+}
+
+// get queued notification that is due, oldest first
+func (r *Repository) GetDueQueue(now time.Time, limit int) ([]*QueuedType, error) {
+	query := `select notification_id, user_id, channel, send_after from notification_queue where send_after <= ? order by send_after asc limit ?;`
+	rows, err := r.db.Query(query, now, limit)
+	if err != nil {
+		log.Println("1. GetDueQueue", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	queued := []*QueuedType{}
+	for rows.Next() {
+		q := new(QueuedType)
+		if err := rows.Scan(&q.Notification_ID, &q.User_ID, &q.Channel, &q.Send_After); err != nil {
+			log.Println("2. GetDueQueue", err)
+			return nil, err
+		}
+
+		queued = append(queued, q)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetDueQueue", err)
+		return nil, err
+	}
+
+	return queued, nil
+}
+
+// delete queued notification, false when other instance already took it
+func (r *Repository) DeleteQueue(notification_id string) (bool, error) {
+	res, err := r.db.Exec(`delete from notification_queue where notification_id = ?;`, notification_id)
+	if err != nil {
+		log.Println("1. DeleteQueue", err)
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Println("2. DeleteQueue", err)
+		return false, err
+	}
+
+	return n == 1, nil
+}
@@ -0,0 +1,179 @@
+package notification
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/util"
+)
+
+// Listener is told when notification of user change, e.g. to push it in real time.
+// It is called in the request goroutine so it must not block.
+type Listener interface {
+	Notified(n *NotificationType)
+	Changed(user_id string)
+}
+
+// Service turn event into notification row. It is the only place notification is created,
+// client can't create it directly.
+type Service struct {
+	Repository *Repository
+	listeners  []Listener
+}
+
+func NewNotificationService(r *Repository) *Service {
+	return &Service{Repository: r}
+}
+
+// add listener, must be done before the server start
+func (s *Service) Subscribe(l Listener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// tell listener that unread notification of user change without new notification
+func (s *Service) Changed(user_id string) {
+	for _, l := range s.listeners {
+		l.Changed(user_id)
+	}
+}
+
+// Emit is called by the producer after the change is saved.
+// Preference and mute of the notified user is checked here so every producer honor it.
+func (s *Service) Emit(e *EventType) error {
+	if !EventTypes[e.Type] {
+		return fmt.Errorf("unknown event %s", e.Type)
+	}
+
+	// nobody is notified about own action
+	if e.Actor_ID == e.User_ID && !selfEvent[e.Type] {
+		return nil
+	}
+
+	channel, err := s.Repository.GetChannel(e.User_ID, e.Type)
+	if err != nil {
+		log.Println("1. Emit", err)
+		return err
+	}
+
+	muted, err := s.Repository.IsMuted(e.User_ID, e)
+	if err != nil {
+		log.Println("2. Emit", err)
+		return err
+	}
+
+	// friend request is still saved because the notifier answer it from there, it is only not delivered
+	silent := channel == "none" || muted
+	if silent && e.Type != "add_friend" {
+		return nil
+	}
+
+	quiet, err := s.Repository.GetQuietHour(e.User_ID)
+	if err != nil {
+		log.Println("3. Emit", err)
+		return err
+	}
+
+	notif := &NotificationType{
+		Issuer:   e.Actor_ID,
+		Notifier: e.User_ID,
+		Status:   "not_read",
+		Post_ID:  e.Post_ID,
+		Room_ID:  e.Room_ID,
+		Type:     e.Type,
+	}
+
+	// friend request wait for the answer of the notifier
+	if e.Type == "add_friend" {
+		notif.Accept = "pending"
+	}
+
+	if err := s.Repository.CreateNotification(notif); err != nil {
+		log.Println("4. Emit", err)
+		return err
+	}
+
+	if silent {
+		return nil
+	}
+
+	// in quiet hour it is saved now and delivered by the Outbox when the quiet hour end
+	if quiet.Contains(notif.Created_At) {
+		if err := s.Repository.QueueNotif(notif.Notification_ID, notif.Notifier, channel, quiet.End(notif.Created_At)); err != nil {
+			log.Println("5. Emit", err)
+			return err
+		}
+
+		return nil
+	}
+
+	for _, l := range s.listeners {
+		l.Notified(notif)
+	}
+
+	if channel == "email" {
+		go s.sendMail(notif.Notification_ID)
+	}
+
+	return nil
+}
+
+// deliver queued notification that is due, the badge is pushed once per user
+func (s *Service) FlushQueue(now time.Time) error {
+	queued, err := s.Repository.GetDueQueue(now, queueBatch)
+	if err != nil {
+		log.Println("1. FlushQueue", err)
+		return err
+	}
+
+	changed := map[string]bool{}
+	for _, q := range queued {
+		// the one that delete the row deliver it, so more instance never send twice
+		claimed, err := s.Repository.DeleteQueue(q.Notification_ID)
+		if err != nil {
+			log.Println("2. FlushQueue", err)
+			return err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		if q.Channel == "email" {
+			s.sendMail(q.Notification_ID)
+		}
+
+		changed[q.User_ID] = true
+	}
+
+	for user_id := range changed {
+		s.Changed(user_id)
+	}
+
+	return nil
+}
+
+// send email of the notification, failure is only logged
+func (s *Service) sendMail(notification_id string) {
+	full, err := s.Repository.GetNotification(notification_id)
+	if err != nil {
+		log.Println("1. sendMail", err)
+		return
+	}
+
+	email, user_name, err := s.Repository.GetRecipient(full.Notifier)
+	if err != nil {
+		log.Println("2. sendMail", err)
+		return
+	}
+
+	subject := full.Issuer_Name + " " + eventSubject[full.Type]
+	if full.Type == "poll_closed" {
+		subject = "Your " + eventSubject[full.Type]
+	}
+
+	if err := util.SendNotificationMAIL(email, user_name, subject, html.EscapeString(subject)); err != nil {
+		log.Println("3. sendMail", err)
+	}
+}
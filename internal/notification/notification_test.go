@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHourContains(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2024, 5, 1, h, m, 0, 0, time.UTC) }
+
+	night := &QuietHourType{Enabled: true, Start_Minute: 22 * 60, End_Minute: 7 * 60}
+	lunch := &QuietHourType{Enabled: true, Start_Minute: 12 * 60, End_Minute: 13 * 60}
+	// 22:00 - 07:00 at utc+7, that is 15:00 - 00:00 utc
+	jakarta := &QuietHourType{Enabled: true, Start_Minute: 22 * 60, End_Minute: 7 * 60, Utc_Offset: 420}
+	// 22:00 - 07:00 at utc-5, that is 03:00 - 12:00 utc
+	newYork := &QuietHourType{Enabled: true, Start_Minute: 22 * 60, End_Minute: 7 * 60, Utc_Offset: -300}
+
+	tests := []struct {
+		name  string
+		quiet *QuietHourType
+		t     time.Time
+		want  bool
+	}{
+		{"nil", nil, at(23, 0), false},
+		{"disabled", &QuietHourType{Start_Minute: 22 * 60, End_Minute: 7 * 60}, at(23, 0), false},
+		{"same start and end", &QuietHourType{Enabled: true, Start_Minute: 600, End_Minute: 600}, at(10, 0), false},
+		{"same day inside", lunch, at(12, 30), true},
+		{"same day at start", lunch, at(12, 0), true},
+		{"same day at end", lunch, at(13, 0), false},
+		{"same day before", lunch, at(11, 59), false},
+		{"overnight before midnight", night, at(23, 30), true},
+		{"overnight after midnight", night, at(3, 0), true},
+		{"overnight at start", night, at(22, 0), true},
+		{"overnight at end", night, at(7, 0), false},
+		{"overnight daytime", night, at(15, 0), false},
+		{"positive offset inside", jakarta, at(16, 0), true},
+		{"positive offset outside", jakarta, at(10, 0), false},
+		{"negative offset inside", newYork, at(4, 0), true},
+		{"negative offset outside", newYork, at(23, 0), false},
+		{"other time zone is converted", lunch, time.Date(2024, 5, 1, 19, 30, 0, 0, time.FixedZone("utc+7", 7*3600)), true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.quiet.Contains(tt.t); got != tt.want {
+			t.Errorf("%s: Contains(%v) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestQuietHourEnd(t *testing.T) {
+	at := func(d, h, m int) time.Time { return time.Date(2024, 5, d, h, m, 0, 0, time.UTC) }
+
+	night := &QuietHourType{Enabled: true, Start_Minute: 22 * 60, End_Minute: 7 * 60}
+	jakarta := &QuietHourType{Enabled: true, Start_Minute: 22 * 60, End_Minute: 7 * 60, Utc_Offset: 420}
+
+	tests := []struct {
+		name  string
+		quiet *QuietHourType
+		t     time.Time
+		want  time.Time
+	}{
+		{"before midnight end next day", night, at(1, 23, 0), at(2, 7, 0)},
+		{"after midnight end same day", night, at(2, 3, 0), at(2, 7, 0)},
+		{"at end is next day", night, at(2, 7, 0), at(3, 7, 0)},
+		{"offset end in utc", jakarta, at(1, 16, 0), at(2, 0, 0)},
+		{"offset after local midnight", jakarta, at(1, 18, 0), at(2, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		got := tt.quiet.End(tt.t)
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: End(%v) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+
+		if !got.After(tt.t) || tt.quiet.Contains(got) {
+			t.Errorf("%s: End(%v) = %v is not the end of quiet hour", tt.name, tt.t, got)
+		}
+	}
+}
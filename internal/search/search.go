@@ -0,0 +1,78 @@
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type UserResultType struct {
+	User_ID       string  `json:"user_id"`
+	User_Name     string  `json:"user_name"`
+	Photo_Profile string  `json:"photo_profile"`
+	Highlight     string  `json:"highlight"`
+	Score         float64 `json:"score"`
+}
+
+type PostResultType struct {
+	Post_ID       string    `json:"post_id"`
+	User_ID       string    `json:"user_id"`
+	User_Name     string    `json:"user_name"`
+	Photo_Profile string    `json:"photo_profile"`
+	Content       string    `json:"content"`
+	Highlight     string    `json:"highlight"`
+	Score         float64   `json:"score"`
+	Created_At    time.Time `json:"created_at"`
+}
+
+type MessageResultType struct {
+	Message_ID string    `json:"message_id"`
+	Room_ID    string    `json:"room_id"`
+	User_ID    string    `json:"user_id"`
+	User_Name  string    `json:"user_name"`
+	Content    string    `json:"content"`
+	Highlight  string    `json:"highlight"`
+	Score      float64   `json:"score"`
+	Created_At time.Time `json:"created_at"`
+}
+
+// Searcher find user, post and message ranked by relevance.
+// limit + 1 row is returned when there is more so the handler can build the page.
+type Searcher interface {
+	SearchUser(query, viewer_id string, limit, offset int) ([]*UserResultType, error)
+	SearchPost(query, viewer_id string, limit, offset int) ([]*PostResultType, error)
+	SearchMessage(query, room_id string, limit, offset int) ([]*MessageResultType, error)
+}
+
+var termRegex = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// split text to lower case term
+func Terms(text string) []string {
+	return termRegex.FindAllString(strings.ToLower(text), -1)
+}
+
+// Highlight escape text and wrap every word that start with one of the term in <mark>
+func Highlight(text string, terms []string) string {
+	var b strings.Builder
+
+	last := 0
+	for _, loc := range termRegex.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[loc[0]:loc[1]])
+
+		for _, t := range terms {
+			if strings.HasPrefix(word, t) {
+				b.WriteString(html.EscapeString(text[last:loc[0]]))
+				b.WriteString("<mark>")
+				b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+				b.WriteString("</mark>")
+				last = loc[1]
+				break
+			}
+		}
+	}
+
+	b.WriteString(html.EscapeString(text[last:]))
+
+	return b.String()
+}
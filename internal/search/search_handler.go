@@ -0,0 +1,85 @@
+package search
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/erlnerlngga/backend-socius/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	Searcher   Searcher
+	Repository *Repository
+}
+
+func NewSearchHandler(s Searcher, r *Repository) *Handler {
+	return &Handler{
+		Searcher:   s,
+		Repository: r,
+	}
+}
+
+// search user by name prefix, query: q
+func (h *Handler) SearchUser(w http.ResponseWriter, r *http.Request) error {
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. SearchUser", err)
+		return err
+	}
+
+	users, err := h.Searcher.SearchUser(r.URL.Query().Get("q"), util.GetUserID(r), page.Limit, page.GetOffset())
+	if err != nil {
+		log.Println("2. SearchUser", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewOffsetPage(users, page))
+}
+
+// search post that caller can see, query: q
+func (h *Handler) SearchPost(w http.ResponseWriter, r *http.Request) error {
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. SearchPost", err)
+		return err
+	}
+
+	posts, err := h.Searcher.SearchPost(r.URL.Query().Get("q"), util.GetUserID(r), page.Limit, page.GetOffset())
+	if err != nil {
+		log.Println("2. SearchPost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewOffsetPage(posts, page))
+}
+
+// search message inside room that caller belong to, query: q
+func (h *Handler) SearchMessage(w http.ResponseWriter, r *http.Request) error {
+	roomID := chi.URLParam(r, "roomID")
+
+	member, err := h.Repository.IsRoomMember(roomID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. SearchMessage", err)
+		return err
+	}
+
+	if !member {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: fmt.Sprintf("not member of room %s", roomID)})
+	}
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("2. SearchMessage", err)
+		return err
+	}
+
+	messages, err := h.Searcher.SearchMessage(r.URL.Query().Get("q"), roomID, page.Limit, page.GetOffset())
+	if err != nil {
+		log.Println("3. SearchMessage", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewOffsetPage(messages, page))
+}
@@ -0,0 +1,272 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maximum document that ranked before visibility filter and paging
+const maxCandidate = 1000
+
+// the memory index only hold the newest post and message and the first term of each,
+// so it stay around a few hundred MB at most no matter how big the database grow.
+// user name is only few term so more user is kept.
+const (
+	MaxMemoryDoc  = 50_000
+	MaxMemoryUser = 200_000
+	maxMemoryTerm = 100
+)
+
+// term -> doc id -> term frequency, with sorted term for prefix lookup
+type termIndex struct {
+	terms  map[string]map[string]int
+	sorted []string
+}
+
+func newTermIndex() *termIndex {
+	return &termIndex{terms: map[string]map[string]int{}}
+}
+
+func (ix *termIndex) add(id, text string) {
+	terms := Terms(text)
+	if len(terms) > maxMemoryTerm {
+		terms = terms[:maxMemoryTerm]
+	}
+
+	for _, t := range terms {
+		docs, ok := ix.terms[t]
+		if !ok {
+			docs = map[string]int{}
+			ix.terms[t] = docs
+		}
+
+		docs[id]++
+	}
+}
+
+func (ix *termIndex) finish() {
+	ix.sorted = make([]string, 0, len(ix.terms))
+	for t := range ix.terms {
+		ix.sorted = append(ix.sorted, t)
+	}
+
+	sort.Strings(ix.sorted)
+}
+
+// rank doc id that match every term as prefix, highest score first
+func (ix *termIndex) match(terms []string) []string {
+	scores := map[string]int{}
+
+	for i, q := range terms {
+		matched := map[string]int{}
+
+		for j := sort.SearchStrings(ix.sorted, q); j < len(ix.sorted) && strings.HasPrefix(ix.sorted[j], q); j++ {
+			for id, tf := range ix.terms[ix.sorted[j]] {
+				matched[id] += tf
+			}
+		}
+
+		// keep only doc that match all previous term too
+		next := map[string]int{}
+		for id, tf := range matched {
+			if score, ok := scores[id]; ok || i == 0 {
+				next[id] = score + tf
+			}
+		}
+
+		scores = next
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(a, b int) bool {
+		if scores[ids[a]] != scores[ids[b]] {
+			return scores[ids[a]] > scores[ids[b]]
+		}
+
+		return ids[a] < ids[b]
+	})
+
+	if len(ids) > maxCandidate {
+		ids = ids[:maxCandidate]
+	}
+
+	return ids
+}
+
+// MemoryIndex is pure Go fallback used when the database has no FULLTEXT support.
+// It is rebuilt from the database periodically, so new content show up after the next refresh.
+// It is used instead of a SQLite FTS index because the server only ship the MySQL driver,
+// and it only keep term of the newest MaxMemoryDoc post and message, older one is not found.
+type MemoryIndex struct {
+	Repository *Repository
+	interval   time.Duration
+
+	mu      sync.RWMutex
+	user    *termIndex
+	post    *termIndex
+	message map[string]*termIndex
+}
+
+func NewMemoryIndex(r *Repository) *MemoryIndex {
+	return &MemoryIndex{
+		Repository: r,
+		interval:   time.Duration(1) * time.Minute,
+		user:       newTermIndex(),
+		post:       newTermIndex(),
+		message:    map[string]*termIndex{},
+	}
+}
+
+// build index from database
+func (m *MemoryIndex) Build() error {
+	userIx := newTermIndex()
+	err := m.Repository.EachUserDoc(MaxMemoryUser, func(id, text string) {
+		userIx.add(id, text)
+	})
+	if err != nil {
+		log.Println("1. Build", err)
+		return err
+	}
+
+	postIx := newTermIndex()
+	err = m.Repository.EachPostDoc(MaxMemoryDoc, func(id, text string) {
+		postIx.add(id, text)
+	})
+	if err != nil {
+		log.Println("2. Build", err)
+		return err
+	}
+
+	messageIx := map[string]*termIndex{}
+	err = m.Repository.EachMessageDoc(MaxMemoryDoc, func(id, room_id, text string) {
+		ix, ok := messageIx[room_id]
+		if !ok {
+			ix = newTermIndex()
+			messageIx[room_id] = ix
+		}
+
+		ix.add(id, text)
+	})
+	if err != nil {
+		log.Println("3. Build", err)
+		return err
+	}
+
+	userIx.finish()
+	postIx.finish()
+	for _, ix := range messageIx {
+		ix.finish()
+	}
+
+	m.mu.Lock()
+	m.user, m.post, m.message = userIx, postIx, messageIx
+	m.mu.Unlock()
+
+	return nil
+}
+
+// rebuild index every interval until context is done
+func (m *MemoryIndex) Run(c context.Context) {
+	if err := m.Build(); err != nil {
+		log.Println("1. Run", err)
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if err := m.Build(); err != nil {
+				log.Println("2. Run", err)
+			}
+		}
+	}
+}
+
+func (m *MemoryIndex) SearchUser(query, viewer_id string, limit, offset int) ([]*UserResultType, error) {
+	terms := Terms(query)
+
+	m.mu.RLock()
+	ids := m.user.match(terms)
+	m.mu.RUnlock()
+
+	users, err := m.Repository.GetUserResultByID(ids, viewer_id)
+	if err != nil {
+		log.Println("1. SearchUser", err)
+		return nil, err
+	}
+
+	for _, u := range users {
+		u.Highlight = Highlight(u.User_Name, terms)
+	}
+
+	return paginate(users, limit, offset), nil
+}
+
+func (m *MemoryIndex) SearchPost(query, viewer_id string, limit, offset int) ([]*PostResultType, error) {
+	terms := Terms(query)
+
+	m.mu.RLock()
+	ids := m.post.match(terms)
+	m.mu.RUnlock()
+
+	posts, err := m.Repository.GetPostResultByID(ids, viewer_id)
+	if err != nil {
+		log.Println("1. SearchPost", err)
+		return nil, err
+	}
+
+	for _, p := range posts {
+		p.Highlight = Highlight(p.Content, terms)
+	}
+
+	return paginate(posts, limit, offset), nil
+}
+
+func (m *MemoryIndex) SearchMessage(query, room_id string, limit, offset int) ([]*MessageResultType, error) {
+	terms := Terms(query)
+
+	m.mu.RLock()
+	ids := []string{}
+	if ix, ok := m.message[room_id]; ok {
+		ids = ix.match(terms)
+	}
+	m.mu.RUnlock()
+
+	messages, err := m.Repository.GetMessageResultByID(ids)
+	if err != nil {
+		log.Println("1. SearchMessage", err)
+		return nil, err
+	}
+
+	for _, msg := range messages {
+		msg.Highlight = Highlight(msg.Content, terms)
+	}
+
+	return paginate(messages, limit, offset), nil
+}
+
+// cut ranked result to limit + 1 row from offset
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+
+	end := offset + limit + 1
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return items[offset:end]
+}
@@ -0,0 +1,386 @@
+package search
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+
+	"github.com/erlnerlngga/backend-socius/internal/user"
+)
+
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Repository search with mysql FULLTEXT index
+type Repository struct {
+	db DBTX
+}
+
+func NewSearchRepository(db DBTX) *Repository {
+	return &Repository{db: db}
+}
+
+// turn user input to boolean mode query, every term required and matched as prefix
+func booleanQuery(terms []string) string {
+	parts := []string{}
+	for _, t := range terms {
+		parts = append(parts, "+"+t+"*")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// search user by name prefix, skip user that block or blocked by viewer
+func (r *Repository) SearchUser(query, viewer_id string, limit, offset int) ([]*UserResultType, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return []*UserResultType{}, nil
+	}
+
+	q := booleanQuery(terms)
+	querySearch := "select user_id, user_name, photo_profile, match(user_name) against(? in boolean mode) as `score` from user " +
+		"where match(user_name) against(? in boolean mode) " +
+		"and not exists (select 1 from user_block where (user_block.user_id = user.user_id and user_block.blocked_id = ?) or (user_block.user_id = ? and user_block.blocked_id = user.user_id)) " +
+		"order by `score` desc, user_name asc, user_id asc limit ? offset ?;"
+
+	rows, err := r.db.Query(querySearch, q, q, viewer_id, viewer_id, limit+1, offset)
+	if err != nil {
+		log.Println("1. SearchUser", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []*UserResultType{}
+	for rows.Next() {
+		u := new(UserResultType)
+
+		if err := rows.Scan(&u.User_ID, &u.User_Name, &u.Photo_Profile, &u.Score); err != nil {
+			log.Println("2. SearchUser", err)
+			return nil, err
+		}
+
+		u.Highlight = Highlight(u.User_Name, terms)
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. SearchUser", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// search main and quote post that viewer can see
+func (r *Repository) SearchPost(query, viewer_id string, limit, offset int) ([]*PostResultType, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return []*PostResultType{}, nil
+	}
+
+	q := booleanQuery(terms)
+	querySearch := "select post.post_id, post.user_id, user.user_name, user.photo_profile, post.content, post.created_at, match(post.content) against(? in boolean mode) as `score` " +
+		"from post inner join user on post.user_id = user.user_id " +
+		"where match(post.content) against(? in boolean mode) and post.type in ('main', 'quote') and " + user.VisibleCond("post") + " " +
+		"order by `score` desc, post.created_at desc limit ? offset ?;"
+
+	args := append([]any{q, q}, user.VisibleArgs(viewer_id)...)
+	rows, err := r.db.Query(querySearch, append(args, limit+1, offset)...)
+	if err != nil {
+		log.Println("1. SearchPost", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	posts := []*PostResultType{}
+	for rows.Next() {
+		p := new(PostResultType)
+
+		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.User_Name, &p.Photo_Profile, &p.Content, &p.Created_At, &p.Score); err != nil {
+			log.Println("2. SearchPost", err)
+			return nil, err
+		}
+
+		p.Highlight = Highlight(p.Content, terms)
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. SearchPost", err)
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// search message inside single room
+func (r *Repository) SearchMessage(query, room_id string, limit, offset int) ([]*MessageResultType, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return []*MessageResultType{}, nil
+	}
+
+	q := booleanQuery(terms)
+	querySearch := "select message.message_id, message.room_id, message.user_id, user.user_name, message.content, message.created_at, match(message.content) against(? in boolean mode) as `score` " +
+		"from message inner join user on message.user_id = user.user_id " +
+		"where match(message.content) against(? in boolean mode) and message.room_id = ? and message.hidden = false " +
+		"order by `score` desc, message.created_at desc limit ? offset ?;"
+
+	rows, err := r.db.Query(querySearch, q, q, room_id, limit+1, offset)
+	if err != nil {
+		log.Println("1. SearchMessage", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	messages := []*MessageResultType{}
+	for rows.Next() {
+		m := new(MessageResultType)
+
+		if err := rows.Scan(&m.Message_ID, &m.Room_ID, &m.User_ID, &m.User_Name, &m.Content, &m.Created_At, &m.Score); err != nil {
+			log.Println("2. SearchMessage", err)
+			return nil, err
+		}
+
+		m.Highlight = Highlight(m.Content, terms)
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. SearchMessage", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// check user is client of the room
+func (r *Repository) IsRoomMember(room_id, user_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from client where room_id = ? and user_id = ?;"
+	err := r.db.QueryRow(query, room_id, user_id).Scan(&number)
+	if err != nil {
+		log.Println("1. IsRoomMember", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// call fn for user up to limit, used to build memory index
+func (r *Repository) EachUserDoc(limit int, fn func(id, text string)) error {
+	return r.eachDoc("EachUserDoc", `select user_id, user_name from user limit ?;`, limit, func(rows *sql.Rows) error {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return err
+		}
+
+		fn(id, text)
+		return nil
+	})
+}
+
+// call fn for newest main post up to limit, used to build memory index
+func (r *Repository) EachPostDoc(limit int, fn func(id, text string)) error {
+	return r.eachDoc("EachPostDoc", `select post_id, content from post where type in ('main', 'quote') order by created_at desc limit ?;`, limit, func(rows *sql.Rows) error {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return err
+		}
+
+		fn(id, text)
+		return nil
+	})
+}
+
+// call fn for newest message up to limit, used to build memory index
+func (r *Repository) EachMessageDoc(limit int, fn func(id, room_id, text string)) error {
+	return r.eachDoc("EachMessageDoc", `select message_id, room_id, content from message order by created_at desc limit ?;`, limit, func(rows *sql.Rows) error {
+		var id, roomID, text string
+		if err := rows.Scan(&id, &roomID, &text); err != nil {
+			return err
+		}
+
+		fn(id, roomID, text)
+		return nil
+	})
+}
+
+func (r *Repository) eachDoc(name, query string, limit int, scan func(rows *sql.Rows) error) error {
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		log.Println("1.", name, err)
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			log.Println("2.", name, err)
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3.", name, err)
+		return err
+	}
+
+	return nil
+}
+
+// placeholder and args for "in (...)" clause
+func inClause(ids []string) (string, []any) {
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")", args
+}
+
+// get user by ranked id, keep the rank and skip user that block or blocked by viewer
+func (r *Repository) GetUserResultByID(ids []string, viewer_id string) ([]*UserResultType, error) {
+	result := []*UserResultType{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	in, args := inClause(ids)
+	query := "select user_id, user_name, photo_profile from user where user_id in " + in + " " +
+		"and not exists (select 1 from user_block where (user_block.user_id = user.user_id and user_block.blocked_id = ?) or (user_block.user_id = ? and user_block.blocked_id = user.user_id));"
+
+	rows, err := r.db.Query(query, append(args, viewer_id, viewer_id)...)
+	if err != nil {
+		log.Println("1. GetUserResultByID", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	found := map[string]*UserResultType{}
+	for rows.Next() {
+		u := new(UserResultType)
+
+		if err := rows.Scan(&u.User_ID, &u.User_Name, &u.Photo_Profile); err != nil {
+			log.Println("2. GetUserResultByID", err)
+			return nil, err
+		}
+
+		found[u.User_ID] = u
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetUserResultByID", err)
+		return nil, err
+	}
+
+	for i, id := range ids {
+		if u, ok := found[id]; ok {
+			u.Score = float64(len(ids) - i)
+			result = append(result, u)
+		}
+	}
+
+	return result, nil
+}
+
+// get post by ranked id, keep the rank and skip post that viewer can't see
+func (r *Repository) GetPostResultByID(ids []string, viewer_id string) ([]*PostResultType, error) {
+	result := []*PostResultType{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	in, args := inClause(ids)
+	query := "select post.post_id, post.user_id, user.user_name, user.photo_profile, post.content, post.created_at from post inner join user on post.user_id = user.user_id " +
+		"where post.post_id in " + in + " and " + user.VisibleCond("post") + ";"
+
+	rows, err := r.db.Query(query, append(args, user.VisibleArgs(viewer_id)...)...)
+	if err != nil {
+		log.Println("1. GetPostResultByID", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	found := map[string]*PostResultType{}
+	for rows.Next() {
+		p := new(PostResultType)
+
+		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.User_Name, &p.Photo_Profile, &p.Content, &p.Created_At); err != nil {
+			log.Println("2. GetPostResultByID", err)
+			return nil, err
+		}
+
+		found[p.Post_ID] = p
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetPostResultByID", err)
+		return nil, err
+	}
+
+	for i, id := range ids {
+		if p, ok := found[id]; ok {
+			p.Score = float64(len(ids) - i)
+			result = append(result, p)
+		}
+	}
+
+	return result, nil
+}
+
+// get message by ranked id and keep the rank
+func (r *Repository) GetMessageResultByID(ids []string) ([]*MessageResultType, error) {
+	result := []*MessageResultType{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	in, args := inClause(ids)
+	query := "select message.message_id, message.room_id, message.user_id, user.user_name, message.content, message.created_at from message inner join user on message.user_id = user.user_id where message.message_id in " + in + " and message.hidden = false;"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Println("1. GetMessageResultByID", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	found := map[string]*MessageResultType{}
+	for rows.Next() {
+		m := new(MessageResultType)
+
+		if err := rows.Scan(&m.Message_ID, &m.Room_ID, &m.User_ID, &m.User_Name, &m.Content, &m.Created_At); err != nil {
+			log.Println("2. GetMessageResultByID", err)
+			return nil, err
+		}
+
+		found[m.Message_ID] = m
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetMessageResultByID", err)
+		return nil, err
+	}
+
+	for i, id := range ids {
+		if m, ok := found[id]; ok {
+			m.Score = float64(len(ids) - i)
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
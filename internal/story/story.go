@@ -0,0 +1,48 @@
+package story
+
+import (
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/internal/media"
+)
+
+// story disappear after this
+const StoryLifetime = time.Duration(24) * time.Hour
+
+// maximum length of text story or caption
+const MaxStoryContent = 500
+
+type StoryReqType struct {
+	Content  string `json:"content"`
+	Media_ID string `json:"media_id"` // uploaded image, empty for text story
+}
+
+type StoryType struct {
+	Story_ID         string           `json:"story_id"`
+	User_ID          string           `json:"user_id"`
+	Type             string           `json:"type"` // text or image
+	Content          string           `json:"content"`
+	Media_ID         string           `json:"media_id,omitempty"`
+	Media            *media.MediaType `json:"media,omitempty"`
+	Seen             bool             `json:"seen"`
+	Number_Of_Viewer int              `json:"number_of_viewer"` // only filled for the author
+	Created_At       time.Time        `json:"created_at"`
+	Expires_At       time.Time        `json:"expires_at"`
+}
+
+// one user in the stories tray
+type TrayType struct {
+	User_ID         string    `json:"user_id"`
+	User_Name       string    `json:"user_name"`
+	Photo_Profile   string    `json:"photo_profile"`
+	Number_Of_Story int       `json:"number_of_story"`
+	Unseen          int       `json:"unseen"`
+	Latest_At       time.Time `json:"latest_at"`
+}
+
+type ViewerType struct {
+	User_ID       string    `json:"user_id"`
+	User_Name     string    `json:"user_name"`
+	Photo_Profile string    `json:"photo_profile"`
+	Viewed_At     time.Time `json:"viewed_at"`
+}
@@ -0,0 +1,212 @@
+package story
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/erlnerlngga/backend-socius/internal/media"
+	"github.com/erlnerlngga/backend-socius/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	Repository *Repository
+	Media      *media.Handler
+}
+
+func NewStoryHandler(r *Repository, m *media.Handler) *Handler {
+	return &Handler{
+		Repository: r,
+		Media:      m,
+	}
+}
+
+// create text story or image story from uploaded media
+func (h *Handler) CreateStory(w http.ResponseWriter, r *http.Request) error {
+	req := new(StoryReqType)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Println("1. CreateStory", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	req.Content = strings.TrimSpace(req.Content)
+	if utf8.RuneCountInString(req.Content) > MaxStoryContent {
+		return fmt.Errorf("content is too long")
+	}
+
+	userID := util.GetUserID(r)
+
+	s := &StoryType{
+		User_ID: userID,
+		Type:    "text",
+		Content: req.Content,
+	}
+
+	if req.Media_ID != "" {
+		// only media uploaded by the user itself
+		found, err := h.Media.Repository.GetOwnMedia([]string{req.Media_ID}, userID)
+		if err != nil {
+			log.Println("2. CreateStory", err)
+			return err
+		}
+
+		s.Type = "image"
+		s.Media_ID = req.Media_ID
+		s.Media = found[0]
+	} else if s.Content == "" {
+		return fmt.Errorf("story must have content or media")
+	}
+
+	if err := h.Repository.CreateStory(s); err != nil {
+		log.Println("3. CreateStory", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, s)
+}
+
+// author delete own story before it expire
+func (h *Handler) DeleteStory(w http.ResponseWriter, r *http.Request) error {
+	storyID := chi.URLParam(r, "storyID")
+
+	ownerID, mediaID, err := h.Repository.GetStoryOwner(storyID)
+	if err != nil {
+		log.Println("1. DeleteStory", err)
+		return err
+	}
+
+	if ownerID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not your story"})
+	}
+
+	if err := h.removeStory(storyID, mediaID); err != nil {
+		log.Println("2. DeleteStory", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// delete story and its media when nothing else use the media.
+// Media go first and the story last, so story that fail halfway is still found by the reaper next time.
+func (h *Handler) removeStory(story_id, media_id string) error {
+	if media_id != "" {
+		used, err := h.Repository.IsMediaUsed(media_id, story_id)
+		if err != nil {
+			log.Println("1. removeStory", err)
+			return err
+		}
+
+		if !used {
+			if err := h.Media.DeleteMedia(media_id); err != nil {
+				log.Println("2. removeStory", err)
+				return err
+			}
+		}
+	}
+
+	if err := h.Repository.DeleteStory(story_id); err != nil {
+		log.Println("3. removeStory", err)
+		return err
+	}
+
+	return nil
+}
+
+// friend that have active story, unseen first
+func (h *Handler) GetStoryTray(w http.ResponseWriter, r *http.Request) error {
+	tray, err := h.Repository.GetTray(util.GetUserID(r))
+	if err != nil {
+		log.Println("1. GetStoryTray", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, tray)
+}
+
+// active story of user, oldest first
+func (h *Handler) GetAllStory(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	stories, err := h.Repository.GetAllStory(userID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. GetAllStory", err)
+		return err
+	}
+
+	for _, s := range stories {
+		if s.Media_ID == "" {
+			continue
+		}
+
+		s.Media, err = h.Media.Repository.GetMedia(s.Media_ID)
+		if err != nil {
+			log.Println("2. GetAllStory", err)
+			return err
+		}
+	}
+
+	return util.WriteJSON(w, http.StatusOK, stories)
+}
+
+// mark story as seen by current user
+func (h *Handler) ViewStory(w http.ResponseWriter, r *http.Request) error {
+	storyID := chi.URLParam(r, "storyID")
+	userID := util.GetUserID(r)
+
+	s, err := h.Repository.GetStory(storyID, userID)
+	if err != nil {
+		log.Println("1. ViewStory", err)
+		return err
+	}
+
+	// author is not counted as viewer
+	if s.User_ID == userID {
+		return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+
+	if err := h.Repository.ViewStory(storyID, userID); err != nil {
+		log.Println("2. ViewStory", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// who has seen the story, only for the author
+func (h *Handler) GetStoryViewer(w http.ResponseWriter, r *http.Request) error {
+	storyID := chi.URLParam(r, "storyID")
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetStoryViewer", err)
+		return err
+	}
+
+	ownerID, _, err := h.Repository.GetStoryOwner(storyID)
+	if err != nil {
+		log.Println("2. GetStoryViewer", err)
+		return err
+	}
+
+	if ownerID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not your story"})
+	}
+
+	viewers, err := h.Repository.GetAllViewer(storyID, page)
+	if err != nil {
+		log.Println("3. GetStoryViewer", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(viewers, page, func(v *ViewerType) (time.Time, string) {
+		return v.Viewed_At, v.User_ID
+	}))
+}
@@ -0,0 +1,69 @@
+package story
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// expired story deleted in one batch
+const reapBatch = 100
+
+// Reaper delete expired story and its media periodically. Every server instance can run one,
+// deleting the same story twice is harmless.
+type Reaper struct {
+	Handler  *Handler
+	interval time.Duration
+}
+
+func NewReaper(h *Handler) *Reaper {
+	return &Reaper{
+		Handler:  h,
+		interval: time.Duration(1) * time.Minute,
+	}
+}
+
+// reap once at start and then every interval until context is done
+func (p *Reaper) Run(c context.Context) {
+	if err := p.Reap(); err != nil {
+		log.Println("1. Run", err)
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if err := p.Reap(); err != nil {
+				log.Println("2. Run", err)
+			}
+		}
+	}
+}
+
+// delete every expired story batch by batch
+func (p *Reaper) Reap() error {
+	now := time.Now().UTC()
+
+	for {
+		stories, err := p.Handler.Repository.GetExpiredStory(now, reapBatch)
+		if err != nil {
+			log.Println("1. Reap", err)
+			return err
+		}
+
+		for _, s := range stories {
+			if err := p.Handler.removeStory(s.Story_ID, s.Media_ID); err != nil {
+				log.Println("2. Reap", err)
+				return err
+			}
+		}
+
+		if len(stories) < reapBatch {
+			return nil
+		}
+	}
+}
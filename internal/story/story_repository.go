@@ -0,0 +1,288 @@
+package story
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/internal/media"
+	"github.com/erlnerlngga/backend-socius/util"
+	"github.com/google/uuid"
+)
+
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type Repository struct {
+	db DBTX
+}
+
+func NewStoryRepository(db DBTX) *Repository {
+	return &Repository{db: db}
+}
+
+// story of alias is visible to author and friend of author, never across block.
+// args are viewer_id four times
+const visibleCond = "(%[1]s.user_id = ? or %[1]s.user_id in (select friend_id from user_friend where user_id = ?)) " +
+	"and not exists (select 1 from user_block where (user_block.user_id = %[1]s.user_id and user_block.blocked_id = ?) or (user_block.user_id = ? and user_block.blocked_id = %[1]s.user_id))"
+
+func visibleArgs(viewer_id string) []any {
+	return []any{viewer_id, viewer_id, viewer_id, viewer_id}
+}
+
+// create story, expire after StoryLifetime
+func (r *Repository) CreateStory(s *StoryType) error {
+	s.Story_ID = uuid.New().String()
+	s.Created_At = time.Now().UTC()
+	s.Expires_At = s.Created_At.Add(StoryLifetime)
+
+	query := `insert into story(story_id, user_id, type, content, media_id, created_at, expires_at) values (?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, s.Story_ID, s.User_ID, s.Type, s.Content, s.Media_ID, s.Created_At, s.Expires_At)
+	if err != nil {
+		log.Println("1. CreateStory", err)
+		return err
+	}
+
+	return nil
+}
+
+// get active story that viewer can see
+func (r *Repository) GetStory(story_id, viewer_id string) (*StoryType, error) {
+	s := new(StoryType)
+
+	query := "select story.story_id, story.user_id, story.type, story.content, story.media_id, story.created_at, story.expires_at, " +
+		"exists (select 1 from story_view where story_view.story_id = story.story_id and story_view.viewer_id = ?) " +
+		"from story where story.story_id = ? and story.expires_at > ? and " + fmt.Sprintf(visibleCond, "story") + ";"
+
+	args := append([]any{viewer_id, story_id, time.Now().UTC()}, visibleArgs(viewer_id)...)
+	err := r.db.QueryRow(query, args...).Scan(&s.Story_ID, &s.User_ID, &s.Type, &s.Content, &s.Media_ID, &s.Created_At, &s.Expires_At, &s.Seen)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("story not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetStory", err)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// get active story of user that viewer can see, oldest first like it is played
+func (r *Repository) GetAllStory(user_id, viewer_id string) ([]*StoryType, error) {
+	query := "select story.story_id, story.user_id, story.type, story.content, story.media_id, story.created_at, story.expires_at, " +
+		"exists (select 1 from story_view where story_view.story_id = story.story_id and story_view.viewer_id = ?), " +
+		"(select count(*) from story_view where story_view.story_id = story.story_id) " +
+		"from story where story.user_id = ? and story.expires_at > ? and " + fmt.Sprintf(visibleCond, "story") + " order by story.created_at asc;"
+
+	rows, err := r.db.Query(query, append([]any{viewer_id, user_id, time.Now().UTC()}, visibleArgs(viewer_id)...)...)
+	if err != nil {
+		log.Println("1. GetAllStory", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stories := []*StoryType{}
+	for rows.Next() {
+		s := new(StoryType)
+
+		if err := rows.Scan(&s.Story_ID, &s.User_ID, &s.Type, &s.Content, &s.Media_ID, &s.Created_At, &s.Expires_At, &s.Seen, &s.Number_Of_Viewer); err != nil {
+			log.Println("2. GetAllStory", err)
+			return nil, err
+		}
+
+		// viewer count is only for the author
+		if s.User_ID != viewer_id {
+			s.Number_Of_Viewer = 0
+		}
+
+		stories = append(stories, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllStory", err)
+		return nil, err
+	}
+
+	return stories, nil
+}
+
+// GetTray list viewer and friend that have active story. own story come first,
+// then user with unseen story, then the most recent one.
+func (r *Repository) GetTray(viewer_id string) ([]*TrayType, error) {
+	unseen := "sum(case when story_view.story_id is null then 1 else 0 end)"
+
+	query := "select story.user_id, user.user_name, user.photo_profile, count(*) as `number_of_story`, " + unseen + " as `unseen`, max(story.created_at) as `latest_at` " +
+		"from story inner join user on story.user_id = user.user_id " +
+		"left join story_view on story_view.story_id = story.story_id and story_view.viewer_id = ? " +
+		"where story.expires_at > ? and " + fmt.Sprintf(visibleCond, "story") + " " +
+		"group by story.user_id, user.user_name, user.photo_profile " +
+		"order by story.user_id = ? desc, " + unseen + " > 0 desc, max(story.created_at) desc;"
+
+	args := append(append([]any{viewer_id, time.Now().UTC()}, visibleArgs(viewer_id)...), viewer_id)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Println("1. GetTray", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tray := []*TrayType{}
+	for rows.Next() {
+		t := new(TrayType)
+
+		if err := rows.Scan(&t.User_ID, &t.User_Name, &t.Photo_Profile, &t.Number_Of_Story, &t.Unseen, &t.Latest_At); err != nil {
+			log.Println("2. GetTray", err)
+			return nil, err
+		}
+
+		// author never has unseen own story
+		if t.User_ID == viewer_id {
+			t.Unseen = 0
+		}
+
+		tray = append(tray, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetTray", err)
+		return nil, err
+	}
+
+	return tray, nil
+}
+
+// mark story seen by viewer, seeing it again keep the first time
+func (r *Repository) ViewStory(story_id, viewer_id string) error {
+	query := `insert ignore into story_view(story_id, viewer_id, viewed_at) values (?, ?, ?);`
+	_, err := r.db.Exec(query, story_id, viewer_id, time.Now().UTC())
+	if err != nil {
+		log.Println("1. ViewStory", err)
+		return err
+	}
+
+	return nil
+}
+
+// get viewer of story, the latest viewer first
+func (r *Repository) GetAllViewer(story_id string, page *util.PageReqType) ([]*ViewerType, error) {
+	cond, args := page.Query("story_view.viewed_at", "story_view.viewer_id")
+	query := "select user.user_id, user.user_name, user.photo_profile, story_view.viewed_at from story_view inner join user on story_view.viewer_id = user.user_id where story_view.story_id = ?" + cond + ";"
+
+	rows, err := r.db.Query(query, append([]any{story_id}, args...)...)
+	if err != nil {
+		log.Println("1. GetAllViewer", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	viewers := []*ViewerType{}
+	for rows.Next() {
+		v := new(ViewerType)
+
+		if err := rows.Scan(&v.User_ID, &v.User_Name, &v.Photo_Profile, &v.Viewed_At); err != nil {
+			log.Println("2. GetAllViewer", err)
+			return nil, err
+		}
+
+		viewers = append(viewers, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllViewer", err)
+		return nil, err
+	}
+
+	return viewers, nil
+}
+
+// get story that already expired, include the one of other instance that fail to finish
+func (r *Repository) GetExpiredStory(now time.Time, limit int) ([]*StoryType, error) {
+	query := `select story_id, user_id, type, content, media_id, created_at, expires_at from story where expires_at <= ? order by expires_at asc limit ?;`
+
+	rows, err := r.db.Query(query, now, limit)
+	if err != nil {
+		log.Println("1. GetExpiredStory", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stories := []*StoryType{}
+	for rows.Next() {
+		s := new(StoryType)
+
+		if err := rows.Scan(&s.Story_ID, &s.User_ID, &s.Type, &s.Content, &s.Media_ID, &s.Created_At, &s.Expires_At); err != nil {
+			log.Println("2. GetExpiredStory", err)
+			return nil, err
+		}
+
+		stories = append(stories, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetExpiredStory", err)
+		return nil, err
+	}
+
+	return stories, nil
+}
+
+// get owner of story, also the expired one
+func (r *Repository) GetStoryOwner(story_id string) (string, string, error) {
+	var user_id, media_id string
+
+	err := r.db.QueryRow(`select user_id, media_id from story where story_id = ?;`, story_id).Scan(&user_id, &media_id)
+	if err == sql.ErrNoRows {
+		return "", "", fmt.Errorf("story not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetStoryOwner", err)
+		return "", "", err
+	}
+
+	return user_id, media_id, nil
+}
+
+// delete story and its view
+func (r *Repository) DeleteStory(story_id string) error {
+	cascade := []string{
+		`delete from story_view where story_id = ?;`,
+		`delete from story where story_id = ?;`,
+	}
+
+	for _, query := range cascade {
+		if _, err := r.db.Exec(query, story_id); err != nil {
+			log.Println("1. DeleteStory", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check media is still used by post, profile photo, album, draft or other story, so it must not be deleted
+func (r *Repository) IsMediaUsed(media_id, story_id string) (bool, error) {
+	var number int
+
+	// draft keep media_id in its json images until it is published
+	query := "select (select count(*) from image_post where media_id = ?) + (select count(*) from user where photo_profile = ?) + " +
+		"(select count(*) from album_photo where media_id = ?) + (select count(*) from story where media_id = ? and story_id <> ?) + " +
+		"(select count(*) from draft where status <> 'published' and images like concat('%\"', ?, '\"%')) as `number`;"
+	err := r.db.QueryRow(query, media_id, media.URL(media_id), media_id, media_id, story_id, media_id).Scan(&number)
+	if err != nil {
+		log.Println("1. IsMediaUsed", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
@@ -0,0 +1,52 @@
+package trending
+
+import (
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/internal/user"
+)
+
+// sliding window of trending, engagement older than the window is ignored and
+// the rest lose half of its weight every half life
+type WindowType struct {
+	Name      string
+	Length    time.Duration
+	Half_Life time.Duration
+}
+
+var Windows = map[string]*WindowType{
+	"day":  {Name: "day", Length: 24 * time.Hour, Half_Life: 6 * time.Hour},
+	"week": {Name: "week", Length: 7 * 24 * time.Hour, Half_Life: 48 * time.Hour},
+}
+
+const DefaultWindow = "day"
+
+// weight of each engagement, repost spread the post the most
+const (
+	ReactionWeight = 1.0
+	CommentWeight  = 2.0
+	RepostWeight   = 3.0
+	HashtagWeight  = 1.0 // every public post that use the tag
+)
+
+// number of item kept per window
+const MaxTrendingPost = 100
+const MaxTrendingTag = 50
+
+// score of one post or hashtag, Item_ID is post_id or tag
+type ScoreType struct {
+	Item_ID string  `json:"-"`
+	Score   float64 `json:"score"`
+}
+
+type TagType struct {
+	Tag   string  `json:"tag"`
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+}
+
+type PostType struct {
+	Rank  int                  `json:"rank"`
+	Score float64              `json:"score"`
+	Post  *user.GetPostResType `json:"post"`
+}
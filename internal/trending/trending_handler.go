@@ -0,0 +1,157 @@
+package trending
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/internal/user"
+	"github.com/erlnerlngga/backend-socius/util"
+)
+
+// stored trending only change every refresh, so keep it in memory for a while
+const cacheTTL = time.Duration(1) * time.Minute
+
+type cacheEntry struct {
+	scores  []*ScoreType
+	expires time.Time
+}
+
+type Handler struct {
+	Repository *Repository
+	User       *user.Repository
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+func NewTrendingHandler(r *Repository, u *user.Repository) *Handler {
+	return &Handler{
+		Repository: r,
+		User:       u,
+		cache:      map[string]*cacheEntry{},
+	}
+}
+
+// get score of period and kind from cache, load from database when expired
+func (h *Handler) getScore(period, kind string) ([]*ScoreType, error) {
+	key := period + ":" + kind
+	now := time.Now()
+
+	h.mu.Lock()
+	entry, ok := h.cache[key]
+	h.mu.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		return entry.scores, nil
+	}
+
+	scores, err := h.Repository.GetScore(period, kind)
+	if err != nil {
+		log.Println("1. getScore", err)
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.cache[key] = &cacheEntry{scores: scores, expires: now.Add(cacheTTL)}
+	h.mu.Unlock()
+
+	return scores, nil
+}
+
+func getWindow(r *http.Request) (*WindowType, error) {
+	name := r.URL.Query().Get("window")
+	if name == "" {
+		name = DefaultWindow
+	}
+
+	w, ok := Windows[name]
+	if !ok {
+		return nil, fmt.Errorf("window must be day or week")
+	}
+
+	return w, nil
+}
+
+// trending public post, query: window=day|week
+func (h *Handler) GetTrendingPost(w http.ResponseWriter, r *http.Request) error {
+	window, err := getWindow(r)
+	if err != nil {
+		return err
+	}
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetTrendingPost", err)
+		return err
+	}
+
+	scores, err := h.getScore(window.Name, "post")
+	if err != nil {
+		log.Println("2. GetTrendingPost", err)
+		return err
+	}
+
+	userID := util.GetUserID(r)
+	posts := []*PostType{}
+
+	// keep reading until one extra visible post so NewOffsetPageAt know there is more,
+	// next page start at that post
+	next := 0
+	for i := page.GetOffset(); i < len(scores) && len(posts) <= page.Limit; i++ {
+		// post that is deleted, hidden or from blocked user is skipped
+		visible, err := h.User.CanViewPost(scores[i].Item_ID, userID)
+		if err != nil {
+			log.Println("3. GetTrendingPost", err)
+			return err
+		}
+
+		if !visible {
+			continue
+		}
+
+		post, err := h.User.GetPost(scores[i].Item_ID, userID)
+		if err != nil {
+			log.Println("4. GetTrendingPost", err)
+			return err
+		}
+
+		if len(posts) == page.Limit {
+			next = i
+		}
+
+		posts = append(posts, &PostType{Rank: i + 1, Score: scores[i].Score, Post: post})
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewOffsetPageAt(posts, page, next))
+}
+
+// trending hashtag, query: window=day|week
+func (h *Handler) GetTrendingHashtag(w http.ResponseWriter, r *http.Request) error {
+	window, err := getWindow(r)
+	if err != nil {
+		return err
+	}
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetTrendingHashtag", err)
+		return err
+	}
+
+	scores, err := h.getScore(window.Name, "hashtag")
+	if err != nil {
+		log.Println("2. GetTrendingHashtag", err)
+		return err
+	}
+
+	tags := []*TagType{}
+	offset := page.GetOffset()
+	for i := offset; i < len(scores) && i <= offset+page.Limit; i++ {
+		tags = append(tags, &TagType{Tag: scores[i].Item_ID, Rank: i + 1, Score: scores[i].Score})
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewOffsetPage(tags, page))
+}
@@ -0,0 +1,73 @@
+package trending
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Refresher compute trending of every window periodically. Every server instance can run one,
+// SaveScore is safe to run at the same time.
+type Refresher struct {
+	Repository *Repository
+	interval   time.Duration
+}
+
+func NewRefresher(r *Repository) *Refresher {
+	return &Refresher{
+		Repository: r,
+		interval:   time.Duration(5) * time.Minute,
+	}
+}
+
+// refresh once at start and then every interval until context is done
+func (f *Refresher) Run(c context.Context) {
+	if err := f.Refresh(); err != nil {
+		log.Println("1. Run", err)
+	}
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if err := f.Refresh(); err != nil {
+				log.Println("2. Run", err)
+			}
+		}
+	}
+}
+
+// compute and store trending post and hashtag of every window
+func (f *Refresher) Refresh() error {
+	now := time.Now().UTC()
+
+	for _, w := range Windows {
+		posts, err := f.Repository.ScorePost(w, now, MaxTrendingPost)
+		if err != nil {
+			log.Println("1. Refresh", err)
+			return err
+		}
+
+		tags, err := f.Repository.ScoreHashtag(w, now, posts, MaxTrendingTag)
+		if err != nil {
+			log.Println("2. Refresh", err)
+			return err
+		}
+
+		if err := f.Repository.SaveScore(w.Name, "post", posts, now); err != nil {
+			log.Println("3. Refresh", err)
+			return err
+		}
+
+		if err := f.Repository.SaveScore(w.Name, "hashtag", tags, now); err != nil {
+			log.Println("4. Refresh", err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package trending
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type Repository struct {
+	db DBTX
+}
+
+func NewTrendingRepository(db DBTX) *Repository {
+	return &Repository{db: db}
+}
+
+// only public main post and quote that is not hidden can trend
+const trendingPostCond = "post.type in ('main', 'quote') and post.audience = 'public' and post.hidden = false"
+
+// decayed weight of row with created_at, args are now and half life in second
+const decay = "pow(0.5, timestampdiff(second, e.created_at, ?) / ?)"
+
+// ScorePost sum decayed comment, reaction and repost of each post inside the window.
+// engagement from the author itself is not counted.
+func (r *Repository) ScorePost(w *WindowType, now time.Time, limit int) ([]*ScoreType, error) {
+	since := now.Add(-w.Length)
+
+	query := "select e.post_id, sum(e.weight * " + decay + ") as `score` from (" +
+		"select comment.root_post_id as post_id, cp.user_id as user_id, ? as weight, cp.created_at as created_at from comment inner join post cp on comment.comment_post_id = cp.post_id where cp.created_at >= ? and cp.hidden = false " +
+		"union all " +
+		"select post_id, user_id, ?, created_at from reaction where created_at >= ? " +
+		"union all " +
+		"select original_post_id, user_id, ?, created_at from post where original_post_id is not null and created_at >= ? and hidden = false" +
+		") e inner join post on post.post_id = e.post_id " +
+		"where " + trendingPostCond + " and e.user_id <> post.user_id " +
+		"group by e.post_id order by `score` desc limit ?;"
+
+	rows, err := r.db.Query(query, now, w.Half_Life.Seconds(), CommentWeight, since, ReactionWeight, since, RepostWeight, since, limit)
+	if err != nil {
+		log.Println("1. ScorePost", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	scores := []*ScoreType{}
+	for rows.Next() {
+		s := new(ScoreType)
+
+		if err := rows.Scan(&s.Item_ID, &s.Score); err != nil {
+			log.Println("2. ScorePost", err)
+			return nil, err
+		}
+
+		scores = append(scores, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. ScorePost", err)
+		return nil, err
+	}
+
+	return scores, nil
+}
+
+// ScoreHashtag sum decayed use of each tag in public post inside the window,
+// plus the engagement score of post that has the tag
+func (r *Repository) ScoreHashtag(w *WindowType, now time.Time, posts []*ScoreType, limit int) ([]*ScoreType, error) {
+	since := now.Add(-w.Length)
+
+	query := "select e.tag, sum(? * " + decay + ") as `score` from (" +
+		"select distinct post_hashtag.tag as tag, post.post_id as post_id, post.created_at as created_at from post_hashtag inner join post on post_hashtag.post_id = post.post_id " +
+		"where " + trendingPostCond + " and post.created_at >= ?" +
+		") e group by e.tag;"
+
+	rows, err := r.db.Query(query, HashtagWeight, now, w.Half_Life.Seconds(), since)
+	if err != nil {
+		log.Println("1. ScoreHashtag", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	byTag := map[string]float64{}
+	for rows.Next() {
+		var tag string
+		var score float64
+
+		if err := rows.Scan(&tag, &score); err != nil {
+			log.Println("2. ScoreHashtag", err)
+			return nil, err
+		}
+
+		byTag[tag] += score
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. ScoreHashtag", err)
+		return nil, err
+	}
+
+	if len(posts) > 0 {
+		postScore := map[string]float64{}
+		ids := []any{}
+		for _, p := range posts {
+			postScore[p.Item_ID] = p.Score
+			ids = append(ids, p.Item_ID)
+		}
+
+		in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")"
+		tagRows, err := r.db.Query("select distinct post_id, tag from post_hashtag where post_id in "+in+";", ids...)
+		if err != nil {
+			log.Println("4. ScoreHashtag", err)
+			return nil, err
+		}
+
+		defer tagRows.Close()
+
+		for tagRows.Next() {
+			var post_id, tag string
+
+			if err := tagRows.Scan(&post_id, &tag); err != nil {
+				log.Println("5. ScoreHashtag", err)
+				return nil, err
+			}
+
+			byTag[tag] += postScore[post_id]
+		}
+
+		if err := tagRows.Err(); err != nil {
+			log.Println("6. ScoreHashtag", err)
+			return nil, err
+		}
+	}
+
+	scores := []*ScoreType{}
+	for tag, score := range byTag {
+		scores = append(scores, &ScoreType{Item_ID: tag, Score: score})
+	}
+
+	sortScore(scores)
+
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+
+	return scores, nil
+}
+
+// highest score first, same score ordered by id so result is stable
+func sortScore(scores []*ScoreType) {
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+
+		return scores[i].Item_ID < scores[j].Item_ID
+	})
+}
+
+// SaveScore replace stored score of period and kind. Row is upserted first and the old row
+// deleted after, so reader never see empty list and two instance refreshing together is fine.
+func (r *Repository) SaveScore(period, kind string, scores []*ScoreType, computed_at time.Time) error {
+	// timestamp column only keep second
+	computed_at = computed_at.Truncate(time.Second)
+
+	query := `insert into trending(period, kind, item_id, score, computed_at) values (?, ?, ?, ?, ?) on duplicate key update score = values(score), computed_at = values(computed_at);`
+
+	for _, s := range scores {
+		if _, err := r.db.Exec(query, period, kind, s.Item_ID, s.Score, computed_at); err != nil {
+			log.Println("1. SaveScore", err)
+			return err
+		}
+	}
+
+	_, err := r.db.Exec(`delete from trending where period = ? and kind = ? and computed_at < ?;`, period, kind, computed_at)
+	if err != nil {
+		log.Println("2. SaveScore", err)
+		return err
+	}
+
+	return nil
+}
+
+// get stored score of period and kind, highest first
+func (r *Repository) GetScore(period, kind string) ([]*ScoreType, error) {
+	rows, err := r.db.Query(`select item_id, score from trending where period = ? and kind = ? order by score desc, item_id asc;`, period, kind)
+	if err != nil {
+		log.Println("1. GetScore", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	scores := []*ScoreType{}
+	for rows.Next() {
+		s := new(ScoreType)
+
+		if err := rows.Scan(&s.Item_ID, &s.Score); err != nil {
+			log.Println("2. GetScore", err)
+			return nil, err
+		}
+
+		scores = append(scores, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetScore", err)
+		return nil, err
+	}
+
+	return scores, nil
+}
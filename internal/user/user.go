@@ -1,20 +1,27 @@
 package user
 
-import "time"
+import (
+	"time"
+
+	"github.com/erlnerlngga/backend-socius/internal/media"
+)
 
 type UserType struct {
-	User_ID       string `json:"user_id"`
-	User_Name     string `json:"user_name"`
-	Email         string `json:"email"`
-	Photo_Profile string `json:"photo_profile"`
+	User_ID          string `json:"user_id"`
+	User_Name        string `json:"user_name"`
+	Email            string `json:"email"`
+	Photo_Profile    string `json:"photo_profile"`
+	Default_Audience string `json:"default_audience,omitempty"`
+	Photo_Media_ID   string `json:"photo_media_id,omitempty"`
 }
 
 type UserFriendType struct {
-	User_ID        string `json:"user_id"`
-	User_Name      string `json:"user_name"`
-	Email          string `json:"email"`
-	Photo_Profile  string `json:"photo_profile"`
-	User_Friend_ID string `json:"user_friend_id"`
+	User_ID        string    `json:"user_id"`
+	User_Name      string    `json:"user_name"`
+	Email          string    `json:"email"`
+	Photo_Profile  string    `json:"photo_profile"`
+	User_Friend_ID string    `json:"user_friend_id"`
+	Created_At     time.Time `json:"created_at"`
 }
 
 type SignInType struct {
@@ -28,33 +35,62 @@ type VerifyResType struct {
 }
 
 type User_FriendType struct {
-	User_Friend_ID string `json:"user_friend_id"`
-	User_ID        string `json:"user_id"`
-	Friend_ID      string `json:"friend_id"`
+	User_Friend_ID string    `json:"user_friend_id"`
+	User_ID        string    `json:"user_id"`
+	Friend_ID      string    `json:"friend_id"`
+	Created_At     time.Time `json:"created_at"`
+}
+
+type FriendRequestType struct {
+	Friend_ID string `json:"friend_id"`
+}
+
+// answer of friend request, the request is pending until answered
+var FriendAnswers = map[string]bool{
+	"accept": true,
+	"reject": true,
 }
 
 type PostType struct {
-	Post_ID    string    `json:"post_id"`
-	User_ID    string    `json:"user_id"`
-	Content    string    `json:"content"`
-	Type       string    `json:"type"`
-	Created_At time.Time `json:"created_at"`
-	Updated_At time.Time `json:"updated_at"`
+	Post_ID          string    `json:"post_id"`
+	User_ID          string    `json:"user_id"`
+	Content          string    `json:"content"`
+	Type             string    `json:"type"`
+	Created_At       time.Time `json:"created_at"`
+	Updated_At       time.Time `json:"updated_at"`
+	Audience         string    `json:"audience"`
+	Original_Post_ID string    `json:"original_post_id"`
 }
 
 type PostReqType struct {
-	User_ID string   `json:"user_id"`
-	Content string   `json:"content"`
-	Images  []string `json:"images"`
+	User_ID       string       `json:"user_id"`
+	Content       string       `json:"content"`
+	Images        []string     `json:"images"` // media_id of uploaded image
+	Audience      string       `json:"audience"`
+	Audience_List []string     `json:"audience_list"`
+	Poll          *PollReqType `json:"poll"`
+}
+
+// share post of other user, empty content is plain repost and otherwise quote
+type RepostReqType struct {
+	Post_ID       string   `json:"post_id"`
+	Content       string   `json:"content"`
+	Audience      string   `json:"audience"`
+	Audience_List []string `json:"audience_list"`
 }
 
 type Image_PostType struct {
-	Image_Post_ID string    `json:"image_post_id"`
-	Post_ID       string    `json:"post_id"`
-	User_ID       string    `json:"user_id"`
-	Image         string    `json:"image"`
-	Created_At    time.Time `json:"created_at"`
-	Updated_At    time.Time `json:"updated_at"`
+	Image_Post_ID string               `json:"image_post_id"`
+	Post_ID       string               `json:"post_id"`
+	User_ID       string               `json:"user_id"`
+	Image         string               `json:"image"`
+	Media_ID      string               `json:"media_id"`
+	Width         int                  `json:"width"`
+	Height        int                  `json:"height"`
+	Blurhash      string               `json:"blurhash"`
+	Variants      []*media.VariantType `json:"variants"`
+	Created_At    time.Time            `json:"created_at"`
+	Updated_At    time.Time            `json:"updated_at"`
 }
 
 type GetPostType struct {
@@ -77,15 +113,25 @@ type GetPostResType struct {
 	Updated_At        time.Time         `json:"updated_at"`
 	Images            []*Image_PostType `json:"images"`
 	Number_Of_Comment int               `json:"number_of_comment"`
+	Audience          string            `json:"audience"`
+	Edited            bool              `json:"edited"`
+	Reactions         map[string]int    `json:"reactions"`
+	My_Reaction       string            `json:"my_reaction"`
+	Entities          []*EntityType     `json:"entities"`
 	User_Name         string            `json:"user_name"`
 	Email             string            `json:"email"`
 	Photo_Profile     string            `json:"photo_profile"`
+	Number_Of_Repost  int               `json:"number_of_repost"`
+	Original_Post_ID  string            `json:"original_post_id,omitempty"`
+	Original          *GetPostResType   `json:"original,omitempty"` // nil when original is deleted or hidden from viewer
+	Poll              *PollType         `json:"poll,omitempty"`
 }
 
 type CommentType struct {
 	Comment_ID      string    `json:"comment_id"`
 	Post_ID         string    `json:"post_id"`
 	Comment_Post_ID string    `json:"comment_post_id"`
+	Root_Post_ID    string    `json:"root_post_id"`
 	Created_At      time.Time `json:"created_at"`
 	Updated_At      time.Time `json:"updated_at"`
 }
@@ -94,25 +140,232 @@ type CommentReqType struct {
 	Post_ID string   `json:"post_id"`
 	User_ID string   `json:"user_id"`
 	Content string   `json:"content"`
-	Images  []string `json:"images"`
+	Images  []string `json:"images"` // media_id of uploaded image
+}
+
+type SuggestionType struct {
+	User_ID       string `json:"user_id"`
+	User_Name     string `json:"user_name"`
+	Email         string `json:"email"`
+	Photo_Profile string `json:"photo_profile"`
+	Mutual_Friend int    `json:"mutual_friend"`
 }
 
-type NotificationType struct {
-	Notification_ID string    `json:"notification_id"`
-	Issuer          string    `json:"issuer"`
-	Issuer_Name     string    `json:"issuer_name"`
-	Notifier        string    `json:"notifier"`
-	Notifier_Name   string    `json:"notifier_name"`
-	Status          string    `json:"status"`
-	Accept          string    `json:"accept"`
+type DismissSuggestionType struct {
+	Suggestion_Dismiss_ID string    `json:"suggestion_dismiss_id"`
+	User_ID               string    `json:"user_id"`
+	Dismissed_ID          string    `json:"dismissed_id"`
+	Created_At            time.Time `json:"created_at"`
+}
+
+type User_BlockType struct {
+	User_Block_ID string    `json:"user_block_id"`
+	User_ID       string    `json:"user_id"`
+	Blocked_ID    string    `json:"blocked_id"`
+	Created_At    time.Time `json:"created_at"`
+}
+
+type User_FollowType struct {
+	User_Follow_ID string    `json:"user_follow_id"`
+	Follower_ID    string    `json:"follower_id"`
+	Following_ID   string    `json:"following_id"`
+	Created_At     time.Time `json:"created_at"`
+}
+
+// user in follower or following list
+type FollowUserType struct {
+	User_ID        string    `json:"user_id"`
+	User_Name      string    `json:"user_name"`
+	Photo_Profile  string    `json:"photo_profile"`
+	User_Follow_ID string    `json:"user_follow_id"`
+	Created_At     time.Time `json:"created_at"`
+}
+
+type FollowCountType struct {
+	Follower  int `json:"follower"`
+	Following int `json:"following"`
+}
+
+type UpdatePostReqType struct {
+	Post_ID string   `json:"post_id"`
+	Content string   `json:"content"`
+	Images  []string `json:"images"` // media_id of uploaded image
+}
+
+type Post_HistoryType struct {
+	Post_History_ID string    `json:"post_history_id"`
 	Post_ID         string    `json:"post_id"`
-	Type            string    `json:"type"`
+	Content         string    `json:"content"`
+	Images          []string  `json:"images"`
 	Created_At      time.Time `json:"created_at"`
-	Updated_At      time.Time `json:"updated_at"`
 }
 
-type UpdateNotifType struct {
-	Notification_ID string    `json:"notification_id"`
-	Accept          string    `json:"accept"`
+// fixed set of reaction that can be given to post and comment
+var ReactionTypes = map[string]bool{
+	"like":  true,
+	"love":  true,
+	"haha":  true,
+	"wow":   true,
+	"sad":   true,
+	"angry": true,
+}
+
+type ReactionType struct {
+	Reaction_ID string    `json:"reaction_id"`
+	Post_ID     string    `json:"post_id"`
+	User_ID     string    `json:"user_id"`
+	Type        string    `json:"type"`
+	Created_At  time.Time `json:"created_at"`
+}
+
+type ReactionUserType struct {
+	Reaction_ID   string    `json:"reaction_id"`
+	User_ID       string    `json:"user_id"`
+	User_Name     string    `json:"user_name"`
+	Photo_Profile string    `json:"photo_profile"`
+	Type          string    `json:"type"`
+	Created_At    time.Time `json:"created_at"`
+}
+
+type CommentThreadType struct {
+	*GetPostResType
+	Parent_ID       string               `json:"parent_id"`
+	Depth           int                  `json:"depth"`
+	Score           int                  `json:"score"`
+	Replies         []*CommentThreadType `json:"replies"`
+	Collapsed_Reply int                  `json:"collapsed_reply"`
+}
+
+// audience of post, custom use list of user in post_audience
+var AudienceTypes = map[string]bool{
+	"public":  true,
+	"friends": true,
+	"only_me": true,
+	"custom":  true,
+}
+
+type DefaultAudienceReqType struct {
+	Default_Audience string `json:"default_audience"`
+}
+
+// hashtag or mention inside content, start and end are UTF-16 offset
+type EntityType struct {
+	Type    string `json:"type"`
+	Text    string `json:"text"`
+	User_ID string `json:"user_id,omitempty"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+type CollectionType struct {
+	Collection_ID  string    `json:"collection_id"`
+	User_ID        string    `json:"user_id"`
+	Name           string    `json:"name"`
+	Number_Of_Post int       `json:"number_of_post"`
+	Created_At     time.Time `json:"created_at"`
+}
+
+type BookmarkType struct {
+	Bookmark_ID   string    `json:"bookmark_id"`
+	User_ID       string    `json:"user_id"`
+	Post_ID       string    `json:"post_id"`
+	Collection_ID string    `json:"collection_id"`
+	Created_At    time.Time `json:"created_at"`
+}
+
+type SavedPostType struct {
+	Bookmark_ID   string          `json:"bookmark_id"`
+	Collection_ID string          `json:"collection_id"`
+	Saved_At      time.Time       `json:"saved_at"`
+	Post          *GetPostResType `json:"post"`
+}
+
+// draft is post that is not published yet, scheduled draft is published at Publish_At
+type DraftType struct {
+	Draft_ID      string       `json:"draft_id"`
+	User_ID       string       `json:"user_id"`
+	Content       string       `json:"content"`
+	Images        []string     `json:"images"` // media_id of uploaded image
+	Audience      string       `json:"audience"`
+	Audience_List []string     `json:"audience_list"`
+	Status        string       `json:"status"`
+	Publish_At    *time.Time   `json:"publish_at"`
+	Post_ID       string       `json:"post_id"`
+	Poll          *PollReqType `json:"poll"`
+	Error         string       `json:"error"`
+	Created_At    time.Time    `json:"created_at"`
+	Updated_At    time.Time    `json:"updated_at"`
+}
+
+// minimum and maximum option of poll
+const MinPollOption = 2
+const MaxPollOption = 10
+
+type PollReqType struct {
+	Options   []string   `json:"options"`
+	Multiple  bool       `json:"multiple"`
+	Closes_At *time.Time `json:"closes_at"`
+}
+
+type PollOptionType struct {
+	Option_ID string `json:"option_id"`
+	Text      string `json:"text"`
+	Votes     int    `json:"votes"`
+}
+
+type PollType struct {
+	Post_ID     string            `json:"post_id"`
+	Multiple    bool              `json:"multiple"`
+	Closes_At   *time.Time        `json:"closes_at"`
+	Closed      bool              `json:"closed"`
+	Options     []*PollOptionType `json:"options"`
+	Total_Voter int               `json:"total_voter"`
+	My_Votes    []string          `json:"my_votes"`
+}
+
+type VoteReqType struct {
+	Post_ID    string   `json:"post_id"`
+	Option_IDs []string `json:"option_ids"`
+}
+
+// album made by the system, keyed by where the photo come from
+var AutoAlbums = map[string]string{
+	"post":    "Post photos",
+	"profile": "Profile photos",
+}
+
+// album can't use custom audience
+var AlbumAudienceTypes = map[string]bool{
+	"public":  true,
+	"friends": true,
+	"only_me": true,
+}
+
+type AlbumType struct {
+	Album_ID        string    `json:"album_id"`
+	User_ID         string    `json:"user_id"`
+	Name            string    `json:"name"`
+	Auto_Key        string    `json:"auto_key"` // post or profile, empty for album made by user
+	Audience        string    `json:"audience"`
+	Cover_Image     string    `json:"cover_image"` // chosen cover or the latest photo
+	Number_Of_Photo int       `json:"number_of_photo"`
+	Created_At      time.Time `json:"created_at"`
 	Updated_At      time.Time `json:"updated_at"`
 }
+
+// photo inside album, post_id is the post that show the photo and empty for profile photo
+type AlbumPhotoType struct {
+	Album_ID string `json:"album_id"`
+	Source   string `json:"source"`
+	Image_PostType
+}
+
+type MoveAlbumPhotoReqType struct {
+	Image    string `json:"image"`
+	Album_ID string `json:"album_id"`
+}
+
+type AlbumCoverReqType struct {
+	Album_ID string `json:"album_id"`
+	Image    string `json:"image"` // empty go back to the latest photo
+}
@@ -0,0 +1,60 @@
+package user
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// hashtag and mention start after beginning of content or non word character
+var entityRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])([#@])([\p{L}\p{N}_]+)`)
+
+// longest hashtag or mention in character, same as the tag and user_name column
+const MaxEntityLength = 100
+
+// ParseEntities find hashtag and mention in content.
+// Start and End are UTF-16 offset so browser client can slice the content directly.
+func ParseEntities(content string) []*EntityType {
+	entities := []*EntityType{}
+
+	for _, m := range entityRegex.FindAllStringSubmatchIndex(content, -1) {
+		markStart, textEnd := m[2], m[5]
+		text := content[m[4]:m[5]]
+
+		// too long to be a tag or name, it stay as plain text
+		if utf8.RuneCountInString(text) > MaxEntityLength {
+			continue
+		}
+
+		e := &EntityType{
+			Type:  "hashtag",
+			Text:  strings.ToLower(text),
+			Start: utf16Len(content[:markStart]),
+			End:   utf16Len(content[:textEnd]),
+		}
+
+		if content[markStart] == '@' {
+			e.Type = "mention"
+			e.Text = text
+		}
+
+		entities = append(entities, e)
+	}
+
+	return entities
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,46 @@
+package user
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEntities(t *testing.T) {
+	long := strings.Repeat("a", MaxEntityLength)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []EntityType
+	}{
+		{"empty", "", []EntityType{}},
+		{"no entity", "just text, no tag", []EntityType{}},
+		{"hashtag at start", "#Golang rocks", []EntityType{{Type: "hashtag", Text: "golang", Start: 0, End: 7}}},
+		{"mention keep case", "hi @Alice_01!", []EntityType{{Type: "mention", Text: "Alice_01", Start: 3, End: 12}}},
+		{"both", "@bob likes #go", []EntityType{
+			{Type: "mention", Text: "bob", Start: 0, End: 4},
+			{Type: "hashtag", Text: "go", Start: 11, End: 14},
+		}},
+		{"inside word", "mail me@example.com or a#b", []EntityType{}},
+		{"mark only", "# and @ alone", []EntityType{}},
+		{"unicode tag", "#Café", []EntityType{{Type: "hashtag", Text: "café", Start: 0, End: 5}}},
+		{"utf16 offset after emoji", "😀 #fun", []EntityType{{Type: "hashtag", Text: "fun", Start: 3, End: 7}}},
+		{"tag at max length", "#" + long, []EntityType{{Type: "hashtag", Text: long, Start: 0, End: MaxEntityLength + 1}}},
+		{"tag over max length", "#" + long + "a #ok", []EntityType{{Type: "hashtag", Text: "ok", Start: MaxEntityLength + 3, End: MaxEntityLength + 6}}},
+		{"mention over max length", "@" + long + "b", []EntityType{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []EntityType{}
+			for _, e := range ParseEntities(tt.content) {
+				got = append(got, *e)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEntities(%q)\n got %+v\nwant %+v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,18 @@ package user
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/erlnerlngga/backend-socius/internal/filter"
+	"github.com/erlnerlngga/backend-socius/internal/media"
+	"github.com/erlnerlngga/backend-socius/internal/notification"
 	"github.com/erlnerlngga/backend-socius/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v4"
@@ -12,11 +21,21 @@ import (
 
 type Handler struct {
 	Repository *Repository
+	Timeline   *Timeline
+	Media      *media.Repository
+	Filter     *filter.Pipeline
+	Notifier   *notification.Service
+	Rankers    []Scorer
 }
 
-func NewUserHandler(r *Repository) *Handler {
+func NewUserHandler(r *Repository, m *media.Repository, f *filter.Pipeline, n *notification.Service) *Handler {
 	return &Handler{
 		Repository: r,
+		Timeline:   NewTimeline(r),
+		Media:      m,
+		Filter:     f,
+		Notifier:   n,
+		Rankers:    NewRankers(os.Getenv("FEED_RANKERS")),
 	}
 }
 
@@ -199,14 +218,42 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) error {
 
 	defer r.Body.Close()
 
-	log.Println(userUp)
+	if userUp.User_ID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the account"})
+	}
 
-	err := h.Repository.UpdateUser(userUp)
+	current, err := h.Repository.GetUser(userUp.User_ID)
 	if err != nil {
 		log.Println("2. UpdateUser", err)
 		return err
 	}
 
+	// photo only change through uploaded media, url from client is ignored
+	userUp.Photo_Profile = current.Photo_Profile
+	if userUp.Photo_Media_ID != "" {
+		photo, err := h.Media.GetOwnMedia([]string{userUp.Photo_Media_ID}, userUp.User_ID)
+		if err != nil {
+			log.Println("3. UpdateUser", err)
+			return err
+		}
+
+		userUp.Photo_Profile = photo[0].URL
+	}
+
+	err = h.Repository.UpdateUser(userUp)
+	if err != nil {
+		log.Println("4. UpdateUser", err)
+		return err
+	}
+
+	if userUp.Photo_Media_ID != "" {
+		err = h.Repository.AddAlbumPhoto(userUp.User_ID, userUp.Photo_Profile, userUp.Photo_Media_ID, "profile")
+		if err != nil {
+			log.Println("5. UpdateUser", err)
+			return err
+		}
+	}
+
 	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -222,19 +269,143 @@ func (h *Handler) GetUserbyEmail(w http.ResponseWriter, r *http.Request) error {
 	return util.WriteJSON(w, http.StatusOK, user)
 }
 
-func (h *Handler) AddNewFriend(w http.ResponseWriter, r *http.Request) error {
-	newFr := new(User_FriendType)
+// send friend request, the other user get add_friend notification to answer
+func (h *Handler) SendFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	req := new(FriendRequestType)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Println("1. SendFriendRequest", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	userID := util.GetUserID(r)
+	if req.Friend_ID == userID {
+		return fmt.Errorf("can't add yourself as friend")
+	}
+
+	if _, err := h.Repository.GetUser(req.Friend_ID); err != nil {
+		log.Println("2. SendFriendRequest", err)
+		return err
+	}
+
+	friend, err := h.Repository.IsFriend(userID, req.Friend_ID)
+	if err != nil {
+		log.Println("3. SendFriendRequest", err)
+		return err
+	}
+
+	if friend {
+		return fmt.Errorf("already friend")
+	}
+
+	blocked, err := h.Repository.IsBlocked(userID, req.Friend_ID)
+	if err != nil {
+		log.Println("4. SendFriendRequest", err)
+		return err
+	}
+
+	if blocked {
+		return fmt.Errorf("user not found")
+	}
+
+	pending, err := h.Notifier.Repository.HasPendingRequest(userID, req.Friend_ID)
+	if err != nil {
+		log.Println("5. SendFriendRequest", err)
+		return err
+	}
+
+	if pending {
+		return fmt.Errorf("friend request already sent")
+	}
+
+	err = h.Notifier.Emit(&notification.EventType{
+		Type:     "add_friend",
+		Actor_ID: userID,
+		User_ID:  req.Friend_ID,
+	})
+	if err != nil {
+		log.Println("6. SendFriendRequest", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// answer friend request, only the notifier can and only once.
+// friendship is only made here so it always come from an accepted request.
+func (h *Handler) AnswerFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	answer := new(notification.UpdateNotifType)
 
-	if err := json.NewDecoder(r.Body).Decode(newFr); err != nil {
-		log.Println("1. AddNewFriend", err)
+	if err := json.NewDecoder(r.Body).Decode(answer); err != nil {
+		log.Println("1. AnswerFriendRequest", err)
 		return err
 	}
 
 	defer r.Body.Close()
 
-	err := h.Repository.AddFriend(newFr)
+	if !FriendAnswers[answer.Accept] {
+		return fmt.Errorf("accept must be accept or reject")
+	}
+
+	notif, err := h.Notifier.Repository.GetNotification(answer.Notification_ID)
 	if err != nil {
-		log.Println("2. AddNewFriend", err)
+		log.Println("2. AnswerFriendRequest", err)
+		return err
+	}
+
+	userID := util.GetUserID(r)
+	if notif.Notifier != userID {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the notification"})
+	}
+
+	if notif.Type != "add_friend" {
+		return fmt.Errorf("notification is not a friend request")
+	}
+
+	// old request has no accept and is still pending
+	if notif.Accept != "pending" && notif.Accept != "" {
+		return fmt.Errorf("friend request already answered")
+	}
+
+	if answer.Accept == "accept" {
+		blocked, err := h.Repository.IsBlocked(userID, notif.Issuer)
+		if err != nil {
+			log.Println("3. AnswerFriendRequest", err)
+			return err
+		}
+
+		if blocked {
+			return fmt.Errorf("user not found")
+		}
+
+		// friendship is one row for each side
+		for _, pair := range [][2]string{{userID, notif.Issuer}, {notif.Issuer, userID}} {
+			friend, err := h.Repository.IsFriend(pair[0], pair[1])
+			if err != nil {
+				log.Println("4. AnswerFriendRequest", err)
+				return err
+			}
+
+			if friend {
+				continue
+			}
+
+			if err := h.Repository.AddFriend(&User_FriendType{User_ID: pair[0], Friend_ID: pair[1]}); err != nil {
+				log.Println("5. AnswerFriendRequest", err)
+				return err
+			}
+		}
+
+		if err := h.Timeline.Rebuild(userID, notif.Issuer); err != nil {
+			log.Println("6. AnswerFriendRequest", err)
+			return err
+		}
+	}
+
+	if err := h.Notifier.Repository.UpdateNotif(answer); err != nil {
+		log.Println("7. AnswerFriendRequest", err)
 		return err
 	}
 
@@ -242,11 +413,15 @@ func (h *Handler) AddNewFriend(w http.ResponseWriter, r *http.Request) error {
 }
 
 func (h *Handler) RemoveFriend(w http.ResponseWriter, r *http.Request) error {
-	userFriendId := chi.URLParam(r, "userFriendID")
 	userID := chi.URLParam(r, "userID")
 	friendID := chi.URLParam(r, "friendID")
 
-	err := h.Repository.RemoveFriend(userFriendId)
+	if userID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the friendship"})
+	}
+
+	// both side is removed by user id, user_friend_id in url is not trusted
+	err := h.Repository.RemoveFriendByUserID(userID, friendID)
 	if err != nil {
 		log.Println("1. RemoveFriend", err)
 		return err
@@ -258,19 +433,39 @@ func (h *Handler) RemoveFriend(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	err = h.Timeline.Rebuild(userID, friendID)
+	if err != nil {
+		log.Println("3. RemoveFriend", err)
+		return err
+	}
+
+	// friends only post that was saved is not visible anymore
+	for _, id := range []string{userID, friendID} {
+		if err := h.Repository.PruneBookmark(id); err != nil {
+			log.Println("4. RemoveFriend", err)
+			return err
+		}
+	}
+
 	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
 }
 
 func (h *Handler) GetAllFriend(w http.ResponseWriter, r *http.Request) error {
 	userID := chi.URLParam(r, "userID")
 
-	friends, err := h.Repository.GetAllFriend(userID)
+	page, err := util.GetPageReq(r)
 	if err != nil {
 		log.Println("1. GetAllFriend", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, friends)
+	friends, err := h.Repository.GetAllFriend(userID, page)
+	if err != nil {
+		log.Println("2. GetAllFriend", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(friends, page, friendKey))
 }
 
 func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) error {
@@ -283,81 +478,233 @@ func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) error {
 
 	defer r.Body.Close()
 
-	p := &PostType{
-		User_ID: newPost.User_ID,
-		Content: newPost.Content,
-		Type:    "main",
+	// author is always the caller, user_id in body is ignored
+	newPost.User_ID = util.GetUserID(r)
+
+	_, err := h.PublishPost(newPost, "")
+	if rej := filter.AsRejection(err); rej != nil {
+		return util.WriteJSON(w, http.StatusUnprocessableEntity, filter.RejectedResType{Error: rej.Reason, Rejection: rej})
 	}
 
-	post_ID, err := h.Repository.CreatePost(p)
 	if err != nil {
 		log.Println("2. CreatePost", err)
 		return err
 	}
 
-	if len(newPost.Images) > 0 {
-		for _, val := range newPost.Images {
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// PublishPost create main post and distribute it, used by CreatePost and scheduled draft.
+// post_id can be reserved before, empty post_id generate new one.
+func (h *Handler) PublishPost(newPost *PostReqType, post_id string) (string, error) {
+	if newPost.Audience == "" {
+		author, err := h.Repository.GetUser(newPost.User_ID)
+		if err != nil {
+			log.Println("1. PublishPost", err)
+			return "", err
+		}
+
+		newPost.Audience = author.Default_Audience
+	}
+
+	if !AudienceTypes[newPost.Audience] {
+		return "", fmt.Errorf("audience must be public, friends, only_me or custom")
+	}
+
+	if newPost.Poll != nil {
+		if err := validatePoll(newPost.Poll, time.Now()); err != nil {
+			return "", err
+		}
+	}
+
+	content, err := h.filterContent(newPost.User_ID, "post", newPost.Content, false)
+	if err != nil {
+		return "", err
+	}
+
+	newPost.Content = content
+
+	images, err := h.Media.GetOwnMedia(newPost.Images, newPost.User_ID)
+	if err != nil {
+		log.Println("2. PublishPost", err)
+		return "", err
+	}
+
+	p := &PostType{
+		Post_ID:  post_id,
+		User_ID:  newPost.User_ID,
+		Content:  newPost.Content,
+		Type:     "main",
+		Audience: newPost.Audience,
+	}
+
+	// post and everything of it is saved together, so a retry never find half a post
+	var post_ID string
+	var mentioned []string
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		post_ID, err = tx.CreatePost(p)
+		if err != nil {
+			log.Println("3. PublishPost", err)
+			return err
+		}
+
+		if p.Audience == "custom" {
+			err = tx.CreatePostAudience(post_ID, newPost.Audience_List)
+			if err != nil {
+				log.Println("4. PublishPost", err)
+				return err
+			}
+		}
+
+		for _, val := range images {
 			im := &Image_PostType{
-				Post_ID: post_ID,
-				User_ID: p.User_ID,
-				Image:   val,
+				Post_ID:  post_ID,
+				User_ID:  p.User_ID,
+				Image:    val.URL,
+				Media_ID: val.Media_ID,
 			}
 
-			err := h.Repository.CreateImagePost(im)
+			if err := tx.CreateImagePost(im); err != nil {
+				log.Println("5. PublishPost", err)
+				return err
+			}
+		}
 
+		if newPost.Poll != nil {
+			err = tx.CreatePoll(post_ID, newPost.Poll)
 			if err != nil {
-				log.Println("3. CreatePost", err)
+				log.Println("6. PublishPost", err)
 				return err
 			}
 		}
+
+		mentioned, err = h.storeEntities(tx, post_ID, p.User_ID, p.Content)
+		if err != nil {
+			log.Println("7. PublishPost", err)
+			return err
+		}
+
+		err = h.Timeline.WithRepository(tx).FanOut(p)
+		if err != nil {
+			log.Println("8. PublishPost", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+	// notification is only sent after the post is really there
+	err = h.notifyMention(post_ID, post_ID, p.User_ID, mentioned)
+	if err != nil {
+		log.Println("9. PublishPost", err)
+		return "", err
+	}
+
+	return post_ID, nil
+}
+
+// run content through filter pipeline, return the text to store which can be masked
+func (h *Handler) filterContent(user_id, kind, text string, edit bool) (string, error) {
+	c := &filter.ContentType{
+		User_ID: user_id,
+		Kind:    kind,
+		Text:    text,
+		Edit:    edit,
+	}
+
+	if err := h.Filter.Apply(c); err != nil {
+		return "", err
+	}
+
+	return c.Text, nil
 }
 
+// feed of the caller, query: mode=chronological (default) or ranked
 func (h *Handler) GetAllPost(w http.ResponseWriter, r *http.Request) error {
-	userID := chi.URLParam(r, "userID")
-	var post []*GetPostResType
+	userID := util.GetUserID(r)
+
+	// feed contain friends only and custom post, so only the owner can read it
+	if chi.URLParam(r, "userID") != userID {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the feed"})
+	}
 
-	number, err := h.Repository.CheckFriend(userID)
+	page, err := util.GetPageReq(r)
 	if err != nil {
 		log.Println("1. GetAllPost", err)
 		return err
 	}
 
-	if number > 0 {
-		post, err = h.Repository.GetAllPost(userID)
-		if err != nil {
-			log.Println("2. GetAllPost", err)
-			return err
-		}
-	} else {
-		post, err = h.Repository.GetAllOwnPost(userID)
+	switch r.URL.Query().Get("mode") {
+	case "", "chronological":
+	case "ranked":
+		return h.getRankedPost(w, userID, page)
+	default:
+		return fmt.Errorf("mode must be chronological or ranked")
+	}
+
+	post, err := h.Repository.GetAllPost(userID, page)
+	if err != nil {
+		log.Println("2. GetAllPost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(post, page, postKey))
+}
+
+// ranked feed use offset cursor, the ranking is computed again for every page
+// so post can move a little between page when new engagement come in
+func (h *Handler) getRankedPost(w http.ResponseWriter, userID string, page *util.PageReqType) error {
+	ranker := h.pickRanker(userID)
+
+	ranked, err := h.RankFeed(userID, ranker, time.Now().UTC())
+	if err != nil {
+		log.Println("1. getRankedPost", err)
+		return err
+	}
+
+	post := []*GetPostResType{}
+	offset := page.GetOffset()
+	for i := offset; i < len(ranked) && i <= offset+page.Limit; i++ {
+		p, err := h.Repository.GetDetailPost(ranked[i], userID)
 		if err != nil {
-			log.Println("3. GetAllPost", err)
+			log.Println("2. getRankedPost", err)
 			return err
 		}
+
+		post = append(post, p)
 	}
 
-	return util.WriteJSON(w, http.StatusOK, post)
+	// client log this so the result of each ranker can be compared
+	w.Header().Set("X-Feed-Ranker", ranker.Name())
+
+	return util.WriteJSON(w, http.StatusOK, util.NewOffsetPage(post, page))
 }
 
 func (h *Handler) GetAllOwnPost(w http.ResponseWriter, r *http.Request) error {
 	userID := chi.URLParam(r, "userID")
 
-	post, err := h.Repository.GetAllOwnPost(userID)
+	page, err := util.GetPageReq(r)
 	if err != nil {
 		log.Println("1. GetAllOwnPost", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, post)
+	post, err := h.Repository.GetAllOwnPost(userID, util.GetUserID(r), page)
+	if err != nil {
+		log.Println("2. GetAllOwnPost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(post, page, postKey))
 }
 
 func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) error {
 	postID := chi.URLParam(r, "postID")
 
-	post, err := h.Repository.GetPost(postID)
+	post, err := h.Repository.GetPost(postID, util.GetUserID(r))
 	if err != nil {
 		log.Println("1. GetPost", err)
 		return err
@@ -369,13 +716,19 @@ func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) error {
 func (h *Handler) GetAllImage(w http.ResponseWriter, r *http.Request) error {
 	userID := chi.URLParam(r, "userID")
 
-	images, err := h.Repository.GetAllImage(userID)
+	page, err := util.GetPageReq(r)
 	if err != nil {
 		log.Println("1. GetAllImage", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, images)
+	images, err := h.Repository.GetAllImage(userID, util.GetUserID(r), page)
+	if err != nil {
+		log.Println("2. GetAllImage", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(images, page, imageKey))
 }
 
 func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) error {
@@ -388,132 +741,1895 @@ func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) error {
 
 	defer r.Body.Close()
 
-	p := &PostType{
-		User_ID: newPost.User_ID,
-		Content: newPost.Content,
-		Type:    "child",
-	}
+	// author is always the caller, user_id in body is ignored
+	newPost.User_ID = util.GetUserID(r)
 
-	post_ID, err := h.Repository.CreatePost(p)
+	// parent can be main post or other comment
+	parent, err := h.Repository.GetPost(newPost.Post_ID, newPost.User_ID)
 	if err != nil {
 		log.Println("2. CreateComment", err)
 		return err
 	}
 
-	if len(newPost.Images) > 0 {
-		for _, val := range newPost.Images {
-			im := &Image_PostType{
-				Post_ID: post_ID,
-				User_ID: p.User_ID,
-				Image:   val,
-			}
-
-			err := h.Repository.CreateImagePost(im)
-
-			if err != nil {
-				log.Println("3. CreateComment", err)
-				return err
-			}
+	rootID := parent.Post_ID
+	if parent.Type == "child" {
+		parentComment, err := h.Repository.GetComment(parent.Post_ID)
+		if err != nil {
+			log.Println("3. CreateComment", err)
+			return err
 		}
+
+		rootID = parentComment.Root_Post_ID
 	}
 
-	commen := &CommentType{
-		Post_ID:         newPost.Post_ID,
-		Comment_Post_ID: post_ID,
+	newPost.Content, err = h.filterContent(newPost.User_ID, "comment", newPost.Content, false)
+	if rej := filter.AsRejection(err); rej != nil {
+		return util.WriteJSON(w, http.StatusUnprocessableEntity, filter.RejectedResType{Error: rej.Reason, Rejection: rej})
 	}
 
-	// insert comment
-	err = h.Repository.CreateComment(commen)
 	if err != nil {
 		log.Println("4. CreateComment", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
-}
-
-func (h *Handler) GetAllComment(w http.ResponseWriter, r *http.Request) error {
-	postID := chi.URLParam(r, "postID")
-
-	comment, err := h.Repository.GetAllComment(postID)
+	images, err := h.Media.GetOwnMedia(newPost.Images, newPost.User_ID)
 	if err != nil {
-		log.Println("1. GetAllComment", err)
+		log.Println("5. CreateComment", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, comment)
-}
+	p := &PostType{
+		User_ID: newPost.User_ID,
+		Content: newPost.Content,
+		Type:    "child",
+	}
 
-func (h *Handler) CreateNotification(w http.ResponseWriter, r *http.Request) error {
-	not := new(NotificationType)
+	// comment post, its image, link to the parent and entities are saved together
+	var post_ID string
+	var mentioned []string
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		post_ID, err = tx.CreatePost(p)
+		if err != nil {
+			log.Println("6. CreateComment", err)
+			return err
+		}
 
-	if err := json.NewDecoder(r.Body).Decode(not); err != nil {
-		log.Println("1. CreateNotification", err)
-		return err
-	}
+		for _, val := range images {
+			im := &Image_PostType{
+				Post_ID:  post_ID,
+				User_ID:  p.User_ID,
+				Image:    val.URL,
+				Media_ID: val.Media_ID,
+			}
 
-	defer r.Body.Close()
+			if err := tx.CreateImagePost(im); err != nil {
+				log.Println("7. CreateComment", err)
+				return err
+			}
+		}
 
-	err := h.Repository.CreateNotification(not)
-	if err != nil {
-		log.Println("2. CreateNotification", err)
-		return err
-	}
+		commen := &CommentType{
+			Post_ID:         newPost.Post_ID,
+			Comment_Post_ID: post_ID,
+			Root_Post_ID:    rootID,
+		}
 
-	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
-}
+		// insert comment
+		err = tx.CreateComment(commen)
+		if err != nil {
+			log.Println("8. CreateComment", err)
+			return err
+		}
 
-func (h *Handler) UpdateAddFriendNotification(w http.ResponseWriter, r *http.Request) error {
-	newNotif := new(UpdateNotifType)
+		mentioned, err = h.storeEntities(tx, post_ID, p.User_ID, p.Content)
+		if err != nil {
+			log.Println("9. CreateComment", err)
+			return err
+		}
 
-	if err := json.NewDecoder(r.Body).Decode(newNotif); err != nil {
-		log.Println("1. UpdateAddFriendNotification", err)
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
-	defer r.Body.Close()
-
-	err := h.Repository.UpdateNotif(newNotif)
+	err = h.notifyMention(post_ID, rootID, p.User_ID, mentioned)
 	if err != nil {
-		log.Println("2. UpdateAddFriendNotification", err)
+		log.Println("10. CreateComment", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
-}
-
-func (h *Handler) UpdateNotificationRead(w http.ResponseWriter, r *http.Request) error {
-	userID := chi.URLParam(r, "userID")
+	// notify author of the parent
+	notifType := "comment"
+	if parent.Type == "child" {
+		notifType = "reply"
+	}
 
-	err := h.Repository.UpdatedNotifRead(userID)
+	err = h.Notifier.Emit(&notification.EventType{
+		Type:     notifType,
+		Actor_ID: newPost.User_ID,
+		User_ID:  parent.User_ID,
+		Post_ID:  rootID,
+	})
 	if err != nil {
-		log.Println("1. UpdateNotificationRead", err)
+		log.Println("11. CreateComment", err)
 		return err
 	}
 
 	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-func (h *Handler) GetCountNotification(w http.ResponseWriter, r *http.Request) error {
-	userID := chi.URLParam(r, "userID")
+// get comment thread of post or comment
+// query: sort=newest|oldest|top, depth of reply loaded, reply_limit per comment
+func (h *Handler) GetAllComment(w http.ResponseWriter, r *http.Request) error {
+	postID := chi.URLParam(r, "postID")
 
-	num, err := h.Repository.GetCountNotif(userID)
+	visible, err := h.Repository.CanViewPost(postID, util.GetUserID(r))
 	if err != nil {
-		log.Println("1. GetCountNotification", err)
+		log.Println("1. GetAllComment", err)
+		return err
+	}
+
+	if !visible {
+		return fmt.Errorf("post not found")
+	}
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("2. GetAllComment", err)
+		return err
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = "newest"
+	}
+
+	if sort != "newest" && sort != "oldest" && sort != "top" {
+		return fmt.Errorf("sort must be newest, oldest or top")
+	}
+
+	page.Asc = sort == "oldest"
+
+	depth := 3
+	if d, err := strconv.Atoi(r.URL.Query().Get("depth")); err == nil && d > 0 && d <= 10 {
+		depth = d
+	}
+
+	replyLimit := 3
+	if l, err := strconv.Atoi(r.URL.Query().Get("reply_limit")); err == nil && l > 0 && l <= 20 {
+		replyLimit = l
+	}
+
+	comment, err := h.Repository.GetAllComment(postID, util.GetUserID(r), sort, page)
+	if err != nil {
+		log.Println("3. GetAllComment", err)
+		return err
+	}
+
+	var res *util.PageResType
+	if sort == "top" {
+		res = util.NewScorePage(comment, page, commentScoreKey)
+	} else {
+		res = util.NewPage(comment, page, commentKey)
+	}
+
+	// load reply only for comment that stay in the page
+	if err := h.Repository.GetCommentThread(res.Data.([]*CommentThreadType), util.GetUserID(r), sort, 1, depth, replyLimit); err != nil {
+		log.Println("4. GetAllComment", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) GetFriendSuggestion(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	if userID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the suggestion"})
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	suggestions, err := h.Repository.GetFriendSuggestion(userID, limit)
+	if err != nil {
+		log.Println("1. GetFriendSuggestion", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, suggestions)
+}
+
+func (h *Handler) DismissSuggestion(w http.ResponseWriter, r *http.Request) error {
+	dismiss := new(DismissSuggestionType)
+
+	if err := json.NewDecoder(r.Body).Decode(dismiss); err != nil {
+		log.Println("1. DismissSuggestion", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	// user_id in body is ignored, only the caller can dismiss
+	dismiss.User_ID = util.GetUserID(r)
+
+	err := h.Repository.DismissSuggestion(dismiss)
+	if err != nil {
+		log.Println("2. DismissSuggestion", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) GetMutualFriend(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+	otherID := chi.URLParam(r, "otherID")
+
+	// only mutual friend between the caller and someone else
+	if userID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the friend list"})
+	}
+
+	blocked, err := h.Repository.IsBlocked(userID, otherID)
+	if err != nil {
+		log.Println("1. GetMutualFriend", err)
+		return err
+	}
+
+	if blocked {
+		return fmt.Errorf("user not found")
+	}
+
+	friends, err := h.Repository.GetMutualFriend(userID, otherID)
+	if err != nil {
+		log.Println("2. GetMutualFriend", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, friends)
+}
+
+func (h *Handler) BlockUser(w http.ResponseWriter, r *http.Request) error {
+	block := new(User_BlockType)
+
+	if err := json.NewDecoder(r.Body).Decode(block); err != nil {
+		log.Println("1. BlockUser", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	// user_id in body is ignored, only the caller can block
+	block.User_ID = util.GetUserID(r)
+	if block.Blocked_ID == block.User_ID {
+		return fmt.Errorf("can't block yourself")
+	}
+
+	err := h.Repository.BlockUser(block)
+	if err != nil {
+		log.Println("2. BlockUser", err)
+		return err
+	}
+
+	// blocking someone also ends the friendship and follow in both direction
+	err = h.Repository.RemoveFriendByUserID(block.User_ID, block.Blocked_ID)
+	if err != nil {
+		log.Println("3. BlockUser", err)
+		return err
+	}
+
+	err = h.Repository.RemoveFriendByUserID(block.Blocked_ID, block.User_ID)
+	if err != nil {
+		log.Println("4. BlockUser", err)
+		return err
+	}
+
+	err = h.Repository.Unfollow(block.User_ID, block.Blocked_ID)
+	if err != nil {
+		log.Println("5. BlockUser", err)
+		return err
+	}
+
+	err = h.Repository.Unfollow(block.Blocked_ID, block.User_ID)
+	if err != nil {
+		log.Println("6. BlockUser", err)
+		return err
+	}
+
+	err = h.Timeline.Rebuild(block.User_ID, block.Blocked_ID)
+	if err != nil {
+		log.Println("7. BlockUser", err)
+		return err
+	}
+
+	// saved post of each other is not visible anymore
+	for _, id := range []string{block.User_ID, block.Blocked_ID} {
+		if err := h.Repository.PruneBookmark(id); err != nil {
+			log.Println("8. BlockUser", err)
+			return err
+		}
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) UnblockUser(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+	blockedID := chi.URLParam(r, "blockedID")
+
+	if userID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the block"})
+	}
+
+	err := h.Repository.UnblockUser(userID, blockedID)
+	if err != nil {
+		log.Println("1. UnblockUser", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+func (h *Handler) Follow(w http.ResponseWriter, r *http.Request) error {
+	follow := new(User_FollowType)
+
+	if err := json.NewDecoder(r.Body).Decode(follow); err != nil {
+		log.Println("1. Follow", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	// follower_id in body is ignored, only the caller can follow
+	follow.Follower_ID = util.GetUserID(r)
+	if follow.Follower_ID == follow.Following_ID {
+		return fmt.Errorf("can't follow yourself")
+	}
+
+	blocked, err := h.Repository.IsBlocked(follow.Follower_ID, follow.Following_ID)
+	if err != nil {
+		log.Println("2. Follow", err)
+		return err
+	}
+
+	if blocked {
+		return fmt.Errorf("user not found")
+	}
+
+	err = h.Repository.Follow(follow)
+	if err != nil {
+		log.Println("3. Follow", err)
+		return err
+	}
+
+	err = h.Timeline.Rebuild(follow.Follower_ID, follow.Following_ID)
+	if err != nil {
+		log.Println("4. Follow", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) Unfollow(w http.ResponseWriter, r *http.Request) error {
+	followerID := chi.URLParam(r, "followerID")
+	followingID := chi.URLParam(r, "followingID")
+
+	if followerID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the follow"})
+	}
+
+	err := h.Repository.Unfollow(followerID, followingID)
+	if err != nil {
+		log.Println("1. Unfollow", err)
+		return err
+	}
+
+	err = h.Timeline.Rebuild(followerID, followingID)
+	if err != nil {
+		log.Println("2. Unfollow", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+func (h *Handler) GetAllFollower(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllFollower", err)
+		return err
+	}
+
+	users, err := h.Repository.GetAllFollower(userID, page)
+	if err != nil {
+		log.Println("2. GetAllFollower", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(users, page, followKey))
+}
+
+func (h *Handler) GetAllFollowing(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllFollowing", err)
+		return err
+	}
+
+	users, err := h.Repository.GetAllFollowing(userID, page)
+	if err != nil {
+		log.Println("2. GetAllFollowing", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(users, page, followKey))
+}
+
+func (h *Handler) GetFollowCount(w http.ResponseWriter, r *http.Request) error {
+	userID := chi.URLParam(r, "userID")
+
+	count, err := h.Repository.GetFollowCount(userID)
+	if err != nil {
+		log.Println("1. GetFollowCount", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, count)
+}
+
+// cursor key for post and comment list
+func postKey(p *GetPostResType) (time.Time, string) {
+	return p.Created_At, p.Post_ID
+}
+
+// cursor key for comment list
+func commentKey(c *CommentThreadType) (time.Time, string) {
+	return c.Created_At, c.Post_ID
+}
+
+// cursor key for comment list sorted by top
+func commentScoreKey(c *CommentThreadType) (int, string) {
+	return c.Score, c.Post_ID
+}
+
+// cursor key for friend list
+func friendKey(f *UserFriendType) (time.Time, string) {
+	return f.Created_At, f.User_Friend_ID
+}
+
+// cursor key for follower and following list
+func followKey(f *FollowUserType) (time.Time, string) {
+	return f.Created_At, f.User_Follow_ID
+}
+
+// cursor key for image list
+func imageKey(i *Image_PostType) (time.Time, string) {
+	return i.Created_At, i.Image_Post_ID
+}
+
+// edit content and image set of post or comment, prior revision kept in history
+func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) error {
+	upPost := new(UpdatePostReqType)
+
+	if err := json.NewDecoder(r.Body).Decode(upPost); err != nil {
+		log.Println("1. UpdatePost", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	post, err := h.Repository.GetPost(upPost.Post_ID, util.GetUserID(r))
+	if err != nil {
+		log.Println("2. UpdatePost", err)
+		return err
+	}
+
+	if post.User_ID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the post"})
+	}
+
+	if post.Type == "repost" {
+		return fmt.Errorf("repost can't be edited")
+	}
+
+	kind := "post"
+	if post.Type == "child" {
+		kind = "comment"
+	}
+
+	upPost.Content, err = h.filterContent(post.User_ID, kind, upPost.Content, true)
+	if rej := filter.AsRejection(err); rej != nil {
+		return util.WriteJSON(w, http.StatusUnprocessableEntity, filter.RejectedResType{Error: rej.Reason, Rejection: rej})
+	}
+
+	if err != nil {
+		log.Println("3. UpdatePost", err)
+		return err
+	}
+
+	images, err := h.Media.GetOwnMedia(upPost.Images, post.User_ID)
+	if err != nil {
+		log.Println("4. UpdatePost", err)
+		return err
+	}
+
+	rootID := post.Post_ID
+	if post.Type == "child" {
+		comment, err := h.Repository.GetComment(post.Post_ID)
+		if err != nil {
+			log.Println("5. UpdatePost", err)
+			return err
+		}
+
+		rootID = comment.Root_Post_ID
+	}
+
+	// history, content, image and entities change together or not at all
+	var mentioned []string
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		err := tx.CreatePostHistory(post)
+		if err != nil {
+			log.Println("6. UpdatePost", err)
+			return err
+		}
+
+		err = tx.UpdatePost(post.Post_ID, upPost.Content)
+		if err != nil {
+			log.Println("7. UpdatePost", err)
+			return err
+		}
+
+		err = tx.RemoveImagePost(post.Post_ID)
+		if err != nil {
+			log.Println("8. UpdatePost", err)
+			return err
+		}
+
+		for _, val := range images {
+			im := &Image_PostType{
+				Post_ID:  post.Post_ID,
+				User_ID:  post.User_ID,
+				Image:    val.URL,
+				Media_ID: val.Media_ID,
+			}
+
+			if err := tx.CreateImagePost(im); err != nil {
+				log.Println("9. UpdatePost", err)
+				return err
+			}
+		}
+
+		// image that is dropped from the post leave the album
+		err = tx.PruneAlbumPhoto(post.User_ID)
+		if err != nil {
+			log.Println("10. UpdatePost", err)
+			return err
+		}
+
+		mentioned, err = h.storeEntities(tx, post.Post_ID, post.User_ID, upPost.Content)
+		if err != nil {
+			log.Println("11. UpdatePost", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = h.notifyMention(post.Post_ID, rootID, post.User_ID, mentioned)
+	if err != nil {
+		log.Println("12. UpdatePost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) error {
+	postID := chi.URLParam(r, "postID")
+
+	post, err := h.Repository.GetPost(postID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. DeletePost", err)
+		return err
+	}
+
+	if post.User_ID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the post"})
+	}
+
+	// whole thread go in one transaction so failed delete can be retried
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		if err := tx.DeletePost(postID); err != nil {
+			log.Println("2. DeletePost", err)
+			return err
+		}
+
+		if err := tx.PruneAlbumPhoto(post.User_ID); err != nil {
+			log.Println("3. DeletePost", err)
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+func (h *Handler) DeleteImage(w http.ResponseWriter, r *http.Request) error {
+	imagePostID := chi.URLParam(r, "imagePostID")
+
+	image, err := h.Repository.GetImage(imagePostID)
+	if err != nil {
+		log.Println("1. DeleteImage", err)
+		return err
+	}
+
+	if image.User_ID != util.GetUserID(r) {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not owner of the image"})
+	}
+
+	post, err := h.Repository.GetPost(image.Post_ID, util.GetUserID(r))
+	if err != nil {
+		log.Println("2. DeleteImage", err)
+		return err
+	}
+
+	err = h.Repository.CreatePostHistory(post)
+	if err != nil {
+		log.Println("3. DeleteImage", err)
+		return err
+	}
+
+	err = h.Repository.RemoveImage(imagePostID)
+	if err != nil {
+		log.Println("4. DeleteImage", err)
+		return err
+	}
+
+	err = h.Repository.PruneAlbumPhoto(image.User_ID)
+	if err != nil {
+		log.Println("5. DeleteImage", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+func (h *Handler) GetPostHistory(w http.ResponseWriter, r *http.Request) error {
+	postID := chi.URLParam(r, "postID")
+
+	// old content follow the audience of the post now
+	visible, err := h.Repository.CanViewPost(postID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. GetPostHistory", err)
+		return err
+	}
+
+	if !visible {
+		return fmt.Errorf("post not found")
+	}
+
+	histories, err := h.Repository.GetPostHistory(postID)
+	if err != nil {
+		log.Println("2. GetPostHistory", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, histories)
+}
+
+// give reaction to post or comment, same reaction again remove it and other reaction replace it
+func (h *Handler) ToggleReaction(w http.ResponseWriter, r *http.Request) error {
+	reaction := new(ReactionType)
+
+	if err := json.NewDecoder(r.Body).Decode(reaction); err != nil {
+		log.Println("1. ToggleReaction", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	if !ReactionTypes[reaction.Type] {
+		return fmt.Errorf("reaction type isn't supported")
+	}
+
+	reaction.User_ID = util.GetUserID(r)
+
+	post, err := h.Repository.GetPost(reaction.Post_ID, reaction.User_ID)
+	if err != nil {
+		log.Println("2. ToggleReaction", err)
+		return err
+	}
+
+	old, err := h.Repository.GetReaction(reaction.Post_ID, reaction.User_ID)
+	if err != nil {
+		log.Println("3. ToggleReaction", err)
+		return err
+	}
+
+	if old != nil && old.Type == reaction.Type {
+		err = h.Repository.RemoveReaction(old.Reaction_ID)
+		if err != nil {
+			log.Println("4. ToggleReaction", err)
+			return err
+		}
+
+		return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+	}
+
+	if old != nil {
+		old.Type = reaction.Type
+
+		err = h.Repository.UpdateReaction(old)
+		if err != nil {
+			log.Println("5. ToggleReaction", err)
+			return err
+		}
+
+		return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+	}
+
+	err = h.Repository.CreateReaction(reaction)
+	if err != nil {
+		log.Println("6. ToggleReaction", err)
+		return err
+	}
+
+	// notify author of the post
+	err = h.Notifier.Emit(&notification.EventType{
+		Type:     "reaction",
+		Actor_ID: reaction.User_ID,
+		User_ID:  post.User_ID,
+		Post_ID:  post.Post_ID,
+	})
+	if err != nil {
+		log.Println("7. ToggleReaction", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "added"})
+}
+
+func (h *Handler) GetAllReaction(w http.ResponseWriter, r *http.Request) error {
+	postID := chi.URLParam(r, "postID")
+
+	// who reacted follow the audience of the post
+	visible, err := h.Repository.CanViewPost(postID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. GetAllReaction", err)
+		return err
+	}
+
+	if !visible {
+		return fmt.Errorf("post not found")
+	}
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("2. GetAllReaction", err)
+		return err
+	}
+
+	users, err := h.Repository.GetAllReaction(postID, r.URL.Query().Get("type"), page)
+	if err != nil {
+		log.Println("3. GetAllReaction", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(users, page, reactionKey))
+}
+
+// cursor key for reaction list
+func reactionKey(u *ReactionUserType) (time.Time, string) {
+	return u.Created_At, u.Reaction_ID
+}
+
+func (h *Handler) UpdateDefaultAudience(w http.ResponseWriter, r *http.Request) error {
+	audience := new(DefaultAudienceReqType)
+
+	if err := json.NewDecoder(r.Body).Decode(audience); err != nil {
+		log.Println("1. UpdateDefaultAudience", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	// custom list is chosen per post, so it can't be the default
+	if !AudienceTypes[audience.Default_Audience] || audience.Default_Audience == "custom" {
+		return fmt.Errorf("default audience must be public, friends or only_me")
+	}
+
+	err := h.Repository.UpdateDefaultAudience(util.GetUserID(r), audience.Default_Audience)
+	if err != nil {
+		log.Println("2. UpdateDefaultAudience", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// save hashtag and mention of post, return user that is newly mentioned
+func (h *Handler) storeEntities(repo *Repository, post_id, author_id, content string) ([]string, error) {
+	oldMention, err := repo.GetMentionedUserID(post_id)
+	if err != nil {
+		log.Println("1. storeEntities", err)
+		return nil, err
+	}
+
+	entities := []*EntityType{}
+	for _, e := range ParseEntities(content) {
+		if e.Type == "mention" {
+			u, err := repo.GetMentionUser(author_id, e.Text)
+			if err != nil {
+				log.Println("2. storeEntities", err)
+				return nil, err
+			}
+
+			// unknown name stay as plain text
+			if u == nil {
+				continue
+			}
+
+			e.User_ID = u.User_ID
+		}
+
+		entities = append(entities, e)
+	}
+
+	err = repo.SaveEntities(post_id, entities)
+	if err != nil {
+		log.Println("3. storeEntities", err)
+		return nil, err
+	}
+
+	mentioned := []string{}
+	seen := map[string]bool{}
+	for _, e := range entities {
+		if e.Type != "mention" || e.User_ID == author_id || oldMention[e.User_ID] || seen[e.User_ID] {
+			continue
+		}
+
+		seen[e.User_ID] = true
+		mentioned = append(mentioned, e.User_ID)
+	}
+
+	return mentioned, nil
+}
+
+// notify mentioned user that can see the post
+func (h *Handler) notifyMention(post_id, root_id, author_id string, mentioned []string) error {
+	for _, user_id := range mentioned {
+		visible, err := h.Repository.CanViewPost(post_id, user_id)
+		if err != nil {
+			log.Println("1. notifyMention", err)
+			return err
+		}
+
+		if !visible {
+			continue
+		}
+
+		err = h.Notifier.Emit(&notification.EventType{
+			Type:     "mention",
+			Actor_ID: author_id,
+			User_ID:  user_id,
+			Post_ID:  root_id,
+		})
+		if err != nil {
+			log.Println("2. notifyMention", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) GetAllPostByTag(w http.ResponseWriter, r *http.Request) error {
+	tag := strings.ToLower(strings.TrimPrefix(chi.URLParam(r, "tag"), "#"))
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllPostByTag", err)
+		return err
+	}
+
+	post, err := h.Repository.GetAllPostByTag(tag, util.GetUserID(r), page)
+	if err != nil {
+		log.Println("2. GetAllPostByTag", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(post, page, postKey))
+}
+
+// repost or quote post, the original must be visible to the caller
+func (h *Handler) Repost(w http.ResponseWriter, r *http.Request) error {
+	repost := new(RepostReqType)
+
+	if err := json.NewDecoder(r.Body).Decode(repost); err != nil {
+		log.Println("1. Repost", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	userID := util.GetUserID(r)
+
+	original, err := h.Repository.GetPost(repost.Post_ID, userID)
+	if err != nil {
+		log.Println("2. Repost", err)
+		return err
+	}
+
+	// repost of repost share the original instead
+	if original.Type == "repost" {
+		original, err = h.Repository.GetPost(original.Original_Post_ID, userID)
+		if err != nil {
+			log.Println("3. Repost", err)
+			return err
+		}
+	}
+
+	if original.Type == "child" {
+		return fmt.Errorf("comment can't be reposted")
+	}
+
+	postType := "quote"
+	if strings.TrimSpace(repost.Content) == "" {
+		postType = "repost"
+
+		existing, err := h.Repository.GetRepost(original.Post_ID, userID)
+		if err != nil {
+			log.Println("4. Repost", err)
+			return err
+		}
+
+		if existing != "" {
+			return fmt.Errorf("post already reposted")
+		}
+	}
+
+	author, err := h.Repository.GetUser(userID)
+	if err != nil {
+		log.Println("5. Repost", err)
+		return err
+	}
+
+	if repost.Audience == "" {
+		repost.Audience = author.Default_Audience
+	}
+
+	if !AudienceTypes[repost.Audience] {
+		return fmt.Errorf("audience must be public, friends, only_me or custom")
+	}
+
+	repost.Content, err = h.filterContent(userID, "post", repost.Content, false)
+	if rej := filter.AsRejection(err); rej != nil {
+		return util.WriteJSON(w, http.StatusUnprocessableEntity, filter.RejectedResType{Error: rej.Reason, Rejection: rej})
+	}
+
+	if err != nil {
+		log.Println("6. Repost", err)
+		return err
+	}
+
+	p := &PostType{
+		User_ID:          userID,
+		Content:          repost.Content,
+		Type:             postType,
+		Audience:         repost.Audience,
+		Original_Post_ID: original.Post_ID,
+	}
+
+	// repost and everything of it is saved together
+	var post_ID string
+	var mentioned []string
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		post_ID, err = tx.CreatePost(p)
+		if err != nil {
+			log.Println("7. Repost", err)
+			return err
+		}
+
+		if p.Audience == "custom" {
+			err = tx.CreatePostAudience(post_ID, repost.Audience_List)
+			if err != nil {
+				log.Println("8. Repost", err)
+				return err
+			}
+		}
+
+		if postType == "quote" {
+			mentioned, err = h.storeEntities(tx, post_ID, userID, p.Content)
+			if err != nil {
+				log.Println("9. Repost", err)
+				return err
+			}
+		}
+
+		err = h.Timeline.WithRepository(tx).FanOut(p)
+		if err != nil {
+			log.Println("10. Repost", err)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = h.notifyMention(post_ID, post_ID, userID, mentioned)
+	if err != nil {
+		log.Println("11. Repost", err)
+		return err
+	}
+
+	// notify author of the original
+	notifPost := original.Post_ID
+	if postType == "quote" {
+		notifPost = post_ID
+	}
+
+	err = h.Notifier.Emit(&notification.EventType{
+		Type:     postType,
+		Actor_ID: userID,
+		User_ID:  original.User_ID,
+		Post_ID:  notifPost,
+	})
+	if err != nil {
+		log.Println("12. Repost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success", "post_id": post_ID})
+}
+
+// remove plain repost of post by the caller
+func (h *Handler) UndoRepost(w http.ResponseWriter, r *http.Request) error {
+	postID := chi.URLParam(r, "postID")
+
+	repostID, err := h.Repository.GetRepost(postID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. UndoRepost", err)
+		return err
+	}
+
+	if repostID == "" {
+		return fmt.Errorf("post not reposted")
+	}
+
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		return tx.DeletePost(repostID)
+	})
+
+	if err != nil {
+		log.Println("2. UndoRepost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+func (h *Handler) CreateCollection(w http.ResponseWriter, r *http.Request) error {
+	collection := new(CollectionType)
+
+	if err := json.NewDecoder(r.Body).Decode(collection); err != nil {
+		log.Println("1. CreateCollection", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	collection.Name = strings.TrimSpace(collection.Name)
+	if collection.Name == "" {
+		return fmt.Errorf("collection name can't be empty")
+	}
+
+	collection.User_ID = util.GetUserID(r)
+
+	err := h.Repository.CreateCollection(collection)
+	if err != nil {
+		log.Println("2. CreateCollection", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, collection)
+}
+
+// collection is private, only the caller own are listed
+func (h *Handler) GetAllCollection(w http.ResponseWriter, r *http.Request) error {
+	collections, err := h.Repository.GetAllCollection(util.GetUserID(r))
+	if err != nil {
+		log.Println("1. GetAllCollection", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, collections)
+}
+
+func (h *Handler) RenameCollection(w http.ResponseWriter, r *http.Request) error {
+	collection := new(CollectionType)
+
+	if err := json.NewDecoder(r.Body).Decode(collection); err != nil {
+		log.Println("1. RenameCollection", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	collection.Name = strings.TrimSpace(collection.Name)
+	if collection.Name == "" {
+		return fmt.Errorf("collection name can't be empty")
+	}
+
+	_, err := h.Repository.GetCollection(collection.Collection_ID, util.GetUserID(r))
+	if err != nil {
+		log.Println("2. RenameCollection", err)
+		return err
+	}
+
+	err = h.Repository.RenameCollection(collection.Collection_ID, util.GetUserID(r), collection.Name)
+	if err != nil {
+		log.Println("3. RenameCollection", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) DeleteCollection(w http.ResponseWriter, r *http.Request) error {
+	collectionID := chi.URLParam(r, "collectionID")
+
+	err := h.Repository.DeleteCollection(collectionID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. DeleteCollection", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// save post the caller can see, optionally into one of their collection
+func (h *Handler) SavePost(w http.ResponseWriter, r *http.Request) error {
+	bookmark := new(BookmarkType)
+
+	if err := json.NewDecoder(r.Body).Decode(bookmark); err != nil {
+		log.Println("1. SavePost", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	bookmark.User_ID = util.GetUserID(r)
+
+	post, err := h.Repository.GetPost(bookmark.Post_ID, bookmark.User_ID)
+	if err != nil {
+		log.Println("2. SavePost", err)
+		return err
+	}
+
+	if post.Type == "child" {
+		return fmt.Errorf("comment can't be saved")
+	}
+
+	if bookmark.Collection_ID != "" {
+		_, err := h.Repository.GetCollection(bookmark.Collection_ID, bookmark.User_ID)
+		if err != nil {
+			log.Println("3. SavePost", err)
+			return err
+		}
+	}
+
+	err = h.Repository.SavePost(bookmark)
+	if err != nil {
+		log.Println("4. SavePost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+func (h *Handler) UnsavePost(w http.ResponseWriter, r *http.Request) error {
+	postID := chi.URLParam(r, "postID")
+
+	err := h.Repository.UnsavePost(postID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. UnsavePost", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// get saved post of the caller, query: collection_id to list one collection, empty value list post without collection
+func (h *Handler) GetAllSaved(w http.ResponseWriter, r *http.Request) error {
+	userID := util.GetUserID(r)
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllSaved", err)
+		return err
+	}
+
+	collectionID, filtered := r.URL.Query()["collection_id"]
+	if filtered && collectionID[0] != "" {
+		_, err := h.Repository.GetCollection(collectionID[0], userID)
+		if err != nil {
+			log.Println("2. GetAllSaved", err)
+			return err
+		}
+	}
+
+	collection := ""
+	if filtered {
+		collection = collectionID[0]
+	}
+
+	saved, err := h.Repository.GetAllSaved(userID, collection, !filtered, page)
+	if err != nil {
+		log.Println("3. GetAllSaved", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(saved, page, savedKey))
+}
+
+func savedKey(s *SavedPostType) (time.Time, string) {
+	return s.Saved_At, s.Bookmark_ID
+}
+
+// publish claimed draft with its reserved post_id, the post is only created once
+// even when publishing is retried after crash
+func (h *Handler) publishDraft(d *DraftType) error {
+	// post is saved in one transaction with everything of it, so when it exists the last try
+	// got that far and only marking the draft is left
+	exists, err := h.Repository.PostExists(d.Post_ID)
+	if err != nil {
+		log.Println("1. publishDraft", err)
+		return err
+	}
+
+	if !exists {
+		newPost := &PostReqType{
+			User_ID:       d.User_ID,
+			Content:       d.Content,
+			Images:        d.Images,
+			Audience:      d.Audience,
+			Audience_List: d.Audience_List,
+			Poll:          d.Poll,
+		}
+
+		if _, err := h.PublishPost(newPost, d.Post_ID); err != nil {
+			log.Println("2. publishDraft", err)
+
+			message := err.Error()
+			if len(message) > 300 {
+				message = message[:300]
+			}
+
+			// draft that was published by hand go back to draft, scheduled one show as failed
+			status := "draft"
+			if d.Publish_At != nil {
+				status = "failed"
+			}
+
+			if err := h.Repository.FinishDraft(d.Draft_ID, status, message); err != nil {
+				log.Println("3. publishDraft", err)
+			}
+
+			return err
+		}
+	}
+
+	err = h.Repository.FinishDraft(d.Draft_ID, "published", "")
+	if err != nil {
+		log.Println("4. publishDraft", err)
+		return err
+	}
+
+	return nil
+}
+
+// check draft before it is saved, empty audience use default audience on publish
+func (h *Handler) validateDraft(d *DraftType) error {
+	if d.Audience != "" && !AudienceTypes[d.Audience] {
+		return fmt.Errorf("audience must be public, friends, only_me or custom")
+	}
+
+	if _, err := h.Media.GetOwnMedia(d.Images, d.User_ID); err != nil {
+		log.Println("1. validateDraft", err)
+		return err
+	}
+
+	d.Status = "draft"
+	if d.Publish_At != nil {
+		if !d.Publish_At.After(time.Now()) {
+			return fmt.Errorf("publish_at must be in the future")
+		}
+
+		utc := d.Publish_At.UTC()
+		d.Publish_At = &utc
+		d.Status = "scheduled"
+	}
+
+	// poll must still be open when the draft is published
+	if d.Poll != nil {
+		from := time.Now()
+		if d.Publish_At != nil {
+			from = *d.Publish_At
+		}
+
+		if err := validatePoll(d.Poll, from); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// create draft, with publish_at it is scheduled
+func (h *Handler) CreateDraft(w http.ResponseWriter, r *http.Request) error {
+	draft := new(DraftType)
+
+	if err := json.NewDecoder(r.Body).Decode(draft); err != nil {
+		log.Println("1. CreateDraft", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	draft.User_ID = util.GetUserID(r)
+
+	if err := h.validateDraft(draft); err != nil {
+		log.Println("2. CreateDraft", err)
+		return err
+	}
+
+	err := h.Repository.CreateDraft(draft)
+	if err != nil {
+		log.Println("3. CreateDraft", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, draft)
+}
+
+// edit draft or scheduled post, publish_at empty turn it back to draft
+func (h *Handler) UpdateDraft(w http.ResponseWriter, r *http.Request) error {
+	draft := new(DraftType)
+
+	if err := json.NewDecoder(r.Body).Decode(draft); err != nil {
+		log.Println("1. UpdateDraft", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	draft.User_ID = util.GetUserID(r)
+
+	if err := h.validateDraft(draft); err != nil {
+		log.Println("2. UpdateDraft", err)
+		return err
+	}
+
+	err := h.Repository.UpdateDraft(draft)
+	if err != nil {
+		log.Println("3. UpdateDraft", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) DeleteDraft(w http.ResponseWriter, r *http.Request) error {
+	draftID := chi.URLParam(r, "draftID")
+
+	err := h.Repository.DeleteDraft(draftID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. DeleteDraft", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// publish draft or scheduled post now
+func (h *Handler) PublishDraft(w http.ResponseWriter, r *http.Request) error {
+	draftID := chi.URLParam(r, "draftID")
+	userID := util.GetUserID(r)
+
+	draft, err := h.Repository.GetDraft(draftID, userID)
+	if err != nil {
+		log.Println("1. PublishDraft", err)
+		return err
+	}
+
+	claimed, err := h.Repository.ClaimDraft(draftID, userID, "request")
+	if err != nil {
+		log.Println("2. PublishDraft", err)
+		return err
+	}
+
+	if !claimed {
+		return fmt.Errorf("draft is already published")
+	}
+
+	err = h.publishDraft(draft)
+	if err != nil {
+		log.Println("3. PublishDraft", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success", "post_id": draft.Post_ID})
+}
+
+// cancel scheduled post, it stay as draft
+func (h *Handler) CancelScheduled(w http.ResponseWriter, r *http.Request) error {
+	draftID := chi.URLParam(r, "draftID")
+
+	err := h.Repository.CancelScheduled(draftID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. CancelScheduled", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// get draft of the caller, newest first
+func (h *Handler) GetAllDraft(w http.ResponseWriter, r *http.Request) error {
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllDraft", err)
+		return err
+	}
+
+	drafts, err := h.Repository.GetAllDraft(util.GetUserID(r), []string{"draft"}, "created_at", page)
+	if err != nil {
+		log.Println("2. GetAllDraft", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(drafts, page, draftKey))
+}
+
+// get scheduled post of the caller, the next to publish first. failed one stay here with its error
+func (h *Handler) GetAllScheduled(w http.ResponseWriter, r *http.Request) error {
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllScheduled", err)
+		return err
+	}
+
+	page.Asc = true
+
+	drafts, err := h.Repository.GetAllDraft(util.GetUserID(r), []string{"scheduled", "failed"}, "publish_at", page)
+	if err != nil {
+		log.Println("2. GetAllScheduled", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(drafts, page, scheduledKey))
+}
+
+func draftKey(d *DraftType) (time.Time, string) {
+	return d.Created_At, d.Draft_ID
+}
+
+func scheduledKey(d *DraftType) (time.Time, string) {
+	return *d.Publish_At, d.Draft_ID
+}
+
+// check option count and close time of poll, close time must be after from
+func validatePoll(poll *PollReqType, from time.Time) error {
+	if len(poll.Options) < MinPollOption || len(poll.Options) > MaxPollOption {
+		return fmt.Errorf("poll must have %d to %d options", MinPollOption, MaxPollOption)
+	}
+
+	for i, text := range poll.Options {
+		text = strings.TrimSpace(text)
+		if text == "" || utf8.RuneCountInString(text) > 200 {
+			return fmt.Errorf("poll option must be 1 to 200 characters")
+		}
+
+		poll.Options[i] = text
+	}
+
+	if poll.Closes_At != nil {
+		if !poll.Closes_At.After(from) {
+			return fmt.Errorf("poll closes_at must be after the post is published")
+		}
+
+		utc := poll.Closes_At.UTC()
+		poll.Closes_At = &utc
+	}
+
+	return nil
+}
+
+// vote on poll, single choice poll take exactly one option
+func (h *Handler) VotePoll(w http.ResponseWriter, r *http.Request) error {
+	vote := new(VoteReqType)
+
+	if err := json.NewDecoder(r.Body).Decode(vote); err != nil {
+		log.Println("1. VotePoll", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	userID := util.GetUserID(r)
+
+	post, err := h.Repository.GetPost(vote.Post_ID, userID)
+	if err != nil {
+		log.Println("2. VotePoll", err)
+		return err
+	}
+
+	poll := post.Poll
+	if poll == nil {
+		return fmt.Errorf("post has no poll")
+	}
+
+	if poll.Closed {
+		return fmt.Errorf("poll is closed")
+	}
+
+	if len(vote.Option_IDs) == 0 || (!poll.Multiple && len(vote.Option_IDs) > 1) {
+		return fmt.Errorf("choose one option")
+	}
+
+	if len(poll.My_Votes) > 0 {
+		return fmt.Errorf("already voted")
+	}
+
+	options := map[string]bool{}
+	for _, o := range poll.Options {
+		options[o.Option_ID] = true
+	}
+
+	for _, id := range vote.Option_IDs {
+		if !options[id] {
+			return fmt.Errorf("option not found")
+		}
+	}
+
+	// ballot is taken first, vote that came in at the same time fail on its key and roll back
+	errVoted := fmt.Errorf("already voted")
+	err = h.Repository.WithTx(func(tx *Repository) error {
+		ok, err := tx.CreateBallot(post.Post_ID, userID)
+		if err != nil {
+			log.Println("3. VotePoll", err)
+			return err
+		}
+
+		if !ok {
+			return errVoted
+		}
+
+		for _, id := range vote.Option_IDs {
+			slot := ""
+			if poll.Multiple {
+				slot = id
+			}
+
+			if _, err := tx.CreateVote(post.Post_ID, id, userID, slot); err != nil {
+				log.Println("4. VotePoll", err)
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	post, err = h.Repository.GetPollPost(post, userID)
+	if err != nil {
+		log.Println("5. VotePoll", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, post.Poll)
+}
+
+// notify author of poll that just closed
+func (h *Handler) notifyClosedPoll(post *PostType) error {
+	err := h.Notifier.Emit(&notification.EventType{
+		Type:     "poll_closed",
+		Actor_ID: post.User_ID,
+		User_ID:  post.User_ID,
+		Post_ID:  post.Post_ID,
+	})
+	if err != nil {
+		log.Println("1. notifyClosedPoll", err)
+		return err
+	}
+
+	return nil
+}
+
+func validateAlbum(album *AlbumType) error {
+	album.Name = strings.TrimSpace(album.Name)
+	if album.Name == "" {
+		return fmt.Errorf("album name can't be empty")
+	}
+
+	if utf8.RuneCountInString(album.Name) > 100 {
+		return fmt.Errorf("album name is too long")
+	}
+
+	if album.Audience == "" {
+		album.Audience = "public"
+	}
+
+	if !AlbumAudienceTypes[album.Audience] {
+		return fmt.Errorf("audience must be public, friends or only_me")
+	}
+
+	return nil
+}
+
+func (h *Handler) CreateAlbum(w http.ResponseWriter, r *http.Request) error {
+	album := new(AlbumType)
+
+	if err := json.NewDecoder(r.Body).Decode(album); err != nil {
+		log.Println("1. CreateAlbum", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	if err := validateAlbum(album); err != nil {
+		return err
+	}
+
+	album.User_ID = util.GetUserID(r)
+	album.Auto_Key = ""
+	album.Cover_Image = ""
+
+	err := h.Repository.CreateAlbum(album)
+	if err != nil {
+		log.Println("2. CreateAlbum", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, map[string]int{"number": num})
+	return util.WriteJSON(w, http.StatusOK, album)
 }
 
-func (h *Handler) GetAllNotification(w http.ResponseWriter, r *http.Request) error {
+// album of user that caller can see
+func (h *Handler) GetAllAlbum(w http.ResponseWriter, r *http.Request) error {
 	userID := chi.URLParam(r, "userID")
 
-	notif, err := h.Repository.GetAllNotif(userID)
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllAlbum", err)
+		return err
+	}
+
+	albums, err := h.Repository.GetAllAlbum(userID, util.GetUserID(r), page)
+	if err != nil {
+		log.Println("2. GetAllAlbum", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(albums, page, albumKey))
+}
+
+func (h *Handler) GetAlbum(w http.ResponseWriter, r *http.Request) error {
+	albumID := chi.URLParam(r, "albumID")
+
+	album, err := h.Repository.GetAlbum(albumID, util.GetUserID(r))
+	if err != nil {
+		log.Println("1. GetAlbum", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, album)
+}
+
+// photo of album, the latest first
+func (h *Handler) GetAllAlbumPhoto(w http.ResponseWriter, r *http.Request) error {
+	albumID := chi.URLParam(r, "albumID")
+	userID := util.GetUserID(r)
+
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		log.Println("1. GetAllAlbumPhoto", err)
+		return err
+	}
+
+	_, err = h.Repository.GetAlbum(albumID, userID)
+	if err != nil {
+		log.Println("2. GetAllAlbumPhoto", err)
+		return err
+	}
+
+	photos, err := h.Repository.GetAllAlbumPhoto(albumID, userID, page)
+	if err != nil {
+		log.Println("3. GetAllAlbumPhoto", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(photos, page, albumPhotoKey))
+}
+
+// rename album and change its audience, auto album can only change audience
+func (h *Handler) UpdateAlbum(w http.ResponseWriter, r *http.Request) error {
+	album := new(AlbumType)
+
+	if err := json.NewDecoder(r.Body).Decode(album); err != nil {
+		log.Println("1. UpdateAlbum", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	album.User_ID = util.GetUserID(r)
+
+	current, err := h.Repository.GetOwnAlbum(album.Album_ID, album.User_ID)
+	if err != nil {
+		log.Println("2. UpdateAlbum", err)
+		return err
+	}
+
+	if current.Auto_Key != "" {
+		album.Name = current.Name
+	}
+
+	if err := validateAlbum(album); err != nil {
+		return err
+	}
+
+	err = h.Repository.UpdateAlbum(album)
+	if err != nil {
+		log.Println("3. UpdateAlbum", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// delete album made by user, its photo go back to the auto album
+func (h *Handler) DeleteAlbum(w http.ResponseWriter, r *http.Request) error {
+	albumID := chi.URLParam(r, "albumID")
+	userID := util.GetUserID(r)
+
+	album, err := h.Repository.GetOwnAlbum(albumID, userID)
+	if err != nil {
+		log.Println("1. DeleteAlbum", err)
+		return err
+	}
+
+	if album.Auto_Key != "" {
+		return fmt.Errorf("%s can't be deleted", album.Name)
+	}
+
+	sources, err := h.Repository.GetAlbumSource(albumID)
 	if err != nil {
-		log.Println("1. GetAllNotification", err)
+		log.Println("2. DeleteAlbum", err)
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, notif)
+	for _, source := range sources {
+		autoID, err := h.Repository.GetAutoAlbum(userID, source)
+		if err != nil {
+			log.Println("3. DeleteAlbum", err)
+			return err
+		}
+
+		if err := h.Repository.MoveAllAlbumPhoto(albumID, source, autoID); err != nil {
+			log.Println("4. DeleteAlbum", err)
+			return err
+		}
+	}
+
+	err = h.Repository.DeleteAlbum(albumID, userID)
+	if err != nil {
+		log.Println("5. DeleteAlbum", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// move own photo to other own album
+func (h *Handler) MoveAlbumPhoto(w http.ResponseWriter, r *http.Request) error {
+	req := new(MoveAlbumPhotoReqType)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Println("1. MoveAlbumPhoto", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	userID := util.GetUserID(r)
+
+	_, err := h.Repository.GetPhotoAlbum(userID, req.Image)
+	if err != nil {
+		log.Println("2. MoveAlbumPhoto", err)
+		return err
+	}
+
+	_, err = h.Repository.GetOwnAlbum(req.Album_ID, userID)
+	if err != nil {
+		log.Println("3. MoveAlbumPhoto", err)
+		return err
+	}
+
+	err = h.Repository.MoveAlbumPhoto(userID, req.Image, req.Album_ID)
+	if err != nil {
+		log.Println("4. MoveAlbumPhoto", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// choose cover of own album from photo inside it
+func (h *Handler) SetAlbumCover(w http.ResponseWriter, r *http.Request) error {
+	req := new(AlbumCoverReqType)
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Println("1. SetAlbumCover", err)
+		return err
+	}
+
+	defer r.Body.Close()
+
+	userID := util.GetUserID(r)
+
+	_, err := h.Repository.GetOwnAlbum(req.Album_ID, userID)
+	if err != nil {
+		log.Println("2. SetAlbumCover", err)
+		return err
+	}
+
+	if req.Image != "" {
+		albumID, err := h.Repository.GetPhotoAlbum(userID, req.Image)
+		if err != nil {
+			log.Println("3. SetAlbumCover", err)
+			return err
+		}
+
+		if albumID != req.Album_ID {
+			return fmt.Errorf("photo is not in the album")
+		}
+	}
+
+	err = h.Repository.SetAlbumCover(req.Album_ID, userID, req.Image)
+	if err != nil {
+		log.Println("4. SetAlbumCover", err)
+		return err
+	}
+
+	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func albumKey(a *AlbumType) (time.Time, string) {
+	return a.Created_At, a.Album_ID
+}
+
+func albumPhotoKey(p *AlbumPhotoType) (time.Time, string) {
+	return p.Created_At, p.Image
 }
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidatePoll(t *testing.T) {
+	from := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := from.Add(time.Hour).In(time.FixedZone("utc+7", 7*3600))
+	options := func(n int) []string {
+		o := make([]string, n)
+		for i := range o {
+			o[i] = "option"
+		}
+
+		return o
+	}
+
+	tests := []struct {
+		name    string
+		poll    PollReqType
+		options []string // option after trim
+		closes  *time.Time
+		err     bool
+	}{
+		{"two option", PollReqType{Options: []string{"yes", "no"}}, []string{"yes", "no"}, nil, false},
+		{"option trimmed", PollReqType{Options: []string{"  yes ", "\tno\n"}}, []string{"yes", "no"}, nil, false},
+		{"max option", PollReqType{Options: options(MaxPollOption)}, options(MaxPollOption), nil, false},
+		{"one option", PollReqType{Options: []string{"yes"}}, nil, nil, true},
+		{"no option", PollReqType{}, nil, nil, true},
+		{"too many option", PollReqType{Options: options(MaxPollOption + 1)}, nil, nil, true},
+		{"blank option", PollReqType{Options: []string{"yes", "   "}}, nil, nil, true},
+		{"option at 200 character", PollReqType{Options: []string{"yes", strings.Repeat("é", 200)}}, []string{"yes", strings.Repeat("é", 200)}, nil, false},
+		{"option over 200 character", PollReqType{Options: []string{"yes", strings.Repeat("é", 201)}}, nil, nil, true},
+		{"closes later in utc", PollReqType{Options: []string{"yes", "no"}, Closes_At: &later}, []string{"yes", "no"}, &later, false},
+		{"closes at publish", PollReqType{Options: []string{"yes", "no"}, Closes_At: &from}, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		poll := tt.poll
+		err := validatePoll(&poll, from)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: validatePoll() err %v, want err %v", tt.name, err, tt.err)
+			continue
+		}
+
+		if tt.err {
+			continue
+		}
+
+		if strings.Join(poll.Options, "|") != strings.Join(tt.options, "|") {
+			t.Errorf("%s: options %q, want %q", tt.name, poll.Options, tt.options)
+		}
+
+		if tt.closes != nil && (poll.Closes_At == nil || !poll.Closes_At.Equal(*tt.closes) || poll.Closes_At.Location() != time.UTC) {
+			t.Errorf("%s: closes_at %v, want %v in utc", tt.name, poll.Closes_At, tt.closes)
+		}
+	}
+}
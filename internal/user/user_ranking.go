@@ -0,0 +1,149 @@
+package user
+
+import (
+	"hash/fnv"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// only recent post of the feed is ranked
+const RankWindow = time.Duration(72) * time.Hour
+const RankCandidate = 300
+
+// interaction of viewer with author inside this period count as affinity
+const AffinityPeriod = time.Duration(30*24) * time.Hour
+
+// feature of one post in the feed that scorer use
+type FeedCandidateType struct {
+	Post       *GetPostResType
+	Affinity   int // reaction, comment and repost of viewer on author post
+	Engagement int // reaction, comment and repost the post got
+	Age        time.Duration
+}
+
+// Scorer rank post in ranked feed. There can be more than one so they can be A/B tested,
+// each user always get the same scorer.
+type Scorer interface {
+	Name() string
+	Score(c *FeedCandidateType) float64
+}
+
+// WeightedScorer multiply affinity and engagement boost with decay of post age
+type WeightedScorer struct {
+	ScorerName string
+	Affinity   float64
+	Engagement float64
+	Half_Life  time.Duration
+}
+
+func (s *WeightedScorer) Name() string {
+	return s.ScorerName
+}
+
+func (s *WeightedScorer) Score(c *FeedCandidateType) float64 {
+	affinity := 1 + s.Affinity*math.Log1p(float64(c.Affinity))
+	engagement := 1 + s.Engagement*math.Log1p(float64(c.Engagement))
+	recency := math.Pow(0.5, c.Age.Hours()/s.Half_Life.Hours())
+
+	return affinity * engagement * recency
+}
+
+// every scorer that can be turned on with FEED_RANKERS
+var Scorers = map[string]Scorer{
+	"default": &WeightedScorer{ScorerName: "default", Affinity: 1, Engagement: 1, Half_Life: 12 * time.Hour},
+	"fresh":   &WeightedScorer{ScorerName: "fresh", Affinity: 0.5, Engagement: 0.5, Half_Life: 4 * time.Hour},
+	"social":  &WeightedScorer{ScorerName: "social", Affinity: 2, Engagement: 0.5, Half_Life: 12 * time.Hour},
+}
+
+// scorer from comma separated name, empty or unknown name fall back to default
+func NewRankers(names string) []Scorer {
+	rankers := []Scorer{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		s, ok := Scorers[name]
+		if !ok {
+			log.Println("1. NewRankers unknown ranker", name)
+			continue
+		}
+
+		rankers = append(rankers, s)
+	}
+
+	if len(rankers) == 0 {
+		rankers = append(rankers, Scorers["default"])
+	}
+
+	return rankers
+}
+
+// pick scorer of user by hash so the user stay in the same bucket
+func (h *Handler) pickRanker(user_id string) Scorer {
+	f := fnv.New32a()
+	f.Write([]byte(user_id))
+
+	return h.Rankers[int(f.Sum32()%uint32(len(h.Rankers)))]
+}
+
+// RankFeed score recent post of the feed and return it highest score first
+func (h *Handler) RankFeed(user_id string, ranker Scorer, now time.Time) ([]*GetPostResType, error) {
+	posts, err := h.Repository.GetFeedCandidate(user_id, now.Add(-RankWindow), RankCandidate)
+	if err != nil {
+		log.Println("1. RankFeed", err)
+		return nil, err
+	}
+
+	if len(posts) == 0 {
+		return posts, nil
+	}
+
+	// plain repost is scored by engagement of the original
+	ids := []string{}
+	for _, p := range posts {
+		ids = append(ids, engagementID(p))
+	}
+
+	engagement, err := h.Repository.GetEngagement(ids)
+	if err != nil {
+		log.Println("2. RankFeed", err)
+		return nil, err
+	}
+
+	affinity, err := h.Repository.GetAffinity(user_id, now.Add(-AffinityPeriod))
+	if err != nil {
+		log.Println("3. RankFeed", err)
+		return nil, err
+	}
+
+	score := map[string]float64{}
+	for _, p := range posts {
+		c := &FeedCandidateType{
+			Post:       p,
+			Affinity:   affinity[p.User_ID],
+			Engagement: engagement[engagementID(p)],
+			Age:        now.Sub(p.Created_At),
+		}
+
+		score[p.Post_ID] = ranker.Score(c)
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		return score[posts[i].Post_ID] > score[posts[j].Post_ID]
+	})
+
+	return posts, nil
+}
+
+func engagementID(p *GetPostResType) string {
+	if p.Type == "repost" {
+		return p.Original_Post_ID
+	}
+
+	return p.Post_ID
+}
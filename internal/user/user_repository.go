@@ -2,10 +2,14 @@ package user
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/erlnerlngga/backend-socius/internal/media"
+	"github.com/erlnerlngga/backend-socius/util"
 	"github.com/google/uuid"
 )
 
@@ -24,10 +28,39 @@ func NewUserRepository(db DBTX) *Repository {
 	return &Repository{db: db}
 }
 
+// run fn in one transaction, repository that is already in transaction just run fn
+func (r *Repository) WithTx(fn func(tx *Repository) error) error {
+	db, ok := r.db.(*sql.DB)
+	if !ok {
+		return fn(r)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Println("1. WithTx", err)
+		return err
+	}
+
+	if err := fn(&Repository{db: tx}); err != nil {
+		if err := tx.Rollback(); err != nil {
+			log.Println("2. WithTx", err)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("3. WithTx", err)
+		return err
+	}
+
+	return nil
+}
+
 func (r *Repository) CheckEmail(email string) (*UserType, error) {
 	acc := new(UserType)
 
-	query := `select * from user where email = ?;`
+	query := `select user_id, user_name, email, photo_profile from user where email = ?;`
 	err := r.db.QueryRow(query, email).Scan(&acc.User_ID, &acc.User_Name, &acc.Email, &acc.Photo_Profile)
 
 	if err == sql.ErrNoRows {
@@ -54,7 +87,7 @@ func (r *Repository) SignUp(acc *UserType) (*UserType, error) {
 		return nil, err
 	}
 
-	queryRes := `select * from user where email = ?;`
+	queryRes := `select user_id, user_name, email, photo_profile from user where email = ?;`
 	err = r.db.QueryRow(queryRes, acc.Email).Scan(&account.User_ID, &account.User_Name, &account.Email, &account.Photo_Profile)
 	if err != nil {
 		return nil, err
@@ -67,9 +100,9 @@ func (r *Repository) SignUp(acc *UserType) (*UserType, error) {
 func (r *Repository) GetUser(user_id string) (*UserType, error) {
 	u := new(UserType)
 
-	query := `select user_id ,user_name, email, photo_profile from user where user_id = ?;`
+	query := `select user_id ,user_name, email, photo_profile, default_audience from user where user_id = ?;`
 
-	err := r.db.QueryRow(query, user_id).Scan(&u.User_ID, &u.User_Name, &u.Email, &u.Photo_Profile)
+	err := r.db.QueryRow(query, user_id).Scan(&u.User_ID, &u.User_Name, &u.Email, &u.Photo_Profile, &u.Default_Audience)
 	if err == sql.ErrNoRows {
 		log.Println("1. GetUser", err)
 		return nil, fmt.Errorf("user not found")
@@ -119,8 +152,9 @@ func (r *Repository) GetUserbyEmail(email string) (*UserType, error) {
 func (r *Repository) AddFriend(acc *User_FriendType) error {
 
 	acc.User_Friend_ID = uuid.New().String()
-	query := `insert into user_friend(user_friend_id, user_id, friend_id) values(?, ?, ?);`
-	_, err := r.db.Exec(query, acc.User_Friend_ID, acc.User_ID, acc.Friend_ID)
+	acc.Created_At = time.Now().UTC()
+	query := `insert into user_friend(user_friend_id, user_id, friend_id, created_at) values(?, ?, ?, ?);`
+	_, err := r.db.Exec(query, acc.User_Friend_ID, acc.User_ID, acc.Friend_ID, acc.Created_At)
 
 	if err != nil {
 		log.Println("1. AddFriend", err)
@@ -144,6 +178,32 @@ func (r *Repository) CheckFriend(user_id string) (int, error) {
 	return *number, nil
 }
 
+// check user already has other as friend
+func (r *Repository) IsFriend(user_id, other_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from user_friend where user_id = ? and friend_id = ?;"
+	if err := r.db.QueryRow(query, user_id, other_id).Scan(&number); err != nil {
+		log.Println("1. IsFriend", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// check either user block the other
+func (r *Repository) IsBlocked(user_id, other_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from user_block where (user_id = ? and blocked_id = ?) or (user_id = ? and blocked_id = ?);"
+	if err := r.db.QueryRow(query, user_id, other_id, other_id, user_id).Scan(&number); err != nil {
+		log.Println("1. IsBlocked", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
 // remove friend
 func (r *Repository) RemoveFriend(user_friend_id string) error {
 	query := "delete from user_friend where user_friend_id = ?;"
@@ -171,10 +231,11 @@ func (r *Repository) RemoveFriendByUserID(user_id, friend_id string) error {
 }
 
 // get all my friend
-func (r *Repository) GetAllFriend(user_id string) ([]*UserFriendType, error) {
-	query := "select user.user_id as `user_id`, user.user_name as `user_name`, user.email as `email`, user.photo_profile as `photo_profile`, user_friend.user_friend_id as `user_friend_id` from user_friend inner join user on user_friend.friend_id = user.user_id where user_friend.user_id = ?;"
+func (r *Repository) GetAllFriend(user_id string, page *util.PageReqType) ([]*UserFriendType, error) {
+	cond, args := page.Query("user_friend.created_at", "user_friend.user_friend_id")
+	query := "select user.user_id as `user_id`, user.user_name as `user_name`, user.email as `email`, user.photo_profile as `photo_profile`, user_friend.user_friend_id as `user_friend_id`, user_friend.created_at as `created_at` from user_friend inner join user on user_friend.friend_id = user.user_id where user_friend.user_id = ?" + cond + ";"
 
-	rows, err := r.db.Query(query, user_id)
+	rows, err := r.db.Query(query, append([]any{user_id}, args...)...)
 
 	if err != nil {
 		log.Println("1. GetAllFriend", err)
@@ -187,7 +248,7 @@ func (r *Repository) GetAllFriend(user_id string) ([]*UserFriendType, error) {
 	for rows.Next() {
 		f := new(UserFriendType)
 
-		if err := rows.Scan(&f.User_ID, &f.User_Name, &f.Email, &f.Photo_Profile, &f.User_Friend_ID); err != nil {
+		if err := rows.Scan(&f.User_ID, &f.User_Name, &f.Email, &f.Photo_Profile, &f.User_Friend_ID, &f.Created_At); err != nil {
 			log.Println("2. GetAllFriend", err)
 			return nil, err
 		}
@@ -205,12 +266,26 @@ func (r *Repository) GetAllFriend(user_id string) ([]*UserFriendType, error) {
 
 // create post
 func (r *Repository) CreatePost(post *PostType) (string, error) {
-	post.Post_ID = uuid.New().String()
+	// scheduled post reserve its id before it is published
+	if post.Post_ID == "" {
+		post.Post_ID = uuid.New().String()
+	}
+
 	post.Created_At = time.Now().UTC()
 	post.Updated_At = time.Now().UTC()
 
-	query := `insert into post(post_id, user_id, content, type, created_at, updated_at) values (?, ?, ?, ?, ?, ?);`
-	_, err := r.db.Exec(query, post.Post_ID, post.User_ID, post.Content, post.Type, post.Created_At, post.Updated_At)
+	if post.Audience == "" {
+		post.Audience = "friends"
+	}
+
+	// only repost and quote point to original post
+	var original any
+	if post.Original_Post_ID != "" {
+		original = post.Original_Post_ID
+	}
+
+	query := `insert into post(post_id, user_id, content, type, created_at, updated_at, audience, original_post_id) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, post.Post_ID, post.User_ID, post.Content, post.Type, post.Created_At, post.Updated_At, post.Audience, original)
 
 	if err != nil {
 		log.Println("1. CreatePost", err)
@@ -239,10 +314,28 @@ func (r *Repository) GetUserPost(u *GetPostResType) (*GetPostResType, error) {
 }
 
 // get ALl post
-func (r *Repository) GetAllPost(user_id string) ([]*GetPostResType, error) {
-	query := "select post.post_id as `post_id`, post.user_id as `user_id`, post.content as `content`, post.type as `type`, post.created_at as `created_at`, post.updated_at as `updated_at` from user_friend inner join post on user_friend.friend_id = post.user_id or post.user_id = ? where user_friend.user_id = ? and post.type = 'main' order by post.created_at desc;"
+// feed of user: timeline row that already fan out on write, plus fan in post from high fanout author.
+// the query end in where clause so caller can add condition, args come from feedArgs
+func feedQuery() string {
+	return "select feed.post_id, feed.user_id, feed.content, feed.type, feed.created_at, feed.updated_at, feed.audience, coalesce(feed.original_post_id, '') from (" +
+		"select post.post_id, post.user_id, post.content, post.type, post.created_at, post.updated_at, post.audience, post.original_post_id, post.hidden from timeline inner join post on timeline.post_id = post.post_id where timeline.user_id = ? " +
+		"union " +
+		"select post.post_id, post.user_id, post.content, post.type, post.created_at, post.updated_at, post.audience, post.original_post_id, post.hidden from post inner join high_fanout_user on post.user_id = high_fanout_user.user_id " +
+		"where post.type in " + FeedTypes + " and (post.user_id = ? " +
+		"or post.user_id in (select friend_id from user_friend where user_id = ?) " +
+		"or (post.audience = 'public' and post.user_id in (select following_id from user_follow where follower_id = ?)))" +
+		") feed where " + VisibleCond("feed") + " and " + RepostVisibleCond("feed")
+}
+
+func feedArgs(user_id string) []any {
+	return append(append([]any{user_id, user_id, user_id, user_id}, VisibleArgs(user_id)...), VisibleArgs(user_id)...)
+}
+
+func (r *Repository) GetAllPost(user_id string, page *util.PageReqType) ([]*GetPostResType, error) {
+	cond, args := page.Query("feed.created_at", "feed.post_id")
+	query := feedQuery() + cond + ";"
 
-	rows, err := r.db.Query(query, user_id, user_id)
+	rows, err := r.db.Query(query, append(feedArgs(user_id), args...)...)
 
 	if err != nil {
 		log.Println("1. GetAllPost", err)
@@ -255,46 +348,150 @@ func (r *Repository) GetAllPost(user_id string) ([]*GetPostResType, error) {
 	for rows.Next() {
 		p := new(GetPostResType)
 
-		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.Content, &p.Type, &p.Created_At, &p.Updated_At); err != nil {
+		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.Content, &p.Type, &p.Created_At, &p.Updated_At, &p.Audience, &p.Original_Post_ID); err != nil {
 			log.Println("2. GetAllPost", err)
 			return nil, err
 		}
 
-		p, err = r.GetImagePost(p)
-
+		p, err = r.GetDetailPost(p, user_id)
 		if err != nil {
 			log.Println("3. GetAllPost", err)
 			return nil, err
 		}
 
-		p, err = r.GetCountPost(p)
-		if err != nil {
-			log.Println("4. GetAllPost", err)
+		allPost = append(allPost, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("4. GetAllPost", err)
+		return nil, err
+	}
+
+	return allPost, nil
+}
+
+// get feed post created since the time without detail, newest first, used by ranked feed
+func (r *Repository) GetFeedCandidate(user_id string, since time.Time, limit int) ([]*GetPostResType, error) {
+	query := feedQuery() + " and feed.created_at >= ? order by feed.created_at desc, feed.post_id desc limit ?;"
+
+	rows, err := r.db.Query(query, append(feedArgs(user_id), since, limit)...)
+	if err != nil {
+		log.Println("1. GetFeedCandidate", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	posts := []*GetPostResType{}
+	for rows.Next() {
+		p := new(GetPostResType)
+
+		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.Content, &p.Type, &p.Created_At, &p.Updated_At, &p.Audience, &p.Original_Post_ID); err != nil {
+			log.Println("2. GetFeedCandidate", err)
 			return nil, err
 		}
 
-		p, err = r.GetUserPost(p)
-		if err != nil {
-			log.Println("5. GetAllPost", err)
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetFeedCandidate", err)
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// count reaction, comment and repost of each post
+func (r *Repository) GetEngagement(post_ids []string) (map[string]int, error) {
+	res := map[string]int{}
+	if len(post_ids) == 0 {
+		return res, nil
+	}
+
+	in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(post_ids)), ", ") + ")"
+	args := []any{}
+	for _, id := range post_ids {
+		args = append(args, id)
+	}
+
+	query := "select post.post_id, (select count(*) from reaction where reaction.post_id = post.post_id) + " +
+		"(select count(*) from comment where comment.root_post_id = post.post_id) + " +
+		"(select count(*) from post rp where rp.original_post_id = post.post_id) as `engagement` from post where post.post_id in " + in + ";"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Println("1. GetEngagement", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var post_id string
+		var number int
+
+		if err := rows.Scan(&post_id, &number); err != nil {
+			log.Println("2. GetEngagement", err)
 			return nil, err
 		}
 
-		allPost = append(allPost, p)
+		res[post_id] = number
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println("6. GetAllPost", err)
+		log.Println("3. GetEngagement", err)
 		return nil, err
 	}
 
-	return allPost, nil
+	return res, nil
+}
+
+// count reaction, comment and repost of viewer on post of each author since the time
+func (r *Repository) GetAffinity(viewer_id string, since time.Time) (map[string]int, error) {
+	query := "select a.author, count(*) as `number` from (" +
+		"select p.user_id as author from reaction inner join post p on reaction.post_id = p.post_id where reaction.user_id = ? and reaction.created_at >= ? " +
+		"union all " +
+		"select p.user_id from comment inner join post cp on comment.comment_post_id = cp.post_id inner join post p on comment.post_id = p.post_id where cp.user_id = ? and cp.created_at >= ? " +
+		"union all " +
+		"select p.user_id from post rp inner join post p on rp.original_post_id = p.post_id where rp.user_id = ? and rp.created_at >= ?" +
+		") a group by a.author;"
+
+	rows, err := r.db.Query(query, viewer_id, since, viewer_id, since, viewer_id, since)
+	if err != nil {
+		log.Println("1. GetAffinity", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	res := map[string]int{}
+	for rows.Next() {
+		var author string
+		var number int
+
+		if err := rows.Scan(&author, &number); err != nil {
+			log.Println("2. GetAffinity", err)
+			return nil, err
+		}
+
+		res[author] = number
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAffinity", err)
+		return nil, err
+	}
+
+	return res, nil
 }
 
 // get ALl OWN post
-func (r *Repository) GetAllOwnPost(user_id string) ([]*GetPostResType, error) {
-	query := "select * from post where user_id = ? and type = 'main' order by created_at desc;"
+func (r *Repository) GetAllOwnPost(user_id, viewer_id string, page *util.PageReqType) ([]*GetPostResType, error) {
+	cond, args := page.Query("created_at", "post_id")
+	query := "select post_id, user_id, content, type, created_at, updated_at, audience, coalesce(original_post_id, '') from post where user_id = ? and type in " + FeedTypes + " and " + VisibleCond("post") + " and " + RepostVisibleCond("post") + cond + ";"
 
-	rows, err := r.db.Query(query, user_id)
+	rows, err := r.db.Query(query, append(append(append([]any{user_id}, VisibleArgs(viewer_id)...), VisibleArgs(viewer_id)...), args...)...)
 
 	if err != nil {
 		log.Println("1. GetAllOwnPost", err)
@@ -307,35 +504,22 @@ func (r *Repository) GetAllOwnPost(user_id string) ([]*GetPostResType, error) {
 	for rows.Next() {
 		p := new(GetPostResType)
 
-		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.Content, &p.Type, &p.Created_At, &p.Updated_At); err != nil {
+		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.Content, &p.Type, &p.Created_At, &p.Updated_At, &p.Audience, &p.Original_Post_ID); err != nil {
 			log.Println("2. GetAllOwnPost", err)
 			return nil, err
 		}
 
-		p, err = r.GetImagePost(p)
-
+		p, err = r.GetDetailPost(p, viewer_id)
 		if err != nil {
 			log.Println("3. GetAllOwnPost", err)
 			return nil, err
 		}
 
-		p, err = r.GetCountPost(p)
-		if err != nil {
-			log.Println("4. GetAllOwnPost", err)
-			return nil, err
-		}
-
-		p, err = r.GetUserPost(p)
-		if err != nil {
-			log.Println("5. GetAllOwnPost", err)
-			return nil, err
-		}
-
 		allPost = append(allPost, p)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println("6. GetAllOwnPost", err)
+		log.Println("4. GetAllOwnPost", err)
 		return nil, err
 	}
 
@@ -343,11 +527,11 @@ func (r *Repository) GetAllOwnPost(user_id string) ([]*GetPostResType, error) {
 }
 
 // get Single post
-func (r *Repository) GetPost(post_id string) (*GetPostResType, error) {
+func (r *Repository) GetPost(post_id, viewer_id string) (*GetPostResType, error) {
 	res := new(GetPostResType)
 
-	query := `select * from post where post_id = ?;`
-	err := r.db.QueryRow(query, post_id).Scan(&res.Post_ID, &res.User_ID, &res.Content, &res.Type, &res.Created_At, &res.Updated_At)
+	query := `select post_id, user_id, content, type, created_at, updated_at, audience, coalesce(original_post_id, '') from post where post_id = ?;`
+	err := r.db.QueryRow(query, post_id).Scan(&res.Post_ID, &res.User_ID, &res.Content, &res.Type, &res.Created_At, &res.Updated_At, &res.Audience, &res.Original_Post_ID)
 
 	if err == sql.ErrNoRows {
 		log.Println("1. GetPost", err)
@@ -359,32 +543,116 @@ func (r *Repository) GetPost(post_id string) (*GetPostResType, error) {
 		return nil, err
 	}
 
-	res, err = r.GetImagePost(res)
+	// hidden post look the same as post that isn't there
+	visible, err := r.CanViewPost(post_id, viewer_id)
 	if err != nil {
 		log.Println("3. GetPost", err)
 		return nil, err
 	}
 
-	res, err = r.GetCountPost(res)
+	if !visible {
+		return nil, fmt.Errorf("post not found")
+	}
+
+	res, err = r.GetDetailPost(res, viewer_id)
 	if err != nil {
 		log.Println("4. GetPost", err)
 		return nil, err
 	}
 
-	res, err = r.GetUserPost(res)
+	return res, nil
+}
+
+// fill image, count, author, reaction and entity of post as seen by viewer
+func (r *Repository) GetDetailPost(post *GetPostResType, viewer_id string) (*GetPostResType, error) {
+	post, err := r.GetImagePost(post)
+	if err != nil {
+		log.Println("1. GetDetailPost", err)
+		return nil, err
+	}
+
+	post, err = r.GetCountPost(post)
+	if err != nil {
+		log.Println("2. GetDetailPost", err)
+		return nil, err
+	}
+
+	post, err = r.GetUserPost(post)
+	if err != nil {
+		log.Println("3. GetDetailPost", err)
+		return nil, err
+	}
+
+	post, err = r.GetReactionPost(post, viewer_id)
+	if err != nil {
+		log.Println("4. GetDetailPost", err)
+		return nil, err
+	}
+
+	post, err = r.GetEntityPost(post)
+	if err != nil {
+		log.Println("5. GetDetailPost", err)
+		return nil, err
+	}
+
+	post, err = r.GetPollPost(post, viewer_id)
+	if err != nil {
+		log.Println("6. GetDetailPost", err)
+		return nil, err
+	}
+
+	if post.Original_Post_ID != "" {
+		post.Original, err = r.GetOriginalPost(post.Original_Post_ID, viewer_id)
+		if err != nil {
+			log.Println("7. GetDetailPost", err)
+			return nil, err
+		}
+	}
+
+	return post, nil
+}
+
+// get post that is reposted or quoted, nil when it is gone or viewer can't see it
+func (r *Repository) GetOriginalPost(post_id, viewer_id string) (*GetPostResType, error) {
+	visible, err := r.CanViewPost(post_id, viewer_id)
+	if err != nil {
+		log.Println("1. GetOriginalPost", err)
+		return nil, err
+	}
+
+	if !visible {
+		return nil, nil
+	}
+
+	res := new(GetPostResType)
+
+	query := `select post_id, user_id, content, type, created_at, updated_at, audience from post where post_id = ?;`
+	err = r.db.QueryRow(query, post_id).Scan(&res.Post_ID, &res.User_ID, &res.Content, &res.Type, &res.Created_At, &res.Updated_At, &res.Audience)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("2. GetOriginalPost", err)
+		return nil, err
+	}
+
+	// original_post_id is left empty so only one level is embedded
+	res, err = r.GetDetailPost(res, viewer_id)
 	if err != nil {
-		log.Println("5. GetPost", err)
+		log.Println("3. GetOriginalPost", err)
 		return nil, err
 	}
 
 	return res, nil
 }
 
-// get count comment per Post
+// get count comment and repost per Post and whether it was edited
 func (r *Repository) GetCountPost(post *GetPostResType) (*GetPostResType, error) {
-	query := "select count(*) as `number_of_comment` from comment where post_id = ?;"
+	query := "select (select count(*) from comment where post_id = ?) as `number_of_comment`, exists (select 1 from post_history where post_id = ?) as `edited`, " +
+		"(select count(*) from post where original_post_id = ?) as `number_of_repost`;"
 
-	err := r.db.QueryRow(query, post.Post_ID).Scan(&post.Number_Of_Comment)
+	err := r.db.QueryRow(query, post.Post_ID, post.Post_ID, post.Post_ID).Scan(&post.Number_Of_Comment, &post.Edited, &post.Number_Of_Repost)
 	if err != nil {
 		log.Println("1. GetCountPost", err)
 		return nil, err
@@ -395,7 +663,7 @@ func (r *Repository) GetCountPost(post *GetPostResType) (*GetPostResType, error)
 
 // get imageforPost
 func (r *Repository) GetImagePost(img *GetPostResType) (*GetPostResType, error) {
-	query := `select * from image_post where post_id = ?;`
+	query := `select image_post_id, post_id, user_id, image, coalesce(media_id, ''), created_at, updated_at from image_post where post_id = ?;`
 
 	rows, err := r.db.Query(query, img.Post_ID)
 
@@ -409,7 +677,7 @@ func (r *Repository) GetImagePost(img *GetPostResType) (*GetPostResType, error)
 	for rows.Next() {
 		i := new(Image_PostType)
 
-		if err := rows.Scan(&i.Image_Post_ID, &i.Post_ID, &i.User_ID, &i.Image, &i.Created_At, &i.Updated_At); err != nil {
+		if err := rows.Scan(&i.Image_Post_ID, &i.Post_ID, &i.User_ID, &i.Image, &i.Media_ID, &i.Created_At, &i.Updated_At); err != nil {
 			log.Println("2. GetImagePost", err)
 			return nil, err
 		}
@@ -422,6 +690,11 @@ func (r *Repository) GetImagePost(img *GetPostResType) (*GetPostResType, error)
 		return nil, err
 	}
 
+	if err := r.GetImageMedia(img.Images); err != nil {
+		log.Println("4. GetImagePost", err)
+		return nil, err
+	}
+
 	return img, nil
 }
 
@@ -432,21 +705,30 @@ func (r *Repository) CreateImagePost(img *Image_PostType) error {
 	img.Created_At = time.Now().UTC()
 	img.Updated_At = time.Now().UTC()
 
-	query := `insert into image_post(image_post_id, post_id, user_id, image, created_at, updated_at) values (?, ?, ?, ?, ?, ?);`
-	_, err := r.db.Exec(query, img.Image_Post_ID, img.Post_ID, img.User_ID, img.Image, img.Created_At, img.Updated_At)
+	query := `insert into image_post(image_post_id, post_id, user_id, image, media_id, created_at, updated_at) values (?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, img.Image_Post_ID, img.Post_ID, img.User_ID, img.Image, img.Media_ID, img.Created_At, img.Updated_At)
 	if err != nil {
 		log.Println("1. CreateImagePost", err)
 		return err
 	}
 
+	if err := r.AddAlbumPhoto(img.User_ID, img.Image, img.Media_ID, "post"); err != nil {
+		log.Println("2. CreateImagePost", err)
+		return err
+	}
+
 	return nil
 }
 
 // Get all image
-func (r *Repository) GetAllImage(user_id string) ([]*Image_PostType, error) {
-	query := `select * from image_post where user_id = ? order by created_at desc;`
-
-	rows, err := r.db.Query(query, user_id)
+func (r *Repository) GetAllImage(user_id, viewer_id string, page *util.PageReqType) ([]*Image_PostType, error) {
+	// image of comment follow audience of the root post
+	cond, args := page.Query("image_post.created_at", "image_post.image_post_id")
+	query := "select image_post.image_post_id, image_post.post_id, image_post.user_id, image_post.image, coalesce(image_post.media_id, ''), image_post.created_at, image_post.updated_at from image_post " +
+		"inner join post on post.post_id = coalesce((select root_post_id from comment where comment.comment_post_id = image_post.post_id), image_post.post_id) " +
+		"where image_post.user_id = ? and " + VisibleCond("post") + cond + ";"
+
+	rows, err := r.db.Query(query, append(append([]any{user_id}, VisibleArgs(viewer_id)...), args...)...)
 	if err != nil {
 		log.Println("1. GetAllImage", err)
 		return nil, err
@@ -458,7 +740,7 @@ func (r *Repository) GetAllImage(user_id string) ([]*Image_PostType, error) {
 	for rows.Next() {
 		i := new(Image_PostType)
 
-		if err := rows.Scan(&i.Image_Post_ID, &i.Post_ID, &i.User_ID, &i.Image, &i.Created_At, &i.Updated_At); err != nil {
+		if err := rows.Scan(&i.Image_Post_ID, &i.Post_ID, &i.User_ID, &i.Image, &i.Media_ID, &i.Created_At, &i.Updated_At); err != nil {
 			log.Println("2. GetAllImage", err)
 			return nil, err
 		}
@@ -471,162 +753,2134 @@ func (r *Repository) GetAllImage(user_id string) ([]*Image_PostType, error) {
 		return nil, err
 	}
 
+	if err := r.GetImageMedia(images); err != nil {
+		log.Println("4. GetAllImage", err)
+		return nil, err
+	}
+
 	return images, nil
 }
 
-// create comment
-func (r *Repository) CreateComment(comment *CommentType) error {
-	comment.Comment_ID = uuid.New().String()
-	comment.Created_At = time.Now().UTC()
-	comment.Updated_At = time.Now().UTC()
+// fill dimension, blurhash and variant of image that uploaded as media
+func (r *Repository) GetImageMedia(images []*Image_PostType) error {
+	byMedia := map[string][]*Image_PostType{}
+	args := []any{}
+	for _, i := range images {
+		i.Variants = []*media.VariantType{}
+		if i.Media_ID == "" {
+			continue
+		}
 
-	query := `insert into comment(comment_id, post_id, comment_post_id, created_at, updated_at) values (?, ?, ?, ?, ?);`
-	_, err := r.db.Exec(query, comment.Comment_ID, comment.Post_ID, comment.Comment_Post_ID, comment.Created_At, comment.Updated_At)
-	if err != nil {
-		log.Println("1. CreateComment", err)
-		return err
+		if _, ok := byMedia[i.Media_ID]; !ok {
+			args = append(args, i.Media_ID)
+		}
+
+		byMedia[i.Media_ID] = append(byMedia[i.Media_ID], i)
 	}
 
-	return nil
-}
+	if len(args) == 0 {
+		return nil
+	}
 
-// get All Comment
-func (r *Repository) GetAllComment(post_id string) ([]*GetPostResType, error) {
-	query := "select post.post_id as `post_id`, post.user_id as `user_id`, post.content as `content`, post.type as `type`, post.created_at as `created_at`, post.updated_at as `updated_at` from comment inner join post on comment.comment_post_id = post.post_id where comment.post_id = ? order by post.created_at desc;"
+	in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ") + ")"
 
-	rows, err := r.db.Query(query, post_id)
+	rows, err := r.db.Query("select media_id, width, height, blurhash from media where media_id in "+in+";", args...)
 	if err != nil {
-		log.Println("1. GetAllComment", err)
-		return nil, err
+		log.Println("1. GetImageMedia", err)
+		return err
 	}
 
 	defer rows.Close()
 
-	allComment := []*GetPostResType{}
 	for rows.Next() {
-		c := new(GetPostResType)
-
-		if err := rows.Scan(&c.Post_ID, &c.User_ID, &c.Content, &c.Type, &c.Created_At, &c.Updated_At); err != nil {
-			log.Println("2. GetAllComment", err)
-			return nil, err
-		}
-
-		c, err = r.GetImagePost(c)
-		if err != nil {
-			log.Println("3. GetAllComment", err)
-			return nil, err
-		}
+		var mediaID, blurhash string
+		var width, height int
 
-		c, err = r.GetCountPost(c)
-		if err != nil {
-			log.Println("4. GetAllComment", err)
-			return nil, err
+		if err := rows.Scan(&mediaID, &width, &height, &blurhash); err != nil {
+			log.Println("2. GetImageMedia", err)
+			return err
 		}
 
-		c, err = r.GetUserPost(c)
-		if err != nil {
-			log.Println("5. GetAllComment", err)
-			return nil, err
+		for _, i := range byMedia[mediaID] {
+			i.Width, i.Height, i.Blurhash = width, height, blurhash
 		}
-
-		allComment = append(allComment, c)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println("6. GetAllComment", err)
-		return nil, err
+		log.Println("3. GetImageMedia", err)
+		return err
 	}
 
-	return allComment, nil
-}
-
-// create notification
-func (r *Repository) CreateNotification(notif *NotificationType) error {
-	notif.Notification_ID = uuid.New().String()
-	notif.Created_At = time.Now().UTC()
-	notif.Updated_At = time.Now().UTC()
-
-	query := `insert into notification(notification_id, issuer, issuer_name, notifier, notifier_name, status, accept, post_id, type, created_at, updated_at) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
-	_, err := r.db.Exec(query, notif.Notification_ID, notif.Issuer, notif.Issuer_Name, notif.Notifier, notif.Notifier_Name, notif.Status, notif.Accept, notif.Post_ID, notif.Type, notif.Created_At, notif.Updated_At)
+	variantRows, err := r.db.Query("select media_id, name, content_type, width, height, size from media_variant where media_id in "+in+" order by width asc, name asc;", args...)
 	if err != nil {
-		log.Println("1. CreateNotification", err)
+		log.Println("4. GetImageMedia", err)
 		return err
 	}
 
-	return nil
-}
+	defer variantRows.Close()
 
-// update friend notif
-func (r *Repository) UpdateNotif(notif *UpdateNotifType) error {
-	notif.Updated_At = time.Now().UTC()
+	for variantRows.Next() {
+		v := new(media.VariantType)
 
-	query := `update notification set accept = ?, updated_at = ? where notification_id = ?;`
-	_, err := r.db.Exec(query, notif.Accept, notif.Updated_At, notif.Notification_ID)
-	if err != nil {
-		log.Println("1. UpdateNotif", err)
+		if err := variantRows.Scan(&v.Media_ID, &v.Name, &v.Content_Type, &v.Width, &v.Height, &v.Size); err != nil {
+			log.Println("5. GetImageMedia", err)
+			return err
+		}
+
+		v.URL = media.VariantURL(v.Media_ID, v.Name)
+		for _, i := range byMedia[v.Media_ID] {
+			i.Variants = append(i.Variants, v)
+		}
+	}
+
+	if err := variantRows.Err(); err != nil {
+		log.Println("6. GetImageMedia", err)
 		return err
 	}
 
 	return nil
 }
 
-// updated become read
-func (r *Repository) UpdatedNotifRead(user_id string) error {
+// create comment
+func (r *Repository) CreateComment(comment *CommentType) error {
+	comment.Comment_ID = uuid.New().String()
+	comment.Created_At = time.Now().UTC()
+	comment.Updated_At = time.Now().UTC()
 
-	query := `update notification set status = "read", updated_at = ? where notifier = ? and status = "not_read";`
-	_, err := r.db.Exec(query, time.Now().UTC(), user_id)
+	query := `insert into comment(comment_id, post_id, comment_post_id, created_at, updated_at, root_post_id) values (?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, comment.Comment_ID, comment.Post_ID, comment.Comment_Post_ID, comment.Created_At, comment.Updated_At, comment.Root_Post_ID)
 	if err != nil {
-		log.Println("1. UpdatedNotifRead", err)
+		log.Println("1. CreateComment", err)
 		return err
 	}
 
 	return nil
 }
 
-// get count Notif
-func (r *Repository) GetCountNotif(user_id string) (int, error) {
-	var number int
-	query := "select count(*) as `number` from notification where notifier = ? and status = 'not_read';"
+// get comment row by post_id of the comment
+func (r *Repository) GetComment(comment_post_id string) (*CommentType, error) {
+	c := new(CommentType)
 
-	err := r.db.QueryRow(query, user_id).Scan(&number)
+	query := `select comment_id, post_id, comment_post_id, root_post_id, created_at, updated_at from comment where comment_post_id = ?;`
+	err := r.db.QueryRow(query, comment_post_id).Scan(&c.Comment_ID, &c.Post_ID, &c.Comment_Post_ID, &c.Root_Post_ID, &c.Created_At, &c.Updated_At)
+
+	if err == sql.ErrNoRows {
+		log.Println("1. GetComment", err)
+		return nil, fmt.Errorf("comment not found")
+	}
 
 	if err != nil {
-		log.Println("1. GetCountNotif", err)
-		return -1, err
+		log.Println("2. GetComment", err)
+		return nil, err
 	}
 
-	return number, nil
+	return c, nil
 }
 
-// get All notif
-func (r *Repository) GetAllNotif(user_id string) ([]*NotificationType, error) {
-	query := `select * from notification where notifier = ? order by created_at desc;`
+// score of comment for top sort, number of reaction and direct reply
+const commentScore = "((select count(*) from reaction where reaction.post_id = post.post_id) + (select count(*) from comment reply where reply.post_id = post.post_id))"
 
-	rows, err := r.db.Query(query, user_id)
+// get All Comment, direct reply of post or comment sorted by newest, oldest or top
+func (r *Repository) GetAllComment(post_id, viewer_id, sort string, page *util.PageReqType) ([]*CommentThreadType, error) {
+	cond, args := page.Query("post.created_at", "post.post_id")
+	if sort == "top" {
+		cond, args = page.QueryScore(commentScore, "post.post_id")
+	}
 
+	query := "select post.post_id as `post_id`, post.user_id as `user_id`, post.content as `content`, post.type as `type`, post.created_at as `created_at`, post.updated_at as `updated_at`, post.audience as `audience`, " + commentScore + " as `score` from comment inner join post on comment.comment_post_id = post.post_id where comment.post_id = ? and post.hidden = false" + cond + ";"
+
+	rows, err := r.db.Query(query, append([]any{post_id}, args...)...)
 	if err != nil {
-		log.Println("1. GetAllNotif", err)
+		log.Println("1. GetAllComment", err)
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	notifs := []*NotificationType{}
+	allComment := []*CommentThreadType{}
 	for rows.Next() {
-		newNotif := new(NotificationType)
+		c := &CommentThreadType{GetPostResType: new(GetPostResType), Parent_ID: post_id, Replies: []*CommentThreadType{}}
+
+		if err := rows.Scan(&c.Post_ID, &c.User_ID, &c.Content, &c.Type, &c.Created_At, &c.Updated_At, &c.Audience, &c.Score); err != nil {
+			log.Println("2. GetAllComment", err)
+			return nil, err
+		}
 
-		if err := rows.Scan(&newNotif.Notification_ID, &newNotif.Issuer, &newNotif.Issuer_Name, &newNotif.Notifier, &newNotif.Notifier_Name, &newNotif.Status, &newNotif.Accept, &newNotif.Post_ID, &newNotif.Type, &newNotif.Created_At, &newNotif.Updated_At); err != nil {
-			log.Println("2. GetAllNotif", err)
+		c.GetPostResType, err = r.GetDetailPost(c.GetPostResType, viewer_id)
+		if err != nil {
+			log.Println("3. GetAllComment", err)
 			return nil, err
 		}
 
-		notifs = append(notifs, newNotif)
+		allComment = append(allComment, c)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println("3. GetAllNotif", err)
+		log.Println("4. GetAllComment", err)
 		return nil, err
 	}
 
-	return notifs, nil
+	return allComment, nil
+}
+
+// load reply of each comment until max depth, the rest only counted as collapsed
+func (r *Repository) GetCommentThread(comments []*CommentThreadType, viewer_id, sort string, depth, maxDepth, replyLimit int) error {
+	for _, c := range comments {
+		c.Depth = depth
+		c.Collapsed_Reply = c.Number_Of_Comment
+
+		if depth >= maxDepth || c.Number_Of_Comment == 0 {
+			continue
+		}
+
+		page := &util.PageReqType{Limit: replyLimit, Asc: sort == "oldest"}
+
+		replies, err := r.GetAllComment(c.Post_ID, viewer_id, sort, page)
+		if err != nil {
+			log.Println("1. GetCommentThread", err)
+			return err
+		}
+
+		if len(replies) > replyLimit {
+			replies = replies[:replyLimit]
+		}
+
+		if err := r.GetCommentThread(replies, viewer_id, sort, depth+1, maxDepth, replyLimit); err != nil {
+			log.Println("2. GetCommentThread", err)
+			return err
+		}
+
+		c.Replies = replies
+		c.Collapsed_Reply = c.Number_Of_Comment - len(replies)
+	}
+
+	return nil
+}
+
+// get friend suggestion, friends of friends ranked by number of mutual friend
+func (r *Repository) GetFriendSuggestion(user_id string, limit int) ([]*SuggestionType, error) {
+	query := "select user.user_id as `user_id`, user.user_name as `user_name`, user.email as `email`, user.photo_profile as `photo_profile`, count(distinct f1.friend_id) as `mutual_friend` " +
+		"from user_friend f1 inner join user_friend f2 on f2.user_id = f1.friend_id inner join user on user.user_id = f2.friend_id " +
+		"where f1.user_id = ? and f2.friend_id <> ? " +
+		"and not exists (select 1 from user_friend uf where uf.user_id = ? and uf.friend_id = f2.friend_id) " +
+		"and not exists (select 1 from user_block ub where (ub.user_id = ? and ub.blocked_id = f2.friend_id) or (ub.user_id = f2.friend_id and ub.blocked_id = ?)) " +
+		"and not exists (select 1 from suggestion_dismiss sd where sd.user_id = ? and sd.dismissed_id = f2.friend_id) " +
+		"and not exists (select 1 from notification n where n.type = 'add_friend' and coalesce(n.accept, '') <> 'reject' and ((n.issuer = ? and n.notifier = f2.friend_id) or (n.issuer = f2.friend_id and n.notifier = ?))) " +
+		"group by user.user_id, user.user_name, user.email, user.photo_profile order by `mutual_friend` desc, user.user_name asc limit ?;"
+
+	rows, err := r.db.Query(query, user_id, user_id, user_id, user_id, user_id, user_id, user_id, user_id, limit)
+	if err != nil {
+		log.Println("1. GetFriendSuggestion", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := []*SuggestionType{}
+	for rows.Next() {
+		s := new(SuggestionType)
+
+		if err := rows.Scan(&s.User_ID, &s.User_Name, &s.Email, &s.Photo_Profile, &s.Mutual_Friend); err != nil {
+			log.Println("2. GetFriendSuggestion", err)
+			return nil, err
+		}
+
+		suggestions = append(suggestions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetFriendSuggestion", err)
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// dismiss suggestion
+func (r *Repository) DismissSuggestion(dismiss *DismissSuggestionType) error {
+	dismiss.Suggestion_Dismiss_ID = uuid.New().String()
+	dismiss.Created_At = time.Now().UTC()
+
+	query := `insert ignore into suggestion_dismiss(suggestion_dismiss_id, user_id, dismissed_id, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, dismiss.Suggestion_Dismiss_ID, dismiss.User_ID, dismiss.Dismissed_ID, dismiss.Created_At)
+	if err != nil {
+		log.Println("1. DismissSuggestion", err)
+		return err
+	}
+
+	return nil
+}
+
+// get mutual friend between two user
+func (r *Repository) GetMutualFriend(user_id, other_id string) ([]*UserType, error) {
+	// friend that blocked the caller or is blocked by the caller is left out
+	query := "select user.user_id as `user_id`, user.user_name as `user_name`, user.photo_profile as `photo_profile` from user_friend f1 inner join user_friend f2 on f1.friend_id = f2.friend_id inner join user on user.user_id = f1.friend_id where f1.user_id = ? and f2.user_id = ? " +
+		"and not exists (select 1 from user_block ub where (ub.user_id = ? and ub.blocked_id = f1.friend_id) or (ub.user_id = f1.friend_id and ub.blocked_id = ?)) " +
+		"order by user.user_name asc;"
+
+	rows, err := r.db.Query(query, user_id, other_id, user_id, user_id)
+	if err != nil {
+		log.Println("1. GetMutualFriend", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	friends := []*UserType{}
+	for rows.Next() {
+		u := new(UserType)
+
+		if err := rows.Scan(&u.User_ID, &u.User_Name, &u.Photo_Profile); err != nil {
+			log.Println("2. GetMutualFriend", err)
+			return nil, err
+		}
+
+		friends = append(friends, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetMutualFriend", err)
+		return nil, err
+	}
+
+	return friends, nil
+}
+
+// block user
+func (r *Repository) BlockUser(block *User_BlockType) error {
+	block.User_Block_ID = uuid.New().String()
+	block.Created_At = time.Now().UTC()
+
+	query := `insert ignore into user_block(user_block_id, user_id, blocked_id, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, block.User_Block_ID, block.User_ID, block.Blocked_ID, block.Created_At)
+	if err != nil {
+		log.Println("1. BlockUser", err)
+		return err
+	}
+
+	return nil
+}
+
+// unblock user
+func (r *Repository) UnblockUser(user_id, blocked_id string) error {
+	_, err := r.db.Exec(`delete from user_block where user_id = ? and blocked_id = ?;`, user_id, blocked_id)
+	if err != nil {
+		log.Println("1. UnblockUser", err)
+		return err
+	}
+
+	return nil
+}
+
+// follow user
+func (r *Repository) Follow(follow *User_FollowType) error {
+	follow.User_Follow_ID = uuid.New().String()
+	follow.Created_At = time.Now().UTC()
+
+	query := `insert ignore into user_follow(user_follow_id, follower_id, following_id, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, follow.User_Follow_ID, follow.Follower_ID, follow.Following_ID, follow.Created_At)
+	if err != nil {
+		log.Println("1. Follow", err)
+		return err
+	}
+
+	return nil
+}
+
+// unfollow user
+func (r *Repository) Unfollow(follower_id, following_id string) error {
+	_, err := r.db.Exec(`delete from user_follow where follower_id = ? and following_id = ?;`, follower_id, following_id)
+	if err != nil {
+		log.Println("1. Unfollow", err)
+		return err
+	}
+
+	return nil
+}
+
+// get all follower of user
+func (r *Repository) GetAllFollower(user_id string, page *util.PageReqType) ([]*FollowUserType, error) {
+	cond, args := page.Query("user_follow.created_at", "user_follow.user_follow_id")
+	query := "select user.user_id as `user_id`, user.user_name as `user_name`, user.photo_profile as `photo_profile`, user_follow.user_follow_id as `user_follow_id`, user_follow.created_at as `created_at` from user_follow inner join user on user_follow.follower_id = user.user_id where user_follow.following_id = ?" + cond + ";"
+
+	return r.getFollowUser("GetAllFollower", query, append([]any{user_id}, args...))
+}
+
+// get all user that followed by user
+func (r *Repository) GetAllFollowing(user_id string, page *util.PageReqType) ([]*FollowUserType, error) {
+	cond, args := page.Query("user_follow.created_at", "user_follow.user_follow_id")
+	query := "select user.user_id as `user_id`, user.user_name as `user_name`, user.photo_profile as `photo_profile`, user_follow.user_follow_id as `user_follow_id`, user_follow.created_at as `created_at` from user_follow inner join user on user_follow.following_id = user.user_id where user_follow.follower_id = ?" + cond + ";"
+
+	return r.getFollowUser("GetAllFollowing", query, append([]any{user_id}, args...))
+}
+
+func (r *Repository) getFollowUser(name, query string, args []any) ([]*FollowUserType, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Println("1.", name, err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []*FollowUserType{}
+	for rows.Next() {
+		u := new(FollowUserType)
+
+		if err := rows.Scan(&u.User_ID, &u.User_Name, &u.Photo_Profile, &u.User_Follow_ID, &u.Created_At); err != nil {
+			log.Println("2.", name, err)
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3.", name, err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// get count follower and following
+func (r *Repository) GetFollowCount(user_id string) (*FollowCountType, error) {
+	count := new(FollowCountType)
+
+	query := "select (select count(*) from user_follow where following_id = ?) as `follower`, (select count(*) from user_follow where follower_id = ?) as `following`;"
+	err := r.db.QueryRow(query, user_id, user_id).Scan(&count.Follower, &count.Following)
+	if err != nil {
+		log.Println("1. GetFollowCount", err)
+		return nil, err
+	}
+
+	return count, nil
+}
+
+// fan out post to timeline of author, friend and follower when post is public
+func (r *Repository) FanOutPost(post *PostType) error {
+	query := `insert ignore into timeline(user_id, post_id, author_id, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, post.User_ID, post.Post_ID, post.User_ID, post.Created_At)
+	if err != nil {
+		log.Println("1. FanOutPost", err)
+		return err
+	}
+
+	if post.Audience == "only_me" {
+		return nil
+	}
+
+	if post.Audience == "custom" {
+		queryCustom := `insert ignore into timeline(user_id, post_id, author_id, created_at) select user_id, ?, ?, ? from post_audience where post_id = ?;`
+		_, err = r.db.Exec(queryCustom, post.Post_ID, post.User_ID, post.Created_At, post.Post_ID)
+		if err != nil {
+			log.Println("4. FanOutPost", err)
+			return err
+		}
+
+		return nil
+	}
+
+	queryFriend := `insert ignore into timeline(user_id, post_id, author_id, created_at) select user_id, ?, ?, ? from user_friend where friend_id = ?;`
+	_, err = r.db.Exec(queryFriend, post.Post_ID, post.User_ID, post.Created_At, post.User_ID)
+	if err != nil {
+		log.Println("2. FanOutPost", err)
+		return err
+	}
+
+	if post.Audience != "public" {
+		return nil
+	}
+
+	queryFollower := `insert ignore into timeline(user_id, post_id, author_id, created_at) select follower_id, ?, ?, ? from user_follow where following_id = ?;`
+	_, err = r.db.Exec(queryFollower, post.Post_ID, post.User_ID, post.Created_At, post.User_ID)
+	if err != nil {
+		log.Println("3. FanOutPost", err)
+		return err
+	}
+
+	return nil
+}
+
+// add post only to timeline of author
+func (r *Repository) InsertOwnTimeline(post *PostType) error {
+	query := `insert ignore into timeline(user_id, post_id, author_id, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, post.User_ID, post.Post_ID, post.User_ID, post.Created_At)
+	if err != nil {
+		log.Println("1. InsertOwnTimeline", err)
+		return err
+	}
+
+	return nil
+}
+
+// rebuild timeline of user for post from single author based on current relation
+func (r *Repository) RebuildTimeline(user_id, author_id string, limit int) error {
+	_, err := r.db.Exec(`delete from timeline where user_id = ? and author_id = ?;`, user_id, author_id)
+	if err != nil {
+		log.Println("1. RebuildTimeline", err)
+		return err
+	}
+
+	query := "insert ignore into timeline(user_id, post_id, author_id, created_at) " +
+		"select ?, post_id, user_id, created_at from post where user_id = ? and type in " + FeedTypes + " " +
+		"and ((audience in ('public', 'friends') and exists (select 1 from user_friend where user_id = ? and friend_id = ?)) " +
+		"or (audience = 'public' and exists (select 1 from user_follow where follower_id = ? and following_id = ?)) " +
+		"or (audience = 'custom' and exists (select 1 from post_audience where post_audience.post_id = post.post_id and post_audience.user_id = ?))) " +
+		"order by created_at desc limit ?;"
+	_, err = r.db.Exec(query, user_id, author_id, user_id, author_id, user_id, author_id, user_id, limit)
+	if err != nil {
+		log.Println("2. RebuildTimeline", err)
+		return err
+	}
+
+	return nil
+}
+
+// get number of friend and follower
+func (r *Repository) GetFanout(user_id string) (int, error) {
+	var number int
+
+	query := "select (select count(*) from user_friend where friend_id = ?) + (select count(*) from user_follow where following_id = ?) as `number`;"
+	err := r.db.QueryRow(query, user_id, user_id).Scan(&number)
+	if err != nil {
+		log.Println("1. GetFanout", err)
+		return -1, err
+	}
+
+	return number, nil
+}
+
+// check is user marked as high fanout
+func (r *Repository) IsHighFanout(user_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from high_fanout_user where user_id = ?;"
+	err := r.db.QueryRow(query, user_id).Scan(&number)
+	if err != nil {
+		log.Println("1. IsHighFanout", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// mark user as high fanout
+func (r *Repository) MarkHighFanout(user_id string) error {
+	_, err := r.db.Exec(`insert ignore into high_fanout_user(user_id, created_at) values (?, ?);`, user_id, time.Now().UTC())
+	if err != nil {
+		log.Println("1. MarkHighFanout", err)
+		return err
+	}
+
+	return nil
+}
+
+// save current revision of post before it is edited
+func (r *Repository) CreatePostHistory(post *GetPostResType) error {
+	images := []string{}
+	for _, val := range post.Images {
+		images = append(images, val.Image)
+	}
+
+	imagesJSON, err := json.Marshal(images)
+	if err != nil {
+		log.Println("1. CreatePostHistory", err)
+		return err
+	}
+
+	query := `insert into post_history(post_history_id, post_id, content, images, created_at) values (?, ?, ?, ?, ?);`
+	_, err = r.db.Exec(query, uuid.New().String(), post.Post_ID, post.Content, string(imagesJSON), time.Now().UTC())
+	if err != nil {
+		log.Println("2. CreatePostHistory", err)
+		return err
+	}
+
+	return nil
+}
+
+// get all prior revision of post
+func (r *Repository) GetPostHistory(post_id string) ([]*Post_HistoryType, error) {
+	query := `select post_history_id, post_id, content, images, created_at from post_history where post_id = ? order by created_at desc;`
+
+	rows, err := r.db.Query(query, post_id)
+	if err != nil {
+		log.Println("1. GetPostHistory", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	histories := []*Post_HistoryType{}
+	for rows.Next() {
+		h := new(Post_HistoryType)
+		var images string
+
+		if err := rows.Scan(&h.Post_History_ID, &h.Post_ID, &h.Content, &images, &h.Created_At); err != nil {
+			log.Println("2. GetPostHistory", err)
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(images), &h.Images); err != nil {
+			log.Println("3. GetPostHistory", err)
+			return nil, err
+		}
+
+		histories = append(histories, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("4. GetPostHistory", err)
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// update content of post
+func (r *Repository) UpdatePost(post_id, content string) error {
+	query := `update post set content = ?, updated_at = ? where post_id = ?;`
+	_, err := r.db.Exec(query, content, time.Now().UTC(), post_id)
+	if err != nil {
+		log.Println("1. UpdatePost", err)
+		return err
+	}
+
+	return nil
+}
+
+// remove all image of post
+func (r *Repository) RemoveImagePost(post_id string) error {
+	_, err := r.db.Exec(`delete from image_post where post_id = ?;`, post_id)
+	if err != nil {
+		log.Println("1. RemoveImagePost", err)
+		return err
+	}
+
+	return nil
+}
+
+// get single image
+func (r *Repository) GetImage(image_post_id string) (*Image_PostType, error) {
+	i := new(Image_PostType)
+
+	query := `select image_post_id, post_id, user_id, image, coalesce(media_id, ''), created_at, updated_at from image_post where image_post_id = ?;`
+	err := r.db.QueryRow(query, image_post_id).Scan(&i.Image_Post_ID, &i.Post_ID, &i.User_ID, &i.Image, &i.Media_ID, &i.Created_At, &i.Updated_At)
+
+	if err == sql.ErrNoRows {
+		log.Println("1. GetImage", err)
+		return nil, fmt.Errorf("image not found")
+	}
+
+	if err != nil {
+		log.Println("2. GetImage", err)
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// remove single image
+func (r *Repository) RemoveImage(image_post_id string) error {
+	_, err := r.db.Exec(`delete from image_post where image_post_id = ?;`, image_post_id)
+	if err != nil {
+		log.Println("1. RemoveImage", err)
+		return err
+	}
+
+	return nil
+}
+
+// get post_id of all comment on post
+func (r *Repository) GetCommentPostID(post_id string) ([]string, error) {
+	rows, err := r.db.Query(`select comment_post_id from comment where post_id = ?;`, post_id)
+	if err != nil {
+		log.Println("1. GetCommentPostID", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+
+		if err := rows.Scan(&id); err != nil {
+			log.Println("2. GetCommentPostID", err)
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetCommentPostID", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// get post_id of all plain repost of post
+func (r *Repository) GetRepostID(post_id string) ([]string, error) {
+	rows, err := r.db.Query(`select post_id from post where original_post_id = ? and type = 'repost';`, post_id)
+	if err != nil {
+		log.Println("1. GetRepostID", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+
+		if err := rows.Scan(&id); err != nil {
+			log.Println("2. GetRepostID", err)
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetRepostID", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// get plain repost of post by user, empty when user hasn't reposted it
+func (r *Repository) GetRepost(post_id, user_id string) (string, error) {
+	var id string
+
+	query := `select post_id from post where original_post_id = ? and user_id = ? and type = 'repost';`
+	err := r.db.QueryRow(query, post_id, user_id).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		log.Println("1. GetRepost", err)
+		return "", err
+	}
+
+	return id, nil
+}
+
+// delete post or comment together with its comment, image, notification and history
+func (r *Repository) DeletePost(post_id string) error {
+	children, err := r.GetCommentPostID(post_id)
+	if err != nil {
+		log.Println("1. DeletePost", err)
+		return err
+	}
+
+	// plain repost go together with the original, quote stay and show original as unavailable
+	reposts, err := r.GetRepostID(post_id)
+	if err != nil {
+		log.Println("2. DeletePost", err)
+		return err
+	}
+
+	for _, child := range append(children, reposts...) {
+		if err := r.DeletePost(child); err != nil {
+			log.Println("3. DeletePost", err)
+			return err
+		}
+	}
+
+	_, err = r.db.Exec(`delete from comment where post_id = ? or comment_post_id = ?;`, post_id, post_id)
+	if err != nil {
+		log.Println("4. DeletePost", err)
+		return err
+	}
+
+	cascade := []string{
+		`delete from image_post where post_id = ?;`,
+		`delete from notification where post_id = ?;`,
+		`delete from timeline where post_id = ?;`,
+		`delete from post_history where post_id = ?;`,
+		`delete from reaction where post_id = ?;`,
+		`delete from post_audience where post_id = ?;`,
+		`delete from post_hashtag where post_id = ?;`,
+		`delete from post_mention where post_id = ?;`,
+		`delete from bookmark where post_id = ?;`,
+		`delete from poll_vote where post_id = ?;`,
+		`delete from poll_ballot where post_id = ?;`,
+		`delete from poll_option where post_id = ?;`,
+		`delete from poll where post_id = ?;`,
+		`delete from report where target_type in ('post', 'comment') and target_id = ?;`,
+		`delete from trending where kind = 'post' and item_id = ?;`,
+		`delete from post where post_id = ?;`,
+	}
+
+	for _, query := range cascade {
+		if _, err := r.db.Exec(query, post_id); err != nil {
+			log.Println("5. DeletePost", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get count of each reaction and reaction of viewer
+func (r *Repository) GetReactionPost(post *GetPostResType, viewer_id string) (*GetPostResType, error) {
+	query := "select type, count(*) as `number` from reaction where post_id = ? group by type;"
+
+	rows, err := r.db.Query(query, post.Post_ID)
+	if err != nil {
+		log.Println("1. GetReactionPost", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	post.Reactions = map[string]int{}
+	for rows.Next() {
+		var reaction string
+		var number int
+
+		if err := rows.Scan(&reaction, &number); err != nil {
+			log.Println("2. GetReactionPost", err)
+			return nil, err
+		}
+
+		post.Reactions[reaction] = number
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetReactionPost", err)
+		return nil, err
+	}
+
+	reaction, err := r.GetReaction(post.Post_ID, viewer_id)
+	if err != nil {
+		log.Println("4. GetReactionPost", err)
+		return nil, err
+	}
+
+	if reaction != nil {
+		post.My_Reaction = reaction.Type
+	}
+
+	return post, nil
+}
+
+// get reaction of user on post, nil when there is none
+func (r *Repository) GetReaction(post_id, user_id string) (*ReactionType, error) {
+	reaction := new(ReactionType)
+
+	query := `select reaction_id, post_id, user_id, type, created_at from reaction where post_id = ? and user_id = ?;`
+	err := r.db.QueryRow(query, post_id, user_id).Scan(&reaction.Reaction_ID, &reaction.Post_ID, &reaction.User_ID, &reaction.Type, &reaction.Created_At)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("1. GetReaction", err)
+		return nil, err
+	}
+
+	return reaction, nil
+}
+
+// create reaction
+func (r *Repository) CreateReaction(reaction *ReactionType) error {
+	reaction.Reaction_ID = uuid.New().String()
+	reaction.Created_At = time.Now().UTC()
+
+	query := `insert into reaction(reaction_id, post_id, user_id, type, created_at) values (?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, reaction.Reaction_ID, reaction.Post_ID, reaction.User_ID, reaction.Type, reaction.Created_At)
+	if err != nil {
+		log.Println("1. CreateReaction", err)
+		return err
+	}
+
+	return nil
+}
+
+// change type of reaction
+func (r *Repository) UpdateReaction(reaction *ReactionType) error {
+	_, err := r.db.Exec(`update reaction set type = ? where reaction_id = ?;`, reaction.Type, reaction.Reaction_ID)
+	if err != nil {
+		log.Println("1. UpdateReaction", err)
+		return err
+	}
+
+	return nil
+}
+
+// remove reaction
+func (r *Repository) RemoveReaction(reaction_id string) error {
+	_, err := r.db.Exec(`delete from reaction where reaction_id = ?;`, reaction_id)
+	if err != nil {
+		log.Println("1. RemoveReaction", err)
+		return err
+	}
+
+	return nil
+}
+
+// get all user who react to post, filtered by type when it is not empty
+func (r *Repository) GetAllReaction(post_id, reaction string, page *util.PageReqType) ([]*ReactionUserType, error) {
+	cond, args := page.Query("reaction.created_at", "reaction.reaction_id")
+	query := "select reaction.reaction_id as `reaction_id`, user.user_id as `user_id`, user.user_name as `user_name`, user.photo_profile as `photo_profile`, reaction.type as `type`, reaction.created_at as `created_at` from reaction inner join user on reaction.user_id = user.user_id where reaction.post_id = ? and (? = '' or reaction.type = ?)" + cond + ";"
+
+	rows, err := r.db.Query(query, append([]any{post_id, reaction, reaction}, args...)...)
+	if err != nil {
+		log.Println("1. GetAllReaction", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []*ReactionUserType{}
+	for rows.Next() {
+		u := new(ReactionUserType)
+
+		if err := rows.Scan(&u.Reaction_ID, &u.User_ID, &u.User_Name, &u.Photo_Profile, &u.Type, &u.Created_At); err != nil {
+			log.Println("2. GetAllReaction", err)
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllReaction", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// condition that viewer can see the post with the alias, the args come from VisibleArgs.
+// post hidden by moderator is not visible to anyone
+func VisibleCond(alias string) string {
+	return "(" + alias + ".hidden = false and (" + alias + ".user_id = ? or " + alias + ".audience = 'public' " +
+		"or (" + alias + ".audience = 'friends' and exists (select 1 from user_friend where user_friend.user_id = ? and user_friend.friend_id = " + alias + ".user_id)) " +
+		"or (" + alias + ".audience = 'custom' and exists (select 1 from post_audience where post_audience.post_id = " + alias + ".post_id and post_audience.user_id = ?))) " +
+		"and not exists (select 1 from user_block where (user_block.user_id = " + alias + ".user_id and user_block.blocked_id = ?) or (user_block.user_id = ? and user_block.blocked_id = " + alias + ".user_id)))"
+}
+
+func VisibleArgs(viewer_id string) []any {
+	return []any{viewer_id, viewer_id, viewer_id, viewer_id, viewer_id}
+}
+
+// type of post that show up in timeline
+const FeedTypes = "('main', 'repost', 'quote')"
+
+// plain repost is only visible when viewer can see the original too, args come from VisibleArgs
+func RepostVisibleCond(alias string) string {
+	return "(" + alias + ".type <> 'repost' or exists (select 1 from post op where op.post_id = " + alias + ".original_post_id and " + VisibleCond("op") + "))"
+}
+
+// check viewer can see post, comment follow audience of its root post
+func (r *Repository) CanViewPost(post_id, viewer_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from post where post.post_id = coalesce((select root_post_id from comment where comment_post_id = ?), ?) and " + VisibleCond("post") + " and " + RepostVisibleCond("post") +
+		" and not exists (select 1 from post hp where hp.post_id = ? and hp.hidden = true);"
+	err := r.db.QueryRow(query, append(append(append([]any{post_id, post_id}, VisibleArgs(viewer_id)...), VisibleArgs(viewer_id)...), post_id)...).Scan(&number)
+	if err != nil {
+		log.Println("1. CanViewPost", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// save list of user that can see post with custom audience
+func (r *Repository) CreatePostAudience(post_id string, user_ids []string) error {
+	query := `insert ignore into post_audience(post_id, user_id) values (?, ?);`
+
+	for _, user_id := range user_ids {
+		if _, err := r.db.Exec(query, post_id, user_id); err != nil {
+			log.Println("1. CreatePostAudience", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// update default audience of new post
+func (r *Repository) UpdateDefaultAudience(user_id, audience string) error {
+	_, err := r.db.Exec(`update user set default_audience = ? where user_id = ?;`, audience, user_id)
+	if err != nil {
+		log.Println("1. UpdateDefaultAudience", err)
+		return err
+	}
+
+	return nil
+}
+
+// find user by mention text, name without space, friend of author come first
+func (r *Repository) GetMentionUser(author_id, text string) (*UserType, error) {
+	u := new(UserType)
+
+	query := "select user.user_id, user.user_name, user.email, user.photo_profile from user " +
+		"where lower(replace(user.user_name, ' ', '')) = lower(?) " +
+		"order by exists (select 1 from user_friend where user_friend.user_id = ? and user_friend.friend_id = user.user_id) desc limit 1;"
+	err := r.db.QueryRow(query, text, author_id).Scan(&u.User_ID, &u.User_Name, &u.Email, &u.Photo_Profile)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Println("1. GetMentionUser", err)
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// get user_id that mentioned in post
+func (r *Repository) GetMentionedUserID(post_id string) (map[string]bool, error) {
+	rows, err := r.db.Query(`select user_id from post_mention where post_id = ?;`, post_id)
+	if err != nil {
+		log.Println("1. GetMentionedUserID", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+
+		if err := rows.Scan(&id); err != nil {
+			log.Println("2. GetMentionedUserID", err)
+			return nil, err
+		}
+
+		ids[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetMentionedUserID", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// replace hashtag and mention of post
+func (r *Repository) SaveEntities(post_id string, entities []*EntityType) error {
+	_, err := r.db.Exec(`delete from post_hashtag where post_id = ?;`, post_id)
+	if err != nil {
+		log.Println("1. SaveEntities", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`delete from post_mention where post_id = ?;`, post_id)
+	if err != nil {
+		log.Println("2. SaveEntities", err)
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, e := range entities {
+		if e.Type == "hashtag" {
+			query := `insert into post_hashtag(post_id, tag, start_index, end_index, created_at) values (?, ?, ?, ?, ?);`
+			if _, err := r.db.Exec(query, post_id, e.Text, e.Start, e.End, now); err != nil {
+				log.Println("3. SaveEntities", err)
+				return err
+			}
+
+			continue
+		}
+
+		query := `insert into post_mention(post_id, user_id, start_index, end_index, created_at) values (?, ?, ?, ?, ?);`
+		if _, err := r.db.Exec(query, post_id, e.User_ID, e.Start, e.End, now); err != nil {
+			log.Println("4. SaveEntities", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get hashtag and mention of post ordered by position
+func (r *Repository) GetEntityPost(post *GetPostResType) (*GetPostResType, error) {
+	query := "select 'hashtag' as `type`, tag as `text`, '' as `user_id`, start_index, end_index from post_hashtag where post_id = ? " +
+		"union all " +
+		"select 'mention' as `type`, user.user_name as `text`, user.user_id as `user_id`, post_mention.start_index, post_mention.end_index from post_mention inner join user on post_mention.user_id = user.user_id where post_mention.post_id = ? " +
+		"order by start_index asc;"
+
+	rows, err := r.db.Query(query, post.Post_ID, post.Post_ID)
+	if err != nil {
+		log.Println("1. GetEntityPost", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	post.Entities = []*EntityType{}
+	for rows.Next() {
+		e := new(EntityType)
+
+		if err := rows.Scan(&e.Type, &e.Text, &e.User_ID, &e.Start, &e.End); err != nil {
+			log.Println("2. GetEntityPost", err)
+			return nil, err
+		}
+
+		post.Entities = append(post.Entities, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetEntityPost", err)
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// get main post with hashtag that viewer can see
+func (r *Repository) GetAllPostByTag(tag, viewer_id string, page *util.PageReqType) ([]*GetPostResType, error) {
+	cond, args := page.Query("post.created_at", "post.post_id")
+	query := "select distinct post.post_id, post.user_id, post.content, post.type, post.created_at, post.updated_at, post.audience, coalesce(post.original_post_id, '') from post_hashtag inner join post on post_hashtag.post_id = post.post_id " +
+		"where post_hashtag.tag = ? and post.type in ('main', 'quote') and " + VisibleCond("post") + cond + ";"
+
+	rows, err := r.db.Query(query, append(append([]any{tag}, VisibleArgs(viewer_id)...), args...)...)
+	if err != nil {
+		log.Println("1. GetAllPostByTag", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	allPost := []*GetPostResType{}
+	for rows.Next() {
+		p := new(GetPostResType)
+
+		if err := rows.Scan(&p.Post_ID, &p.User_ID, &p.Content, &p.Type, &p.Created_At, &p.Updated_At, &p.Audience, &p.Original_Post_ID); err != nil {
+			log.Println("2. GetAllPostByTag", err)
+			return nil, err
+		}
+
+		p, err = r.GetDetailPost(p, viewer_id)
+		if err != nil {
+			log.Println("3. GetAllPostByTag", err)
+			return nil, err
+		}
+
+		allPost = append(allPost, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("4. GetAllPostByTag", err)
+		return nil, err
+	}
+
+	return allPost, nil
+}
+
+// create collection
+func (r *Repository) CreateCollection(collection *CollectionType) error {
+	collection.Collection_ID = uuid.New().String()
+	collection.Created_At = time.Now().UTC()
+
+	query := `insert into collection(collection_id, user_id, name, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, collection.Collection_ID, collection.User_ID, collection.Name, collection.Created_At)
+	if err != nil {
+		log.Println("1. CreateCollection", err)
+		return err
+	}
+
+	return nil
+}
+
+// get collection of user
+func (r *Repository) GetCollection(collection_id, user_id string) (*CollectionType, error) {
+	c := new(CollectionType)
+
+	query := "select collection_id, user_id, name, created_at, (select count(*) from bookmark where bookmark.collection_id = collection.collection_id) as `number_of_post` from collection where collection_id = ? and user_id = ?;"
+	err := r.db.QueryRow(query, collection_id, user_id).Scan(&c.Collection_ID, &c.User_ID, &c.Name, &c.Created_At, &c.Number_Of_Post)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("collection not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetCollection", err)
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// get all collection of user
+func (r *Repository) GetAllCollection(user_id string) ([]*CollectionType, error) {
+	query := "select collection_id, user_id, name, created_at, (select count(*) from bookmark where bookmark.collection_id = collection.collection_id) as `number_of_post` from collection where user_id = ? order by name asc;"
+
+	rows, err := r.db.Query(query, user_id)
+	if err != nil {
+		log.Println("1. GetAllCollection", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	collections := []*CollectionType{}
+	for rows.Next() {
+		c := new(CollectionType)
+
+		if err := rows.Scan(&c.Collection_ID, &c.User_ID, &c.Name, &c.Created_At, &c.Number_Of_Post); err != nil {
+			log.Println("2. GetAllCollection", err)
+			return nil, err
+		}
+
+		collections = append(collections, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllCollection", err)
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// rename collection
+func (r *Repository) RenameCollection(collection_id, user_id, name string) error {
+	_, err := r.db.Exec(`update collection set name = ? where collection_id = ? and user_id = ?;`, name, collection_id, user_id)
+	if err != nil {
+		log.Println("1. RenameCollection", err)
+		return err
+	}
+
+	return nil
+}
+
+// delete collection, post in it stay saved without collection
+func (r *Repository) DeleteCollection(collection_id, user_id string) error {
+	_, err := r.db.Exec(`update bookmark set collection_id = '' where collection_id = ? and user_id = ?;`, collection_id, user_id)
+	if err != nil {
+		log.Println("1. DeleteCollection", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`delete from collection where collection_id = ? and user_id = ?;`, collection_id, user_id)
+	if err != nil {
+		log.Println("2. DeleteCollection", err)
+		return err
+	}
+
+	return nil
+}
+
+// save post, saving again move it to other collection
+func (r *Repository) SavePost(bookmark *BookmarkType) error {
+	bookmark.Bookmark_ID = uuid.New().String()
+	bookmark.Created_At = time.Now().UTC()
+
+	query := `insert into bookmark(bookmark_id, user_id, post_id, collection_id, created_at) values (?, ?, ?, ?, ?) on duplicate key update collection_id = values(collection_id);`
+	_, err := r.db.Exec(query, bookmark.Bookmark_ID, bookmark.User_ID, bookmark.Post_ID, bookmark.Collection_ID, bookmark.Created_At)
+	if err != nil {
+		log.Println("1. SavePost", err)
+		return err
+	}
+
+	return nil
+}
+
+// remove saved post
+func (r *Repository) UnsavePost(post_id, user_id string) error {
+	_, err := r.db.Exec(`delete from bookmark where post_id = ? and user_id = ?;`, post_id, user_id)
+	if err != nil {
+		log.Println("1. UnsavePost", err)
+		return err
+	}
+
+	return nil
+}
+
+// remove saved post that user can't see anymore, e.g. after unfriend or block
+func (r *Repository) PruneBookmark(user_id string) error {
+	query := "delete from bookmark where user_id = ? and not exists (select 1 from post where post.post_id = bookmark.post_id and " + VisibleCond("post") + " and " + RepostVisibleCond("post") + ");"
+
+	_, err := r.db.Exec(query, append(append([]any{user_id}, VisibleArgs(user_id)...), VisibleArgs(user_id)...)...)
+	if err != nil {
+		log.Println("1. PruneBookmark", err)
+		return err
+	}
+
+	return nil
+}
+
+// get saved post of user, all or only in one collection
+func (r *Repository) GetAllSaved(user_id, collection_id string, all bool, page *util.PageReqType) ([]*SavedPostType, error) {
+	cond, args := page.Query("bookmark.created_at", "bookmark.bookmark_id")
+
+	filter := ""
+	queryArgs := []any{user_id}
+	if !all {
+		filter = " and bookmark.collection_id = ?"
+		queryArgs = append(queryArgs, collection_id)
+	}
+
+	query := "select bookmark.bookmark_id, bookmark.collection_id, bookmark.created_at, post.post_id, post.user_id, post.content, post.type, post.created_at, post.updated_at, post.audience, coalesce(post.original_post_id, '') " +
+		"from bookmark inner join post on bookmark.post_id = post.post_id where bookmark.user_id = ?" + filter + " and " + VisibleCond("post") + " and " + RepostVisibleCond("post") + cond + ";"
+
+	queryArgs = append(append(append(queryArgs, VisibleArgs(user_id)...), VisibleArgs(user_id)...), args...)
+
+	rows, err := r.db.Query(query, queryArgs...)
+	if err != nil {
+		log.Println("1. GetAllSaved", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	saved := []*SavedPostType{}
+	for rows.Next() {
+		s := &SavedPostType{Post: new(GetPostResType)}
+		p := s.Post
+
+		if err := rows.Scan(&s.Bookmark_ID, &s.Collection_ID, &s.Saved_At, &p.Post_ID, &p.User_ID, &p.Content, &p.Type, &p.Created_At, &p.Updated_At, &p.Audience, &p.Original_Post_ID); err != nil {
+			log.Println("2. GetAllSaved", err)
+			return nil, err
+		}
+
+		s.Post, err = r.GetDetailPost(p, user_id)
+		if err != nil {
+			log.Println("3. GetAllSaved", err)
+			return nil, err
+		}
+
+		saved = append(saved, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("4. GetAllSaved", err)
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+const draftColumn = "draft_id, user_id, content, images, audience, audience_list, status, publish_at, post_id, coalesce(poll, 'null'), error, created_at, updated_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDraft(row rowScanner) (*DraftType, error) {
+	d := new(DraftType)
+
+	var images, audienceList, poll string
+	var publishAt sql.NullTime
+	if err := row.Scan(&d.Draft_ID, &d.User_ID, &d.Content, &images, &d.Audience, &audienceList, &d.Status, &publishAt, &d.Post_ID, &poll, &d.Error, &d.Created_At, &d.Updated_At); err != nil {
+		return nil, err
+	}
+
+	if publishAt.Valid {
+		d.Publish_At = &publishAt.Time
+	}
+
+	if err := json.Unmarshal([]byte(images), &d.Images); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(audienceList), &d.Audience_List); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(poll), &d.Poll); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// create draft and reserve post_id it is published with
+func (r *Repository) CreateDraft(d *DraftType) error {
+	d.Draft_ID = uuid.New().String()
+	d.Post_ID = uuid.New().String()
+	d.Created_At = time.Now().UTC()
+	d.Updated_At = d.Created_At
+
+	images, err := json.Marshal(d.Images)
+	if err != nil {
+		log.Println("1. CreateDraft", err)
+		return err
+	}
+
+	audienceList, err := json.Marshal(d.Audience_List)
+	if err != nil {
+		log.Println("2. CreateDraft", err)
+		return err
+	}
+
+	poll, err := json.Marshal(d.Poll)
+	if err != nil {
+		log.Println("3. CreateDraft", err)
+		return err
+	}
+
+	query := `insert into draft(draft_id, user_id, content, images, audience, audience_list, status, publish_at, post_id, poll, created_at, updated_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err = r.db.Exec(query, d.Draft_ID, d.User_ID, d.Content, string(images), d.Audience, string(audienceList), d.Status, d.Publish_At, d.Post_ID, string(poll), d.Created_At, d.Updated_At)
+	if err != nil {
+		log.Println("4. CreateDraft", err)
+		return err
+	}
+
+	return nil
+}
+
+// get draft of user
+func (r *Repository) GetDraft(draft_id, user_id string) (*DraftType, error) {
+	query := "select " + draftColumn + " from draft where draft_id = ? and user_id = ?;"
+
+	d, err := scanDraft(r.db.QueryRow(query, draft_id, user_id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("draft not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetDraft", err)
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// update draft that is not being published
+func (r *Repository) UpdateDraft(d *DraftType) error {
+	d.Updated_At = time.Now().UTC()
+
+	images, err := json.Marshal(d.Images)
+	if err != nil {
+		log.Println("1. UpdateDraft", err)
+		return err
+	}
+
+	audienceList, err := json.Marshal(d.Audience_List)
+	if err != nil {
+		log.Println("2. UpdateDraft", err)
+		return err
+	}
+
+	poll, err := json.Marshal(d.Poll)
+	if err != nil {
+		log.Println("3. UpdateDraft", err)
+		return err
+	}
+
+	query := `update draft set content = ?, images = ?, audience = ?, audience_list = ?, status = ?, publish_at = ?, poll = ?, error = '', updated_at = ? where draft_id = ? and user_id = ? and status in ('draft', 'scheduled', 'failed');`
+	res, err := r.db.Exec(query, d.Content, string(images), d.Audience, string(audienceList), d.Status, d.Publish_At, string(poll), d.Updated_At, d.Draft_ID, d.User_ID)
+	if err != nil {
+		log.Println("4. UpdateDraft", err)
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("draft can't be changed")
+	}
+
+	return nil
+}
+
+// delete draft that is not being published
+func (r *Repository) DeleteDraft(draft_id, user_id string) error {
+	res, err := r.db.Exec(`delete from draft where draft_id = ? and user_id = ? and status in ('draft', 'scheduled', 'failed');`, draft_id, user_id)
+	if err != nil {
+		log.Println("1. DeleteDraft", err)
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("draft not found")
+	}
+
+	return nil
+}
+
+// move scheduled draft back to draft
+func (r *Repository) CancelScheduled(draft_id, user_id string) error {
+	query := `update draft set status = 'draft', publish_at = null, error = '', updated_at = ? where draft_id = ? and user_id = ? and status in ('scheduled', 'failed');`
+	res, err := r.db.Exec(query, time.Now().UTC(), draft_id, user_id)
+	if err != nil {
+		log.Println("1. CancelScheduled", err)
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("scheduled post not found")
+	}
+
+	return nil
+}
+
+// get all draft of user with the status, keyed on keyCol
+func (r *Repository) GetAllDraft(user_id string, status []string, keyCol string, page *util.PageReqType) ([]*DraftType, error) {
+	cond, args := page.Query(keyCol, "draft_id")
+
+	in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(status)), ", ") + ")"
+	queryArgs := []any{user_id}
+	for _, s := range status {
+		queryArgs = append(queryArgs, s)
+	}
+
+	query := "select " + draftColumn + " from draft where user_id = ? and status in " + in + cond + ";"
+
+	rows, err := r.db.Query(query, append(queryArgs, args...)...)
+	if err != nil {
+		log.Println("1. GetAllDraft", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	drafts := []*DraftType{}
+	for rows.Next() {
+		d, err := scanDraft(rows)
+		if err != nil {
+			log.Println("2. GetAllDraft", err)
+			return nil, err
+		}
+
+		drafts = append(drafts, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllDraft", err)
+		return nil, err
+	}
+
+	return drafts, nil
+}
+
+// ClaimDraft mark draft as being published by instance. Only one caller can win the claim
+// because the update check the status again, false mean other instance already took it.
+func (r *Repository) ClaimDraft(draft_id, user_id, instance string) (bool, error) {
+	query := `update draft set status = 'publishing', claimed_by = ?, claimed_at = ? where draft_id = ? and user_id = ? and status in ('draft', 'scheduled', 'failed');`
+	res, err := r.db.Exec(query, instance, time.Now().UTC(), draft_id, user_id)
+	if err != nil {
+		log.Println("1. ClaimDraft", err)
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Println("2. ClaimDraft", err)
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// ClaimDueDraft claim scheduled draft that is due, and draft that stuck in publishing
+// longer than stale because the instance that claimed it died
+func (r *Repository) ClaimDueDraft(instance string, now, stale time.Time, limit int) ([]*DraftType, error) {
+	due := "((status = 'scheduled' and publish_at <= ?) or (status = 'publishing' and claimed_at < ?))"
+
+	rows, err := r.db.Query("select draft_id from draft where "+due+" order by publish_at asc limit ?;", now, stale, limit)
+	if err != nil {
+		log.Println("1. ClaimDueDraft", err)
+		return nil, err
+	}
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Println("2. ClaimDueDraft", err)
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. ClaimDueDraft", err)
+		return nil, err
+	}
+
+	drafts := []*DraftType{}
+	for _, id := range ids {
+		res, err := r.db.Exec("update draft set status = 'publishing', claimed_by = ?, claimed_at = ? where draft_id = ? and "+due+";", instance, now, id, now, stale)
+		if err != nil {
+			log.Println("4. ClaimDueDraft", err)
+			return nil, err
+		}
+
+		if n, _ := res.RowsAffected(); n != 1 {
+			continue
+		}
+
+		d, err := scanDraft(r.db.QueryRow("select "+draftColumn+" from draft where draft_id = ?;", id))
+		if err != nil {
+			log.Println("5. ClaimDueDraft", err)
+			return nil, err
+		}
+
+		drafts = append(drafts, d)
+	}
+
+	return drafts, nil
+}
+
+// set final status of draft after publishing
+func (r *Repository) FinishDraft(draft_id, status, message string) error {
+	_, err := r.db.Exec(`update draft set status = ?, error = ?, updated_at = ? where draft_id = ?;`, status, message, time.Now().UTC(), draft_id)
+	if err != nil {
+		log.Println("1. FinishDraft", err)
+		return err
+	}
+
+	return nil
+}
+
+// check post already exist
+func (r *Repository) PostExists(post_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from post where post_id = ?;"
+	err := r.db.QueryRow(query, post_id).Scan(&number)
+	if err != nil {
+		log.Println("1. PostExists", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
+
+// create poll and its option for post
+func (r *Repository) CreatePoll(post_id string, poll *PollReqType) error {
+	query := `insert into poll(post_id, multiple, closes_at, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, post_id, poll.Multiple, poll.Closes_At, time.Now().UTC())
+	if err != nil {
+		log.Println("1. CreatePoll", err)
+		return err
+	}
+
+	for i, text := range poll.Options {
+		queryOption := `insert into poll_option(option_id, post_id, text, position) values (?, ?, ?, ?);`
+		_, err := r.db.Exec(queryOption, uuid.New().String(), post_id, text, i)
+		if err != nil {
+			log.Println("2. CreatePoll", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get poll of post with current result and vote of viewer, post without poll is left as is
+func (r *Repository) GetPollPost(post *GetPostResType, viewer_id string) (*GetPostResType, error) {
+	poll := &PollType{Post_ID: post.Post_ID, Options: []*PollOptionType{}, My_Votes: []string{}}
+
+	var closesAt sql.NullTime
+	query := "select multiple, closes_at, (select count(distinct user_id) from poll_vote where poll_vote.post_id = poll.post_id) as `total_voter` from poll where post_id = ?;"
+	err := r.db.QueryRow(query, post.Post_ID).Scan(&poll.Multiple, &closesAt, &poll.Total_Voter)
+	if err == sql.ErrNoRows {
+		return post, nil
+	}
+
+	if err != nil {
+		log.Println("1. GetPollPost", err)
+		return nil, err
+	}
+
+	if closesAt.Valid {
+		poll.Closes_At = &closesAt.Time
+		poll.Closed = !closesAt.Time.After(time.Now())
+	}
+
+	queryOption := "select option_id, text, (select count(*) from poll_vote where poll_vote.option_id = poll_option.option_id) as `votes` from poll_option where post_id = ? order by position asc;"
+	rows, err := r.db.Query(queryOption, post.Post_ID)
+	if err != nil {
+		log.Println("2. GetPollPost", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		o := new(PollOptionType)
+
+		if err := rows.Scan(&o.Option_ID, &o.Text, &o.Votes); err != nil {
+			log.Println("3. GetPollPost", err)
+			return nil, err
+		}
+
+		poll.Options = append(poll.Options, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("4. GetPollPost", err)
+		return nil, err
+	}
+
+	voteRows, err := r.db.Query(`select option_id from poll_vote where post_id = ? and user_id = ?;`, post.Post_ID, viewer_id)
+	if err != nil {
+		log.Println("5. GetPollPost", err)
+		return nil, err
+	}
+
+	defer voteRows.Close()
+
+	for voteRows.Next() {
+		var id string
+
+		if err := voteRows.Scan(&id); err != nil {
+			log.Println("6. GetPollPost", err)
+			return nil, err
+		}
+
+		poll.My_Votes = append(poll.My_Votes, id)
+	}
+
+	if err := voteRows.Err(); err != nil {
+		log.Println("7. GetPollPost", err)
+		return nil, err
+	}
+
+	post.Poll = poll
+
+	return post, nil
+}
+
+// create ballot of voter, false when the voter already voted on the poll
+func (r *Repository) CreateBallot(post_id, user_id string) (bool, error) {
+	query := `insert ignore into poll_ballot(post_id, user_id, created_at) values (?, ?, ?);`
+	res, err := r.db.Exec(query, post_id, user_id, time.Now().UTC())
+	if err != nil {
+		log.Println("1. CreateBallot", err)
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Println("2. CreateBallot", err)
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// create vote, false when the unique key already has the vote
+func (r *Repository) CreateVote(post_id, option_id, user_id, slot string) (bool, error) {
+	query := `insert ignore into poll_vote(post_id, option_id, user_id, slot, created_at) values (?, ?, ?, ?, ?);`
+	res, err := r.db.Exec(query, post_id, option_id, user_id, slot, time.Now().UTC())
+	if err != nil {
+		log.Println("1. CreateVote", err)
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Println("2. CreateVote", err)
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// ClaimClosedPoll get poll that closed and not notified yet, each poll is only
+// returned once even when more than one instance call it
+func (r *Repository) ClaimClosedPoll(now time.Time, limit int) ([]*PostType, error) {
+	query := `select poll.post_id, post.user_id from poll inner join post on poll.post_id = post.post_id where poll.closed_notified = false and poll.closes_at <= ? limit ?;`
+
+	rows, err := r.db.Query(query, now, limit)
+	if err != nil {
+		log.Println("1. ClaimClosedPoll", err)
+		return nil, err
+	}
+
+	due := []*PostType{}
+	for rows.Next() {
+		p := new(PostType)
+
+		if err := rows.Scan(&p.Post_ID, &p.User_ID); err != nil {
+			rows.Close()
+			log.Println("2. ClaimClosedPoll", err)
+			return nil, err
+		}
+
+		due = append(due, p)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. ClaimClosedPoll", err)
+		return nil, err
+	}
+
+	claimed := []*PostType{}
+	for _, p := range due {
+		res, err := r.db.Exec(`update poll set closed_notified = true where post_id = ? and closed_notified = false;`, p.Post_ID)
+		if err != nil {
+			log.Println("4. ClaimClosedPoll", err)
+			return nil, err
+		}
+
+		if n, _ := res.RowsAffected(); n == 1 {
+			claimed = append(claimed, p)
+		}
+	}
+
+	return claimed, nil
+}
+
+// album of alias is visible to its owner, everyone when public and friend when friends, never across block.
+// args come from AlbumVisibleArgs
+func AlbumVisibleCond(alias string) string {
+	return "((" + alias + ".user_id = ? or " + alias + ".audience = 'public' " +
+		"or (" + alias + ".audience = 'friends' and exists (select 1 from user_friend where user_friend.user_id = ? and user_friend.friend_id = " + alias + ".user_id))) " +
+		"and not exists (select 1 from user_block where (user_block.user_id = " + alias + ".user_id and user_block.blocked_id = ?) or (user_block.user_id = ? and user_block.blocked_id = " + alias + ".user_id)))"
+}
+
+func AlbumVisibleArgs(viewer_id string) []any {
+	return []any{viewer_id, viewer_id, viewer_id, viewer_id}
+}
+
+// photo of album with the first post that show it and viewer can see, args come from VisibleArgs.
+// post photo without such post is not visible, see photoVisible
+const albumPhotoSelect = "select album_photo.album_id as album_id, album_photo.user_id as user_id, album_photo.image as image, album_photo.media_id as media_id, album_photo.source as source, album_photo.created_at as created_at, " +
+	"coalesce((select ip.post_id from image_post ip inner join post on post.post_id = coalesce((select root_post_id from comment where comment.comment_post_id = ip.post_id), ip.post_id) " +
+	"where ip.user_id = album_photo.user_id and ip.image = album_photo.image and %s order by ip.created_at asc limit 1), '') as post_id from album_photo where album_photo.album_id = ?"
+
+const photoVisible = "(p.source <> 'post' or p.post_id <> '')"
+
+func albumPhotoQuery() string {
+	return fmt.Sprintf(albumPhotoSelect, VisibleCond("post"))
+}
+
+// get auto album of user, create it the first time
+func (r *Repository) GetAutoAlbum(user_id, auto_key string) (string, error) {
+	now := time.Now().UTC()
+
+	query := `insert ignore into album(album_id, user_id, name, auto_key, audience, created_at, updated_at) values (?, ?, ?, ?, 'public', ?, ?);`
+	_, err := r.db.Exec(query, uuid.New().String(), user_id, AutoAlbums[auto_key], auto_key, now, now)
+	if err != nil {
+		log.Println("1. GetAutoAlbum", err)
+		return "", err
+	}
+
+	var album_id string
+	err = r.db.QueryRow(`select album_id from album where user_id = ? and auto_key = ?;`, user_id, auto_key).Scan(&album_id)
+	if err != nil {
+		log.Println("2. GetAutoAlbum", err)
+		return "", err
+	}
+
+	return album_id, nil
+}
+
+// put new image to auto album of its source, image already in some album stay there
+func (r *Repository) AddAlbumPhoto(user_id, image, media_id, source string) error {
+	album_id, err := r.GetAutoAlbum(user_id, source)
+	if err != nil {
+		log.Println("1. AddAlbumPhoto", err)
+		return err
+	}
+
+	query := `insert ignore into album_photo(user_id, image, album_id, media_id, source, created_at) values (?, ?, ?, ?, ?, ?);`
+	_, err = r.db.Exec(query, user_id, image, album_id, media_id, source, time.Now().UTC())
+	if err != nil {
+		log.Println("2. AddAlbumPhoto", err)
+		return err
+	}
+
+	return nil
+}
+
+// remove post photo that no post of the user has anymore, and cover that is gone
+func (r *Repository) PruneAlbumPhoto(user_id string) error {
+	query := `delete from album_photo where user_id = ? and source = 'post' and not exists (select 1 from image_post where image_post.user_id = album_photo.user_id and image_post.image = album_photo.image);`
+	if _, err := r.db.Exec(query, user_id); err != nil {
+		log.Println("1. PruneAlbumPhoto", err)
+		return err
+	}
+
+	query = `update album set cover_image = '' where user_id = ? and cover_image <> '' and not exists (select 1 from album_photo where album_photo.album_id = album.album_id and album_photo.image = album.cover_image);`
+	if _, err := r.db.Exec(query, user_id); err != nil {
+		log.Println("2. PruneAlbumPhoto", err)
+		return err
+	}
+
+	return nil
+}
+
+// create album made by user
+func (r *Repository) CreateAlbum(album *AlbumType) error {
+	album.Album_ID = uuid.New().String()
+	album.Created_At = time.Now().UTC()
+	album.Updated_At = album.Created_At
+
+	query := `insert into album(album_id, user_id, name, audience, created_at, updated_at) values (?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, album.Album_ID, album.User_ID, album.Name, album.Audience, album.Created_At, album.Updated_At)
+	if err != nil {
+		log.Println("1. CreateAlbum", err)
+		return err
+	}
+
+	return nil
+}
+
+// get album that viewer can see
+func (r *Repository) GetAlbum(album_id, viewer_id string) (*AlbumType, error) {
+	a := new(AlbumType)
+
+	query := "select album.album_id, album.user_id, album.name, coalesce(album.auto_key, ''), album.audience, album.cover_image, album.created_at, album.updated_at from album where album.album_id = ? and " + AlbumVisibleCond("album") + ";"
+	err := r.db.QueryRow(query, append([]any{album_id}, AlbumVisibleArgs(viewer_id)...)...).Scan(&a.Album_ID, &a.User_ID, &a.Name, &a.Auto_Key, &a.Audience, &a.Cover_Image, &a.Created_At, &a.Updated_At)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("album not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetAlbum", err)
+		return nil, err
+	}
+
+	if err := r.GetAlbumSummary(a, viewer_id); err != nil {
+		log.Println("2. GetAlbum", err)
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// get album of user that viewer can see
+func (r *Repository) GetAllAlbum(user_id, viewer_id string, page *util.PageReqType) ([]*AlbumType, error) {
+	cond, args := page.Query("album.created_at", "album.album_id")
+	query := "select album.album_id, album.user_id, album.name, coalesce(album.auto_key, ''), album.audience, album.cover_image, album.created_at, album.updated_at from album where album.user_id = ? and " + AlbumVisibleCond("album") + cond + ";"
+
+	rows, err := r.db.Query(query, append(append([]any{user_id}, AlbumVisibleArgs(viewer_id)...), args...)...)
+	if err != nil {
+		log.Println("1. GetAllAlbum", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	albums := []*AlbumType{}
+	for rows.Next() {
+		a := new(AlbumType)
+
+		if err := rows.Scan(&a.Album_ID, &a.User_ID, &a.Name, &a.Auto_Key, &a.Audience, &a.Cover_Image, &a.Created_At, &a.Updated_At); err != nil {
+			log.Println("2. GetAllAlbum", err)
+			return nil, err
+		}
+
+		albums = append(albums, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllAlbum", err)
+		return nil, err
+	}
+
+	for _, a := range albums {
+		if err := r.GetAlbumSummary(a, viewer_id); err != nil {
+			log.Println("4. GetAllAlbum", err)
+			return nil, err
+		}
+	}
+
+	return albums, nil
+}
+
+// fill number of photo and cover that viewer can see, chosen cover first and the latest photo otherwise
+func (r *Repository) GetAlbumSummary(a *AlbumType, viewer_id string) error {
+	query := "select count(*) as `number` from (" + albumPhotoQuery() + ") p where " + photoVisible + ";"
+	err := r.db.QueryRow(query, append(VisibleArgs(viewer_id), a.Album_ID)...).Scan(&a.Number_Of_Photo)
+	if err != nil {
+		log.Println("1. GetAlbumSummary", err)
+		return err
+	}
+
+	if a.Number_Of_Photo == 0 {
+		a.Cover_Image = ""
+		return nil
+	}
+
+	query = "select p.image from (" + albumPhotoQuery() + ") p where " + photoVisible + " order by p.image = ? desc, p.created_at desc limit 1;"
+	err = r.db.QueryRow(query, append(VisibleArgs(viewer_id), a.Album_ID, a.Cover_Image)...).Scan(&a.Cover_Image)
+	if err != nil {
+		log.Println("2. GetAlbumSummary", err)
+		return err
+	}
+
+	return nil
+}
+
+// get photo of album that viewer can see, album must be checked with GetAlbum first
+func (r *Repository) GetAllAlbumPhoto(album_id, viewer_id string, page *util.PageReqType) ([]*AlbumPhotoType, error) {
+	cond, args := page.Query("p.created_at", "p.image")
+	query := "select p.album_id, p.user_id, p.image, p.media_id, p.source, p.post_id, p.created_at from (" + albumPhotoQuery() + ") p where " + photoVisible + cond + ";"
+
+	rows, err := r.db.Query(query, append(append(VisibleArgs(viewer_id), album_id), args...)...)
+	if err != nil {
+		log.Println("1. GetAllAlbumPhoto", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	photos := []*AlbumPhotoType{}
+	images := []*Image_PostType{}
+	for rows.Next() {
+		p := new(AlbumPhotoType)
+
+		if err := rows.Scan(&p.Album_ID, &p.User_ID, &p.Image, &p.Media_ID, &p.Source, &p.Post_ID, &p.Created_At); err != nil {
+			log.Println("2. GetAllAlbumPhoto", err)
+			return nil, err
+		}
+
+		p.Updated_At = p.Created_At
+		photos = append(photos, p)
+		images = append(images, &p.Image_PostType)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAllAlbumPhoto", err)
+		return nil, err
+	}
+
+	if err := r.GetImageMedia(images); err != nil {
+		log.Println("4. GetAllAlbumPhoto", err)
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// get album of the owner
+func (r *Repository) GetOwnAlbum(album_id, user_id string) (*AlbumType, error) {
+	a := new(AlbumType)
+
+	query := `select album_id, user_id, name, coalesce(auto_key, ''), audience, cover_image, created_at, updated_at from album where album_id = ? and user_id = ?;`
+	err := r.db.QueryRow(query, album_id, user_id).Scan(&a.Album_ID, &a.User_ID, &a.Name, &a.Auto_Key, &a.Audience, &a.Cover_Image, &a.Created_At, &a.Updated_At)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("album not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetOwnAlbum", err)
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// update name and audience of album
+func (r *Repository) UpdateAlbum(album *AlbumType) error {
+	query := `update album set name = ?, audience = ?, updated_at = ? where album_id = ? and user_id = ?;`
+	_, err := r.db.Exec(query, album.Name, album.Audience, time.Now().UTC(), album.Album_ID, album.User_ID)
+	if err != nil {
+		log.Println("1. UpdateAlbum", err)
+		return err
+	}
+
+	return nil
+}
+
+// get album where photo of user is
+func (r *Repository) GetPhotoAlbum(user_id, image string) (string, error) {
+	var album_id string
+
+	err := r.db.QueryRow(`select album_id from album_photo where user_id = ? and image = ?;`, user_id, image).Scan(&album_id)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("photo not found")
+	}
+
+	if err != nil {
+		log.Println("1. GetPhotoAlbum", err)
+		return "", err
+	}
+
+	return album_id, nil
+}
+
+// move photo to other album of the same user, album that had it as cover go back to the latest photo
+func (r *Repository) MoveAlbumPhoto(user_id, image, album_id string) error {
+	_, err := r.db.Exec(`update album_photo set album_id = ? where user_id = ? and image = ?;`, album_id, user_id, image)
+	if err != nil {
+		log.Println("1. MoveAlbumPhoto", err)
+		return err
+	}
+
+	_, err = r.db.Exec(`update album set cover_image = '' where user_id = ? and cover_image = ? and album_id <> ?;`, user_id, image, album_id)
+	if err != nil {
+		log.Println("2. MoveAlbumPhoto", err)
+		return err
+	}
+
+	return nil
+}
+
+// set cover of album, empty image use the latest photo
+func (r *Repository) SetAlbumCover(album_id, user_id, image string) error {
+	_, err := r.db.Exec(`update album set cover_image = ?, updated_at = ? where album_id = ? and user_id = ?;`, image, time.Now().UTC(), album_id, user_id)
+	if err != nil {
+		log.Println("1. SetAlbumCover", err)
+		return err
+	}
+
+	return nil
+}
+
+// source of photo inside album
+func (r *Repository) GetAlbumSource(album_id string) ([]string, error) {
+	rows, err := r.db.Query(`select distinct source from album_photo where album_id = ?;`, album_id)
+	if err != nil {
+		log.Println("1. GetAlbumSource", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sources := []string{}
+	for rows.Next() {
+		var source string
+
+		if err := rows.Scan(&source); err != nil {
+			log.Println("2. GetAlbumSource", err)
+			return nil, err
+		}
+
+		sources = append(sources, source)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("3. GetAlbumSource", err)
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// move photo of one source from album to other album
+func (r *Repository) MoveAllAlbumPhoto(from_id, source, to_id string) error {
+	_, err := r.db.Exec(`update album_photo set album_id = ? where album_id = ? and source = ?;`, to_id, from_id, source)
+	if err != nil {
+		log.Println("1. MoveAllAlbumPhoto", err)
+		return err
+	}
+
+	return nil
+}
+
+// delete album made by user, photo must be moved out first
+func (r *Repository) DeleteAlbum(album_id, user_id string) error {
+	_, err := r.db.Exec(`delete from album where album_id = ? and user_id = ? and auto_key is null;`, album_id, user_id)
+	if err != nil {
+		log.Println("1. DeleteAlbum", err)
+		return err
+	}
+
+	return nil
 }
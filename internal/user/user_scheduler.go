@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// draft stuck in publishing longer than this is claimed again
+const DraftClaimTimeout = time.Duration(5) * time.Minute
+
+// maximum draft published per tick
+const draftBatch = 100
+
+// Scheduler publish scheduled draft when it is due and close poll. Every server instance can run one,
+// the claim in ClaimDueDraft and ClaimClosedPoll make sure each item is only handled by single instance.
+type Scheduler struct {
+	Handler  *Handler
+	instance string
+	interval time.Duration
+}
+
+func NewScheduler(h *Handler) *Scheduler {
+	return &Scheduler{
+		Handler:  h,
+		instance: uuid.New().String(),
+		interval: time.Duration(15) * time.Second,
+	}
+}
+
+// check for due draft every interval until context is done
+func (s *Scheduler) Run(c context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if err := s.PublishDue(); err != nil {
+				log.Println("1. Run", err)
+			}
+
+			if err := s.ClosePoll(); err != nil {
+				log.Println("2. Run", err)
+			}
+		}
+	}
+}
+
+// publish every draft that is due
+func (s *Scheduler) PublishDue() error {
+	now := time.Now().UTC()
+
+	drafts, err := s.Handler.Repository.ClaimDueDraft(s.instance, now, now.Add(-DraftClaimTimeout), draftBatch)
+	if err != nil {
+		log.Println("1. PublishDue", err)
+		return err
+	}
+
+	for _, d := range drafts {
+		// one broken draft must not stop the others
+		if err := s.Handler.publishDraft(d); err != nil {
+			log.Println("2. PublishDue", err)
+		}
+	}
+
+	return nil
+}
+
+// notify author of every poll that closed since last tick
+func (s *Scheduler) ClosePoll() error {
+	polls, err := s.Handler.Repository.ClaimClosedPoll(time.Now().UTC(), draftBatch)
+	if err != nil {
+		log.Println("1. ClosePoll", err)
+		return err
+	}
+
+	for _, p := range polls {
+		if err := s.Handler.notifyClosedPoll(p); err != nil {
+			log.Println("2. ClosePoll", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package user
+
+import "log"
+
+// author with more friend and follower than this are not fan out on write,
+// their post are fan in when the timeline is read
+const HighFanoutThreshold = 1000
+
+// number of post per author copied when relation between user change
+const TimelineBackfillLimit = 200
+
+type Timeline struct {
+	Repository *Repository
+	threshold  int
+}
+
+func NewTimeline(r *Repository) *Timeline {
+	return &Timeline{
+		Repository: r,
+		threshold:  HighFanoutThreshold,
+	}
+}
+
+// same timeline on other repository, e.g. one in transaction
+func (t *Timeline) WithRepository(r *Repository) *Timeline {
+	return &Timeline{
+		Repository: r,
+		threshold:  t.threshold,
+	}
+}
+
+// distribute new main post to timeline
+func (t *Timeline) FanOut(post *PostType) error {
+	high, err := t.Repository.IsHighFanout(post.User_ID)
+	if err != nil {
+		log.Println("1. FanOut", err)
+		return err
+	}
+
+	if !high {
+		number, err := t.Repository.GetFanout(post.User_ID)
+		if err != nil {
+			log.Println("2. FanOut", err)
+			return err
+		}
+
+		// once user become high fanout it stay that way, so post that
+		// never fan out will not disappear from timeline
+		if number >= t.threshold {
+			if err := t.Repository.MarkHighFanout(post.User_ID); err != nil {
+				log.Println("3. FanOut", err)
+				return err
+			}
+
+			high = true
+		}
+	}
+
+	if high {
+		return t.Repository.InsertOwnTimeline(post)
+	}
+
+	return t.Repository.FanOutPost(post)
+}
+
+// rebuild timeline of both user after friend or follow relation change
+func (t *Timeline) Rebuild(user_id, other_id string) error {
+	if err := t.Repository.RebuildTimeline(user_id, other_id, TimelineBackfillLimit); err != nil {
+		log.Println("1. Rebuild", err)
+		return err
+	}
+
+	if err := t.Repository.RebuildTimeline(other_id, user_id, TimelineBackfillLimit); err != nil {
+		log.Println("2. Rebuild", err)
+		return err
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/erlnerlngga/backend-socius/internal/filter"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -32,6 +33,7 @@ type CreateNewRoomType struct {
 type Client struct {
 	Conn      *websocket.Conn
 	Message   chan *MessageType
+	Errors    chan *ErrorFrameType
 	Client_ID string `json:"client_id"`
 	User_ID   string `json:"user_id"`
 	Room_ID   string `json:"room_id"`
@@ -60,6 +62,13 @@ type MessageType struct {
 	Updated_At    time.Time `json:"updated_at"`
 }
 
+// frame sent to the sender only when message is not accepted, type is always "error"
+type ErrorFrameType struct {
+	Type      string                `json:"type"`
+	Error     string                `json:"error"`
+	Rejection *filter.RejectionType `json:"rejection,omitempty"`
+}
+
 type LogType struct {
 	Log_ID     string    `json:"log_id"`
 	Client_ID  string    `json:"client_id"`
@@ -74,12 +83,27 @@ func (c *Client) writeMessage() {
 	}()
 
 	for {
-		message, ok := <-c.Message
-		if !ok {
-			return
+		select {
+		case message, ok := <-c.Message:
+			if !ok {
+				return
+			}
+
+			c.Conn.WriteJSON(message)
+
+		case frame := <-c.Errors:
+			c.Conn.WriteJSON(frame)
 		}
+	}
+}
 
-		c.Conn.WriteJSON(message)
+// send error frame without blocking read loop, dropped when the buffer is full
+func (c *Client) sendError(frame *ErrorFrameType) {
+	frame.Type = "error"
+
+	select {
+	case c.Errors <- frame:
+	default:
 	}
 }
 
@@ -100,12 +124,37 @@ func (c *Client) readMessage(hub *Hub) {
 			break
 		}
 
+		// suspended user can still read but the message is dropped
+		suspended, err := hub.IsSuspended(c.User_ID)
+		if err != nil {
+			log.Println("2. readMessage", err)
+			c.sendError(&ErrorFrameType{Error: "message not sent"})
+			continue
+		}
+
+		if suspended {
+			c.sendError(&ErrorFrameType{Error: "account is suspended"})
+			continue
+		}
+
+		content := &filter.ContentType{
+			User_ID: c.User_ID,
+			Kind:    "message",
+			Text:    string(m),
+		}
+
+		if err := hub.Filter.Apply(content); err != nil {
+			log.Println("3. readMessage", err)
+			c.sendError(&ErrorFrameType{Error: err.Error(), Rejection: filter.AsRejection(err)})
+			continue
+		}
+
 		msg := &MessageType{
 			Message_ID: uuid.New().String(),
 			Room_ID:    c.Room_ID,
 			User_ID:    c.User_ID,
 			Client_ID:  c.Client_ID,
-			Content:    string(m),
+			Content:    content.Text,
 			Created_At: time.Now().UTC(),
 			Updated_At: time.Now().UTC(),
 		}
@@ -2,21 +2,26 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/erlnerlngga/backend-socius/internal/notification"
 	"github.com/erlnerlngga/backend-socius/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/websocket"
 )
 
 type Handler struct {
-	hub *Hub
+	hub      *Hub
+	notifier *notification.Service
 }
 
-func NewWSHandler(h *Hub) *Handler {
+func NewWSHandler(h *Hub, n *notification.Service) *Handler {
 	return &Handler{
-		hub: h,
+		hub:      h,
+		notifier: n,
 	}
 }
 
@@ -113,6 +118,12 @@ func (h *Handler) AddFriend(w http.ResponseWriter, r *http.Request) error {
 
 	defer r.Body.Close()
 
+	// only member of the room can invite
+	userID := util.GetUserID(r)
+	if _, err := h.hub.Repository.CheckClient(userID, friend.Room_ID); err != nil {
+		return util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "not member of the room"})
+	}
+
 	friend.Role = "user"
 
 	err := h.hub.Repository.InsertNewClient(friend)
@@ -120,6 +131,16 @@ func (h *Handler) AddFriend(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	err = h.notifier.Emit(&notification.EventType{
+		Type:     "room_invite",
+		Actor_ID: userID,
+		User_ID:  friend.User_ID,
+		Room_ID:  friend.Room_ID,
+	})
+	if err != nil {
+		return err
+	}
+
 	return util.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -153,6 +174,7 @@ func (h *Handler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 	cl = &Client{
 		Conn:      conn,
 		Message:   make(chan *MessageType, 10),
+		Errors:    make(chan *ErrorFrameType, 10),
 		Client_ID: res.Client_ID,
 		User_ID:   res.User_ID,
 		Room_ID:   res.Room_ID,
@@ -165,6 +187,33 @@ func (h *Handler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 	cl.readMessage(h.hub)
 }
 
+// open stream of notification and unread count of the caller, first frame is the current badge
+func (h *Handler) JoinStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("1. JoinStream", err)
+		return
+	}
+
+	cl := &StreamClient{
+		Conn:    conn,
+		User_ID: util.GetUserID(r),
+		Frames:  make(chan *StreamFrameType, 10),
+	}
+
+	h.hub.Stream.Register(cl)
+
+	frame, err := h.hub.Stream.badge(cl.User_ID)
+	if err != nil {
+		log.Println("2. JoinStream", err)
+	} else {
+		cl.Frames <- frame
+	}
+
+	go cl.writeFrame()
+	cl.readFrame(h.hub.Stream)
+}
+
 func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) error {
 	userID := chi.URLParam(r, "userID")
 	roomID := chi.URLParam(r, "roomID")
@@ -196,31 +245,40 @@ func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) error {
 func (h *Handler) GetAllMessage(w http.ResponseWriter, r *http.Request) error {
 	roomID := chi.URLParam(r, "roomID")
 
-	message, err := h.hub.GetAllMessage(roomID)
+	page, err := util.GetPageReq(r)
+	if err != nil {
+		return err
+	}
+
+	// history is oldest first like before paging, order=newest start from the latest message
+	switch r.URL.Query().Get("order") {
+	case "", "oldest":
+		page.Asc = true
+	case "newest":
+	default:
+		return fmt.Errorf("order must be oldest or newest")
+	}
+
+	message, err := h.hub.GetAllMessage(roomID, page)
 	if err != nil {
 		return err
 	}
 
-	return util.WriteJSON(w, http.StatusOK, message)
+	return util.WriteJSON(w, http.StatusOK, util.NewPage(message, page, messageKey))
 }
 
 func (h *Handler) CountAllUnreadMessage(w http.ResponseWriter, r *http.Request) error {
 	userID := chi.URLParam(r, "userID")
-	result := 0
 
-	rooms, err := h.hub.GetRoomsByUserID(userID)
+	result, err := h.hub.CountUnreadAll(userID)
 	if err != nil {
 		return err
 	}
 
-	for _, val := range rooms {
-		num, err := h.hub.CountAllUnreadMessage(userID, val.Room_ID)
-		if err != nil {
-			return err
-		}
-
-		result = result + num
-	}
-
 	return util.WriteJSON(w, http.StatusOK, map[string]int{"unread_message": result})
 }
+
+// cursor key for message list
+func messageKey(m *MessageType) (time.Time, string) {
+	return m.Created_At, m.Message_ID
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"time"
+
+	"github.com/erlnerlngga/backend-socius/internal/filter"
 )
 
 type Room struct {
@@ -18,16 +20,20 @@ type Hub struct {
 	Unregister chan *Client
 	Broadcast  chan *MessageType
 	Repository
+	Filter  *filter.Pipeline
+	Stream  *Stream
 	timeout time.Duration
 }
 
-func NewHub(repository Repository) *Hub {
+func NewHub(repository Repository, f *filter.Pipeline, s *Stream) *Hub {
 	return &Hub{
 		Rooms:      make(map[string]*Room),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Broadcast:  make(chan *MessageType, 5),
 		Repository: repository,
+		Filter:     f,
+		Stream:     s,
 		timeout:    time.Duration(2) * time.Second,
 	}
 }
@@ -79,6 +85,9 @@ func (h *Hub) Run(c context.Context) {
 					h.Repository.CreateLog(log)
 					delete(h.Rooms[cl.Room_ID].Clients, cl.Client_ID)
 					close(cl.Message)
+
+					// leaving mark the room as read
+					h.Stream.Changed(cl.User_ID)
 				}
 			}
 
@@ -94,6 +103,8 @@ func (h *Hub) Run(c context.Context) {
 					log.Println("2. Broadcast", err)
 				}
 
+				h.Stream.RoomChanged(m.Room_ID, m.User_ID)
+
 				for _, cl := range h.Rooms[m.Room_ID].Clients {
 					cl.Message <- m
 				}
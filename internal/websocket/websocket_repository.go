@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/erlnerlngga/backend-socius/util"
 	"github.com/google/uuid"
 )
 
@@ -210,7 +211,7 @@ func (r *Repository) InsertNewClient(client *ClientType) error {
 
 // get client base on room ID
 func (r *Repository) GetClients(room_id string) ([]*ClientType, error) {
-	query := `select * from client where client_id = ?;`
+	query := `select client_id, room_id, user_id, user_name, role, created_at, updated_at from client where room_id = ?;`
 
 	rows, err := r.db.Query(query, room_id)
 
@@ -225,7 +226,7 @@ func (r *Repository) GetClients(room_id string) ([]*ClientType, error) {
 	for rows.Next() {
 		c := new(ClientType)
 
-		if err := rows.Scan(&c.Client_ID, &c.Room_ID, &c.User_ID, &c.User_Name, &c.Created_At, &c.Updated_At); err != nil {
+		if err := rows.Scan(&c.Client_ID, &c.Room_ID, &c.User_ID, &c.User_Name, &c.Role, &c.Created_At, &c.Updated_At); err != nil {
 			log.Println("2. GetClients", err)
 			return nil, err
 		}
@@ -284,7 +285,7 @@ func (r *Repository) CreateLog(log *LogType) error {
 // get count is not yet join
 func (r *Repository) CountMessageNotYetJoin(room_id string) (int, error) {
 	var unread_message int
-	query := "select count(*) as `unread_message` from message where room_id = ?;"
+	query := "select count(*) as `unread_message` from message where room_id = ? and hidden = false;"
 
 	err := r.db.QueryRow(query, room_id).Scan(&unread_message)
 
@@ -323,7 +324,7 @@ func (r *Repository) CountUnreadMessage(user_id string, room *RoomTypeRes) (*Roo
 		return nil, err
 	}
 
-	countQuery := "select count(*) as `unread_message` from message where created_at >= ? and room_id = ?;"
+	countQuery := "select count(*) as `unread_message` from message where created_at >= ? and room_id = ? and hidden = false;"
 	err = r.db.QueryRow(countQuery, log.Created_At, room.Room_ID).Scan(&room.Unread_Message)
 
 	if err == sql.ErrNoRows {
@@ -362,7 +363,7 @@ func (r *Repository) CountAllUnreadMessage(user_id, room_id string) (int, error)
 		return -1, err
 	}
 
-	countQuery := "select count(*) as `unread_message` from message where created_at >= ? and room_id = ?;"
+	countQuery := "select count(*) as `unread_message` from message where created_at >= ? and room_id = ? and hidden = false;"
 	err = r.db.QueryRow(countQuery, log.Created_At, room_id).Scan(&number)
 
 	if err == sql.ErrNoRows {
@@ -377,6 +378,29 @@ func (r *Repository) CountAllUnreadMessage(user_id, room_id string) (int, error)
 	return number, nil
 }
 
+// count unread message of user in every room
+func (r *Repository) CountUnreadAll(user_id string) (int, error) {
+	result := 0
+
+	rooms, err := r.GetRoomsByUserID(user_id)
+	if err != nil {
+		fmt.Println("1. CountUnreadAll", err)
+		return -1, err
+	}
+
+	for _, val := range rooms {
+		num, err := r.CountAllUnreadMessage(user_id, val.Room_ID)
+		if err != nil {
+			fmt.Println("2. CountUnreadAll", err)
+			return -1, err
+		}
+
+		result = result + num
+	}
+
+	return result, nil
+}
+
 // get user
 func (r *Repository) GetUser(u *MessageType) (*MessageType, error) {
 	query := `select user_name, photo_profile from user where user_id = ?;`
@@ -391,10 +415,11 @@ func (r *Repository) GetUser(u *MessageType) (*MessageType, error) {
 }
 
 // get all message
-func (r *Repository) GetAllMessage(room_id string) ([]*MessageType, error) {
-	query := `select * from message where room_id = ?;`
+func (r *Repository) GetAllMessage(room_id string, page *util.PageReqType) ([]*MessageType, error) {
+	cond, args := page.Query("created_at", "message_id")
+	query := "select message_id, room_id, user_id, client_id, content, created_at, updated_at from message where room_id = ? and hidden = false" + cond + ";"
 
-	rows, err := r.db.Query(query, room_id)
+	rows, err := r.db.Query(query, append([]any{room_id}, args...)...)
 	if err != nil {
 		fmt.Println("1. GetAllMessage", err)
 		return nil, err
@@ -446,3 +471,17 @@ func (r *Repository) CreateMessage(msg *MessageType) (*MessageType, error) {
 
 	return msg, nil
 }
+
+// check user is suspended by moderator
+func (r *Repository) IsSuspended(user_id string) (bool, error) {
+	var number int
+
+	query := "select count(*) as `number` from user where user_id = ? and suspended_until > ?;"
+	err := r.db.QueryRow(query, user_id, time.Now().UTC()).Scan(&number)
+	if err != nil {
+		fmt.Println("1. IsSuspended", err)
+		return false, err
+	}
+
+	return number > 0, nil
+}
@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/erlnerlngga/backend-socius/internal/notification"
+	"github.com/gorilla/websocket"
+)
+
+// frame pushed to user stream, notification come together with the new badge
+type StreamFrameType struct {
+	Type                string                         `json:"type"` // notification or badge
+	Notification        *notification.NotificationType `json:"notification,omitempty"`
+	Unread_Notification int                            `json:"unread_notification"`
+	Unread_Message      int                            `json:"unread_message"`
+}
+
+// one open stream, a user can have many like one per tab
+type StreamClient struct {
+	Conn    *websocket.Conn
+	User_ID string
+	Frames  chan *StreamFrameType
+}
+
+// Stream push notification and unread count to every open stream of user.
+// It listen to the notification service and get told by the hub when chat change.
+type Stream struct {
+	Repository   *Repository
+	Notification *notification.Repository
+
+	mu      sync.Mutex
+	clients map[string]map[*StreamClient]bool
+}
+
+func NewStream(r *Repository, n *notification.Repository) *Stream {
+	return &Stream{
+		Repository:   r,
+		Notification: n,
+		clients:      map[string]map[*StreamClient]bool{},
+	}
+}
+
+func (s *Stream) Register(cl *StreamClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clients[cl.User_ID] == nil {
+		s.clients[cl.User_ID] = map[*StreamClient]bool{}
+	}
+
+	s.clients[cl.User_ID][cl] = true
+}
+
+func (s *Stream) Unregister(cl *StreamClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[cl.User_ID][cl]; !ok {
+		return
+	}
+
+	delete(s.clients[cl.User_ID], cl)
+	if len(s.clients[cl.User_ID]) == 0 {
+		delete(s.clients, cl.User_ID)
+	}
+
+	close(cl.Frames)
+}
+
+func (s *Stream) isOnline(user_id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.clients[user_id]) > 0
+}
+
+// send frame to every stream of user, slow stream miss the frame instead of blocking
+func (s *Stream) send(user_id string, frame *StreamFrameType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for cl := range s.clients[user_id] {
+		select {
+		case cl.Frames <- frame:
+		default:
+		}
+	}
+}
+
+// count unread notification and chat message of user
+func (s *Stream) badge(user_id string) (*StreamFrameType, error) {
+	notif, err := s.Notification.GetCountNotif(user_id)
+	if err != nil {
+		fmt.Println("1. badge", err)
+		return nil, err
+	}
+
+	message, err := s.Repository.CountUnreadAll(user_id)
+	if err != nil {
+		fmt.Println("2. badge", err)
+		return nil, err
+	}
+
+	return &StreamFrameType{Type: "badge", Unread_Notification: notif, Unread_Message: message}, nil
+}
+
+// push the current badge to user, nothing is counted when user has no stream
+func (s *Stream) pushBadge(user_id string) {
+	if !s.isOnline(user_id) {
+		return
+	}
+
+	frame, err := s.badge(user_id)
+	if err != nil {
+		fmt.Println("1. pushBadge", err)
+		return
+	}
+
+	s.send(user_id, frame)
+}
+
+// Notified push new notification, the name of issuer is loaded like the notification list
+func (s *Stream) Notified(n *notification.NotificationType) {
+	if !s.isOnline(n.Notifier) {
+		return
+	}
+
+	go func() {
+		full, err := s.Notification.GetNotification(n.Notification_ID)
+		if err != nil {
+			fmt.Println("1. Notified", err)
+			return
+		}
+
+		s.send(n.Notifier, &StreamFrameType{Type: "notification", Notification: full})
+		s.pushBadge(n.Notifier)
+	}()
+}
+
+// Changed push new badge after notification is read
+func (s *Stream) Changed(user_id string) {
+	go s.pushBadge(user_id)
+}
+
+// RoomChanged push new badge to member of room after message is sent,
+// except the sender and member that muted the room or the sender
+func (s *Stream) RoomChanged(room_id, sender_id string) {
+	go func() {
+		clients, err := s.Repository.GetClients(room_id)
+		if err != nil {
+			fmt.Println("1. RoomChanged", err)
+			return
+		}
+
+		e := &notification.EventType{Actor_ID: sender_id, Room_ID: room_id}
+		for _, c := range clients {
+			if c.User_ID == sender_id || !s.isOnline(c.User_ID) {
+				continue
+			}
+
+			muted, err := s.Notification.IsMuted(c.User_ID, e)
+			if err != nil {
+				fmt.Println("2. RoomChanged", err)
+				continue
+			}
+
+			if !muted {
+				s.pushBadge(c.User_ID)
+			}
+		}
+	}()
+}
+
+func (cl *StreamClient) writeFrame() {
+	defer func() {
+		cl.Conn.Close()
+	}()
+
+	for frame := range cl.Frames {
+		if err := cl.Conn.WriteJSON(frame); err != nil {
+			fmt.Println("1. writeFrame", err)
+			return
+		}
+	}
+}
+
+// stream only push to client, reading is just to know when it is closed
+func (cl *StreamClient) readFrame(s *Stream) {
+	defer func() {
+		s.Unregister(cl)
+		cl.Conn.Close()
+	}()
+
+	for {
+		if _, _, err := cl.Conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
@@ -6,6 +6,13 @@ import (
 	"os"
 
 	"github.com/erlnerlngga/backend-socius/db"
+	"github.com/erlnerlngga/backend-socius/internal/filter"
+	"github.com/erlnerlngga/backend-socius/internal/media"
+	"github.com/erlnerlngga/backend-socius/internal/moderation"
+	"github.com/erlnerlngga/backend-socius/internal/notification"
+	"github.com/erlnerlngga/backend-socius/internal/search"
+	"github.com/erlnerlngga/backend-socius/internal/story"
+	"github.com/erlnerlngga/backend-socius/internal/trending"
 	"github.com/erlnerlngga/backend-socius/internal/user"
 	"github.com/erlnerlngga/backend-socius/internal/websocket"
 	"github.com/erlnerlngga/backend-socius/router"
@@ -24,19 +31,65 @@ func main() {
 
 	defer db.Close()
 
+	mediaStore, err := media.NewBlobStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mediaRepo := media.NewMediaRepository(db.GetDB())
+	mediaHandler := media.NewMediaHandler(mediaRepo, mediaStore)
+
+	// content filter applied on every post, comment and chat message
+	contentFilter, err := filter.NewPipelineFromEnv(filter.NewFilterRepository(db.GetDB()))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// every notification is created by the server from event of the other package
+	notifRepo := notification.NewNotificationRepository(db.GetDB())
+	notifService := notification.NewNotificationService(notifRepo)
+	notifHandler := notification.NewNotificationHandler(notifService)
+	go notification.NewOutbox(notifService).Run(context.Background())
+
 	userRepo := user.NewUserRepository(db.GetDB())
-	userHandler := user.NewUserHandler(userRepo)
+	userHandler := user.NewUserHandler(userRepo, mediaRepo, contentFilter, notifService)
+	go user.NewScheduler(userHandler).Run(context.Background())
 
 	wsRepo := websocket.NewRepositoryWS(db.GetDB())
-	wsHub := websocket.NewHub(*wsRepo)
-	wsHandler := websocket.NewWSHandler(wsHub)
+	// per user stream of notification and unread count, fed by the hub and notification service
+	wsStream := websocket.NewStream(wsRepo, notifRepo)
+	notifService.Subscribe(wsStream)
+	wsHub := websocket.NewHub(*wsRepo, contentFilter, wsStream)
+	wsHandler := websocket.NewWSHandler(wsHub, notifService)
 	go wsHub.Run(context.Background())
 
+	// use FULLTEXT index when the database support it, otherwise keep index in memory
+	searchRepo := search.NewSearchRepository(db.GetDB())
+	var searcher search.Searcher = searchRepo
+	if err := db.InitFulltext(); err != nil {
+		log.Println("fulltext not available, use memory index: ", err)
+		mem := search.NewMemoryIndex(searchRepo)
+		go mem.Run(context.Background())
+		searcher = mem
+	}
+	searchHandler := search.NewSearchHandler(searcher, searchRepo)
+
+	modRepo := moderation.NewModerationRepository(db.GetDB())
+	modHandler := moderation.NewModerationHandler(modRepo, userRepo)
+
+	trendRepo := trending.NewTrendingRepository(db.GetDB())
+	trendHandler := trending.NewTrendingHandler(trendRepo, userRepo)
+	go trending.NewRefresher(trendRepo).Run(context.Background())
+
+	storyRepo := story.NewStoryRepository(db.GetDB())
+	storyHandler := story.NewStoryHandler(storyRepo, mediaHandler)
+	go story.NewReaper(storyHandler).Run(context.Background())
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000"
 	}
 
-	server := router.NewApiServer("0.0.0.0:"+port, userHandler, wsHandler)
+	server := router.NewApiServer("0.0.0.0:"+port, userHandler, wsHandler, searchHandler, mediaHandler, modHandler, trendHandler, storyHandler, notifHandler)
 	server.Run()
 }
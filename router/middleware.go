@@ -1,11 +1,13 @@
 package router
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"strings"
 
+	"github.com/erlnerlngga/backend-socius/internal/moderation"
 	"github.com/erlnerlngga/backend-socius/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v4"
@@ -29,6 +31,7 @@ func WithJWTAuth(next http.Handler) http.Handler {
 		headerParts := strings.Split(authHeader, " ")
 		if len(headerParts) != 2 {
 			util.WriteJSON(w, http.StatusUnauthorized, util.ApiError{Error: "Invalid auth header"})
+			return
 		}
 
 		tokenString := headerParts[1]
@@ -62,9 +65,13 @@ func WithJWTAuth(next http.Handler) http.Handler {
 
 		if !token.Valid {
 			util.WriteJSON(w, http.StatusUnauthorized, util.ApiError{Error: "token invalid"})
+			return
 		}
 
-		next.ServeHTTP(w, r)
+		// store user_id so handler know who is the caller
+		ctx := context.WithValue(r.Context(), util.UserIDKey, claims.User_ID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -73,9 +80,6 @@ func WithJWTAuthWS(next http.Handler) http.Handler {
 
 		thoken := chi.URLParam(r, "token")
 
-		// tokenString := thoken
-		log.Println("tokenString", thoken)
-
 		// init claims
 		claims := new(util.ClaimsType)
 
@@ -88,8 +92,6 @@ func WithJWTAuthWS(next http.Handler) http.Handler {
 			return util.JwtKey, nil
 		})
 
-		log.Println("TOKEN", token)
-
 		if err != nil {
 			if err == jwt.ErrSignatureInvalid {
 				log.Println("1. WithJWTAuthWS ", err)
@@ -114,6 +116,55 @@ func WithJWTAuthWS(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), util.UserIDKey, claims.User_ID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// suspended user can still read but can't create or change anything
+func WithActiveUser(m *moderation.Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			suspended, err := m.IsSuspended(util.GetUserID(r))
+			if err != nil {
+				log.Println("1. WithActiveUser", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.ApiError{Error: err.Error()})
+				return
+			}
+
+			if suspended {
+				util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "account is suspended"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// only moderator can pass, used by moderation queue
+func WithModerator(m *moderation.Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			moderator, err := m.IsModerator(util.GetUserID(r))
+			if err != nil {
+				log.Println("1. WithModerator", err)
+				util.WriteJSON(w, http.StatusInternalServerError, util.ApiError{Error: err.Error()})
+				return
+			}
+
+			if !moderator {
+				util.WriteJSON(w, http.StatusForbidden, util.ApiError{Error: "moderator only"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
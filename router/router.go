@@ -4,6 +4,12 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/erlnerlngga/backend-socius/internal/media"
+	"github.com/erlnerlngga/backend-socius/internal/moderation"
+	"github.com/erlnerlngga/backend-socius/internal/notification"
+	"github.com/erlnerlngga/backend-socius/internal/search"
+	"github.com/erlnerlngga/backend-socius/internal/story"
+	"github.com/erlnerlngga/backend-socius/internal/trending"
 	"github.com/erlnerlngga/backend-socius/internal/user"
 	"github.com/erlnerlngga/backend-socius/internal/websocket"
 	"github.com/erlnerlngga/backend-socius/util"
@@ -13,16 +19,28 @@ import (
 )
 
 type APIServer struct {
-	listenAddr  string
-	userHandler *user.Handler
-	wsHandler   *websocket.Handler
+	listenAddr    string
+	userHandler   *user.Handler
+	wsHandler     *websocket.Handler
+	searchHandler *search.Handler
+	mediaHandler  *media.Handler
+	modHandler    *moderation.Handler
+	trendHandler  *trending.Handler
+	storyHandler  *story.Handler
+	notifHandler  *notification.Handler
 }
 
-func NewApiServer(listenAddr string, userHandler *user.Handler, wsHandler *websocket.Handler) *APIServer {
+func NewApiServer(listenAddr string, userHandler *user.Handler, wsHandler *websocket.Handler, searchHandler *search.Handler, mediaHandler *media.Handler, modHandler *moderation.Handler, trendHandler *trending.Handler, storyHandler *story.Handler, notifHandler *notification.Handler) *APIServer {
 	return &APIServer{
-		listenAddr:  listenAddr,
-		userHandler: userHandler,
-		wsHandler:   wsHandler,
+		listenAddr:    listenAddr,
+		userHandler:   userHandler,
+		wsHandler:     wsHandler,
+		searchHandler: searchHandler,
+		mediaHandler:  mediaHandler,
+		modHandler:    modHandler,
+		trendHandler:  trendHandler,
+		storyHandler:  storyHandler,
+		notifHandler:  notifHandler,
 	}
 }
 
@@ -33,6 +51,7 @@ func (s *APIServer) Run() {
 		AllowedOrigins:   []string{"https://socius-jade.vercel.app", "https://socius-laannen-gmailcom.vercel.app", "https://socius-5ym9o8can-laannen-gmailcom.vercel.app", "https://socius-git-main-laannen-gmailcom.vercel.app"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"X-Feed-Ranker"},
 		AllowCredentials: true,
 	}))
 
@@ -40,34 +59,124 @@ func (s *APIServer) Run() {
 		r.Get("/ws/joinRoom/{roomID}/{userID}", s.wsHandler.JoinRoom)
 	})
 
+	// browser websocket can't send header, token is in the path
+	router.Group(func(r chi.Router) {
+		r.Use(WithJWTAuthWS)
+		r.Get("/ws/stream/{token}", s.wsHandler.JoinStream)
+	})
+
 	router.Get("/", util.MakeHTTPHandleFunc(s.userHandler.Welcome))
 	router.Post("/signup", util.MakeHTTPHandleFunc(s.userHandler.SignUp))
 	router.Post("/signin", util.MakeHTTPHandleFunc(s.userHandler.SignIn))
 	router.Get("/auth/{token}", util.MakeHTTPHandleFunc(s.userHandler.VerifySignIn))
+	router.Get("/media/{mediaID}", util.MakeHTTPHandleFunc(s.mediaHandler.GetMedia))
+	router.Get("/media/{mediaID}/{variant}", util.MakeHTTPHandleFunc(s.mediaHandler.GetVariant))
 
 	router.Group(func(r chi.Router) {
 		r.Use(middleware.Logger)
 		r.Use(WithJWTAuth)
+		r.Use(WithActiveUser(s.modHandler.Repository))
 		r.Get("/justCheck/{token}", util.MakeHTTPHandleFunc(s.userHandler.JustCheck))
 		r.Post("/checkEmail", util.MakeHTTPHandleFunc(s.userHandler.CheckEmail))
 		r.Get("/getUser/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetUserByID))
 		r.Put("/updateUser", util.MakeHTTPHandleFunc(s.userHandler.UpdateUser))
+		r.Put("/updateDefaultAudience", util.MakeHTTPHandleFunc(s.userHandler.UpdateDefaultAudience))
 		r.Get("/getUserbyEmail/{email}", util.MakeHTTPHandleFunc(s.userHandler.GetUserbyEmail))
-		r.Post("/addNewFriend", util.MakeHTTPHandleFunc(s.userHandler.AddNewFriend))
+		r.Post("/sendFriendRequest", util.MakeHTTPHandleFunc(s.userHandler.SendFriendRequest))
+		r.Put("/updateAddFriendNotification", util.MakeHTTPHandleFunc(s.userHandler.AnswerFriendRequest))
 		r.Delete("/removeFriend/{userID}/{friendID}/{userFriendID}", util.MakeHTTPHandleFunc(s.userHandler.RemoveFriend))
 		r.Get("/getAllFriend/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllFriend))
+		r.Get("/getFriendSuggestion/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetFriendSuggestion))
+		r.Post("/dismissSuggestion", util.MakeHTTPHandleFunc(s.userHandler.DismissSuggestion))
+		r.Get("/getMutualFriend/{userID}/{otherID}", util.MakeHTTPHandleFunc(s.userHandler.GetMutualFriend))
+		r.Post("/blockUser", util.MakeHTTPHandleFunc(s.userHandler.BlockUser))
+		r.Delete("/unblockUser/{userID}/{blockedID}", util.MakeHTTPHandleFunc(s.userHandler.UnblockUser))
+		r.Post("/follow", util.MakeHTTPHandleFunc(s.userHandler.Follow))
+		r.Delete("/unfollow/{followerID}/{followingID}", util.MakeHTTPHandleFunc(s.userHandler.Unfollow))
+		r.Get("/getAllFollower/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllFollower))
+		r.Get("/getAllFollowing/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllFollowing))
+		r.Get("/getFollowCount/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetFollowCount))
 		r.Post("/createPost", util.MakeHTTPHandleFunc(s.userHandler.CreatePost))
 		r.Get("/getAllPost/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllPost))
 		r.Get("/getAllOwnPost/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllOwnPost))
 		r.Get("/getPost/{postID}", util.MakeHTTPHandleFunc(s.userHandler.GetPost))
+		r.Put("/updatePost", util.MakeHTTPHandleFunc(s.userHandler.UpdatePost))
+		r.Delete("/deletePost/{postID}", util.MakeHTTPHandleFunc(s.userHandler.DeletePost))
+		r.Delete("/deleteImage/{imagePostID}", util.MakeHTTPHandleFunc(s.userHandler.DeleteImage))
+		r.Get("/getPostHistory/{postID}", util.MakeHTTPHandleFunc(s.userHandler.GetPostHistory))
+		r.Post("/createDraft", util.MakeHTTPHandleFunc(s.userHandler.CreateDraft))
+		r.Put("/updateDraft", util.MakeHTTPHandleFunc(s.userHandler.UpdateDraft))
+		r.Delete("/deleteDraft/{draftID}", util.MakeHTTPHandleFunc(s.userHandler.DeleteDraft))
+		r.Post("/publishDraft/{draftID}", util.MakeHTTPHandleFunc(s.userHandler.PublishDraft))
+		r.Get("/getAllDraft", util.MakeHTTPHandleFunc(s.userHandler.GetAllDraft))
+		r.Get("/getAllScheduled", util.MakeHTTPHandleFunc(s.userHandler.GetAllScheduled))
+		r.Put("/cancelScheduled/{draftID}", util.MakeHTTPHandleFunc(s.userHandler.CancelScheduled))
+		r.Post("/votePoll", util.MakeHTTPHandleFunc(s.userHandler.VotePoll))
+		r.Post("/repost", util.MakeHTTPHandleFunc(s.userHandler.Repost))
+		r.Delete("/undoRepost/{postID}", util.MakeHTTPHandleFunc(s.userHandler.UndoRepost))
+		r.Post("/savePost", util.MakeHTTPHandleFunc(s.userHandler.SavePost))
+		r.Delete("/unsavePost/{postID}", util.MakeHTTPHandleFunc(s.userHandler.UnsavePost))
+		r.Get("/getAllSaved", util.MakeHTTPHandleFunc(s.userHandler.GetAllSaved))
+		r.Post("/createCollection", util.MakeHTTPHandleFunc(s.userHandler.CreateCollection))
+		r.Get("/getAllCollection", util.MakeHTTPHandleFunc(s.userHandler.GetAllCollection))
+		r.Put("/renameCollection", util.MakeHTTPHandleFunc(s.userHandler.RenameCollection))
+		r.Delete("/deleteCollection/{collectionID}", util.MakeHTTPHandleFunc(s.userHandler.DeleteCollection))
+		r.Post("/toggleReaction", util.MakeHTTPHandleFunc(s.userHandler.ToggleReaction))
+		r.Get("/getAllReaction/{postID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllReaction))
+		r.Get("/tags/{tag}", util.MakeHTTPHandleFunc(s.userHandler.GetAllPostByTag))
 		r.Get("/getAllImage/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllImage))
+		r.Post("/createAlbum", util.MakeHTTPHandleFunc(s.userHandler.CreateAlbum))
+		r.Get("/getAllAlbum/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllAlbum))
+		r.Get("/getAlbum/{albumID}", util.MakeHTTPHandleFunc(s.userHandler.GetAlbum))
+		r.Get("/getAllAlbumPhoto/{albumID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllAlbumPhoto))
+		r.Put("/updateAlbum", util.MakeHTTPHandleFunc(s.userHandler.UpdateAlbum))
+		r.Delete("/deleteAlbum/{albumID}", util.MakeHTTPHandleFunc(s.userHandler.DeleteAlbum))
+		r.Put("/moveAlbumPhoto", util.MakeHTTPHandleFunc(s.userHandler.MoveAlbumPhoto))
+		r.Put("/setAlbumCover", util.MakeHTTPHandleFunc(s.userHandler.SetAlbumCover))
 		r.Post("/createComment", util.MakeHTTPHandleFunc(s.userHandler.CreateComment))
 		r.Get("/getAllComment/{postID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllComment))
-		r.Post("/createNotification", util.MakeHTTPHandleFunc(s.userHandler.CreateNotification))
-		r.Put("/updateAddFriendNotification", util.MakeHTTPHandleFunc(s.userHandler.UpdateAddFriendNotification))
-		r.Put("/updateNotificationRead/{userID}", util.MakeHTTPHandleFunc(s.userHandler.UpdateNotificationRead))
-		r.Get("/getCountNotification/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetCountNotification))
-		r.Get("/getAllNotification/{userID}", util.MakeHTTPHandleFunc(s.userHandler.GetAllNotification))
+		r.Put("/updateComment", util.MakeHTTPHandleFunc(s.userHandler.UpdatePost))
+		r.Delete("/deleteComment/{postID}", util.MakeHTTPHandleFunc(s.userHandler.DeletePost))
+
+		// notification
+		r.Put("/updateNotificationRead/{userID}", util.MakeHTTPHandleFunc(s.notifHandler.UpdateNotificationRead))
+		r.Get("/getCountNotification/{userID}", util.MakeHTTPHandleFunc(s.notifHandler.GetCountNotification))
+		r.Get("/getAllNotification/{userID}", util.MakeHTTPHandleFunc(s.notifHandler.GetAllNotification))
+		r.Get("/getNotificationSetting", util.MakeHTTPHandleFunc(s.notifHandler.GetNotificationSetting))
+		r.Put("/updateNotificationPreference", util.MakeHTTPHandleFunc(s.notifHandler.UpdateNotificationPreference))
+		r.Put("/updateQuietHour", util.MakeHTTPHandleFunc(s.notifHandler.UpdateQuietHour))
+		r.Post("/muteNotification", util.MakeHTTPHandleFunc(s.notifHandler.MuteNotification))
+		r.Delete("/unmuteNotification/{targetType}/{targetID}", util.MakeHTTPHandleFunc(s.notifHandler.UnmuteNotification))
+		r.Get("/getAllMute", util.MakeHTTPHandleFunc(s.notifHandler.GetAllMute))
+
+		// media
+		r.Post("/uploadMedia", util.MakeHTTPHandleFunc(s.mediaHandler.Upload))
+
+		// moderation
+		r.Post("/report", util.MakeHTTPHandleFunc(s.modHandler.CreateReport))
+		r.Group(func(r chi.Router) {
+			r.Use(WithModerator(s.modHandler.Repository))
+			r.Get("/moderation/queue", util.MakeHTTPHandleFunc(s.modHandler.GetQueue))
+			r.Post("/moderation/action", util.MakeHTTPHandleFunc(s.modHandler.TakeAction))
+			r.Get("/moderation/actions", util.MakeHTTPHandleFunc(s.modHandler.GetAllAction))
+		})
+
+		// story
+		r.Post("/createStory", util.MakeHTTPHandleFunc(s.storyHandler.CreateStory))
+		r.Delete("/deleteStory/{storyID}", util.MakeHTTPHandleFunc(s.storyHandler.DeleteStory))
+		r.Get("/getStoryTray", util.MakeHTTPHandleFunc(s.storyHandler.GetStoryTray))
+		r.Get("/getAllStory/{userID}", util.MakeHTTPHandleFunc(s.storyHandler.GetAllStory))
+		r.Post("/viewStory/{storyID}", util.MakeHTTPHandleFunc(s.storyHandler.ViewStory))
+		r.Get("/getStoryViewer/{storyID}", util.MakeHTTPHandleFunc(s.storyHandler.GetStoryViewer))
+
+		// trending
+		r.Get("/trending/posts", util.MakeHTTPHandleFunc(s.trendHandler.GetTrendingPost))
+		r.Get("/trending/hashtags", util.MakeHTTPHandleFunc(s.trendHandler.GetTrendingHashtag))
+
+		// search
+		r.Get("/search/users", util.MakeHTTPHandleFunc(s.searchHandler.SearchUser))
+		r.Get("/search/posts", util.MakeHTTPHandleFunc(s.searchHandler.SearchPost))
+		r.Get("/search/messages/{roomID}", util.MakeHTTPHandleFunc(s.searchHandler.SearchMessage))
 
 		// ws
 		r.Post("/ws/createRoom", util.MakeHTTPHandleFunc(s.wsHandler.CreateRoom))
@@ -0,0 +1,232 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const DefaultLimit = 20
+const MaxLimit = 100
+
+// cursor point to one row, keyed on created_at + id or score + id
+type CursorType struct {
+	Created_At time.Time `json:"t"`
+	Score      int       `json:"s,omitempty"`
+	Offset     int       `json:"o,omitempty"`
+	By_Offset  bool      `json:"b,omitempty"` // offset cursor has no id
+	ID         string    `json:"id"`
+	Prev       bool      `json:"p,omitempty"`
+}
+
+type PageReqType struct {
+	Limit  int
+	Asc    bool
+	Cursor *CursorType
+}
+
+type PageResType struct {
+	Data        any    `json:"data"`
+	Limit       int    `json:"limit"`
+	Next_Cursor string `json:"next_cursor"`
+	Prev_Cursor string `json:"prev_cursor"`
+}
+
+// encode cursor to opaque string
+func EncodeCursor(c *CursorType) string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode opaque string to cursor
+func DecodeCursor(s string) (*CursorType, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	c := new(CursorType)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	// keyset cursor need the id to break tie, offset cursor only the offset
+	if (c.By_Offset && c.Offset < 0) || (!c.By_Offset && c.ID == "") {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// read limit and cursor from query string
+func GetPageReq(r *http.Request) (*PageReqType, error) {
+	page := &PageReqType{Limit: DefaultLimit}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit")
+		}
+
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+
+		page.Limit = limit
+	}
+
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		cursor, err := DecodeCursor(c)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Cursor = cursor
+	}
+
+	return page, nil
+}
+
+// Query return the keyset condition, order and limit for the page.
+// It fetch one extra row so NewPage can tell whether there is more.
+func (p *PageReqType) Query(createdCol, idCol string) (string, []any) {
+	var value any
+	if p.Cursor != nil {
+		value = p.Cursor.Created_At
+	}
+
+	return p.keyset(createdCol, idCol, value)
+}
+
+// QueryScore is like Query but keyed on score + id, highest score first
+func (p *PageReqType) QueryScore(scoreExpr, idCol string) (string, []any) {
+	var value any
+	if p.Cursor != nil {
+		value = p.Cursor.Score
+	}
+
+	return p.keyset(scoreExpr, idCol, value)
+}
+
+func (p *PageReqType) keyset(keyCol, idCol string, value any) (string, []any) {
+	// going forward follow the requested order, going back flip it
+	forward := !p.Asc
+	if p.Cursor != nil && p.Cursor.Prev {
+		forward = !forward
+	}
+
+	order, op := "asc", ">"
+	if forward {
+		order, op = "desc", "<"
+	}
+
+	if p.Cursor == nil {
+		return fmt.Sprintf(" order by %s %s, %s %s limit ?", keyCol, order, idCol, order), []any{p.Limit + 1}
+	}
+
+	cond := fmt.Sprintf(" and (%s %s ? or (%s = ? and %s %s ?)) order by %s %s, %s %s limit ?", keyCol, op, keyCol, idCol, op, keyCol, order, idCol, order)
+	return cond, []any{value, value, p.Cursor.ID, p.Limit + 1}
+}
+
+// build response envelope from rows fetched with Query
+func NewPage[T any](items []T, p *PageReqType, key func(T) (time.Time, string)) *PageResType {
+	return newPage(items, p, func(item T) *CursorType {
+		createdAt, id := key(item)
+		return &CursorType{Created_At: createdAt, ID: id}
+	})
+}
+
+// build response envelope from rows fetched with QueryScore
+func NewScorePage[T any](items []T, p *PageReqType, key func(T) (int, string)) *PageResType {
+	return newPage(items, p, func(item T) *CursorType {
+		score, id := key(item)
+		return &CursorType{Score: score, ID: id}
+	})
+}
+
+func newPage[T any](items []T, p *PageReqType, cursor func(T) *CursorType) *PageResType {
+	hasMore := len(items) > p.Limit
+	if hasMore {
+		items = items[:p.Limit]
+	}
+
+	prev := p.Cursor != nil && p.Cursor.Prev
+	if prev {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	res := &PageResType{
+		Data:  items,
+		Limit: p.Limit,
+	}
+
+	if len(items) == 0 {
+		return res
+	}
+
+	// there is more row after when we got extra row going forward, or when we came back from later page
+	if prev || hasMore {
+		res.Next_Cursor = EncodeCursor(cursor(items[len(items)-1]))
+	}
+
+	// there is more row before when we got extra row going back, or when we came from earlier page
+	if (prev && hasMore) || (!prev && p.Cursor != nil) {
+		first := cursor(items[0])
+		first.Prev = true
+		res.Prev_Cursor = EncodeCursor(first)
+	}
+
+	return res
+}
+
+// offset of page for result that ranked by relevance instead of key
+func (p *PageReqType) GetOffset() int {
+	if p.Cursor == nil {
+		return 0
+	}
+
+	return p.Cursor.Offset
+}
+
+// build response envelope from rows fetched with limit + 1 from GetOffset
+func NewOffsetPage[T any](items []T, p *PageReqType) *PageResType {
+	return NewOffsetPageAt(items, p, p.GetOffset()+p.Limit)
+}
+
+// same as NewOffsetPage for list that skip row while reading, next page start at offset next
+func NewOffsetPageAt[T any](items []T, p *PageReqType, next int) *PageResType {
+	hasMore := len(items) > p.Limit
+	if hasMore {
+		items = items[:p.Limit]
+	}
+
+	res := &PageResType{
+		Data:  items,
+		Limit: p.Limit,
+	}
+
+	offset := p.GetOffset()
+	if hasMore {
+		res.Next_Cursor = EncodeCursor(&CursorType{Offset: next, By_Offset: true})
+	}
+
+	if offset > 0 {
+		prev := offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+
+		res.Prev_Cursor = EncodeCursor(&CursorType{Offset: prev, By_Offset: true})
+	}
+
+	return res
+}
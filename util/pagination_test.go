@@ -0,0 +1,96 @@
+package util
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+		want   *CursorType
+		err    bool
+	}{
+		{"keyset", EncodeCursor(&CursorType{Created_At: at, ID: "abc"}), &CursorType{Created_At: at, ID: "abc"}, false},
+		{"keyset with score and prev", EncodeCursor(&CursorType{Score: 7, ID: "abc", Prev: true}), &CursorType{Score: 7, ID: "abc", Prev: true}, false},
+		{"keyset without id", EncodeCursor(&CursorType{Created_At: at}), nil, true},
+		{"offset", EncodeCursor(&CursorType{Offset: 40, By_Offset: true}), &CursorType{Offset: 40, By_Offset: true}, false},
+		{"offset at zero", EncodeCursor(&CursorType{By_Offset: true}), &CursorType{By_Offset: true}, false},
+		{"negative offset", raw(`{"o":-20,"b":true,"id":""}`), nil, true},
+		{"offset without flag need id", raw(`{"o":20,"id":""}`), nil, true},
+		{"empty", "", nil, true},
+		{"bad base64", "not*base64!", nil, true},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"id":"ab"}`)), nil, true},
+		{"bad json", raw(`{"id":`), nil, true},
+		{"wrong type", raw(`{"id":1}`), nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := DecodeCursor(tt.cursor)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: DecodeCursor() err %v, want err %v", tt.name, err, tt.err)
+			continue
+		}
+
+		if tt.err {
+			continue
+		}
+
+		if !got.Created_At.Equal(tt.want.Created_At) || got.Score != tt.want.Score || got.Offset != tt.want.Offset ||
+			got.By_Offset != tt.want.By_Offset || got.ID != tt.want.ID || got.Prev != tt.want.Prev {
+			t.Errorf("%s: DecodeCursor() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewOffsetPageAt(t *testing.T) {
+	first := &PageReqType{Limit: 2}
+	second := &PageReqType{Limit: 2, Cursor: &CursorType{Offset: 4, By_Offset: true}}
+
+	tests := []struct {
+		name  string
+		items []int
+		page  *PageReqType
+		next  int
+		data  int
+		more  bool
+		prev  bool
+	}{
+		{"one extra row", []int{1, 2, 3}, first, 5, 2, true, false},
+		{"exactly limit", []int{1, 2}, first, 5, 2, false, false},
+		{"short", []int{1}, first, 5, 1, false, false},
+		{"second page", []int{1, 2, 3}, second, 9, 2, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewOffsetPageAt(tt.items, tt.page, tt.next)
+
+			if got := len(res.Data.([]int)); got != tt.data {
+				t.Errorf("data has %d item, want %d", got, tt.data)
+			}
+
+			if (res.Prev_Cursor != "") != tt.prev {
+				t.Errorf("prev cursor %q, want prev %v", res.Prev_Cursor, tt.prev)
+			}
+
+			if !tt.more {
+				if res.Next_Cursor != "" {
+					t.Errorf("next cursor %q, want none", res.Next_Cursor)
+				}
+
+				return
+			}
+
+			c, err := DecodeCursor(res.Next_Cursor)
+			if err != nil || c.Offset != tt.next {
+				t.Errorf("next cursor %+v, %v, want offset %d", c, err, tt.next)
+			}
+		})
+	}
+}
@@ -33,6 +33,17 @@ func MakeHTTPHandleFunc(f apiFunc) http.HandlerFunc {
 	}
 }
 
+type contextKey string
+
+// key of user_id from jwt claims that stored in request context
+const UserIDKey contextKey = "user_id"
+
+// get user_id of the caller, set by jwt middleware
+func GetUserID(r *http.Request) string {
+	userID, _ := r.Context().Value(UserIDKey).(string)
+	return userID
+}
+
 type ClaimsType struct {
 	User_ID string `json:"user_id"`
 	jwt.RegisteredClaims
@@ -93,6 +104,24 @@ func SendMAIL(email, user_name, token string) error {
 	return nil
 }
 
+// send notification email, text is already escaped plain text
+func SendNotificationMAIL(email, user_name, subject, text string) error {
+	mailer := gomail.NewMessage()
+	mailer.SetHeader("From", CONFIG_SENDER_NAME)
+	mailer.SetAddressHeader("To", email, user_name)
+	mailer.SetHeader("Subject", subject)
+	mailer.SetBody("text/html", `<p style="font-family:sans-serif;font-size:16px;color:#374151">`+text+`</p>`)
+
+	dialer := gomail.NewDialer(
+		CONFIG_SMTP_HOST,
+		CONFIG_SMTP_PORT,
+		CONFIG_AUTH_EMAIL,
+		CONFIG_AUTH_PASSWORD,
+	)
+
+	return dialer.DialAndSend(mailer)
+}
+
 func templeteEmail(user_name, token string) string {
 	return `
 	<table border="0" cellpadding="0" cellspacing="0" width="100%" style="table-layout:fixed;background-color:#f9f9f9" id="bodyTable">